@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/database/users"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+type AdminService interface {
+	ListUsers(ctx context.Context, req ListUsersDTO) (*UsersPageDTO, error)
+	GetUser(ctx context.Context, userID string) (*UserDTO, error)
+
+	SuspendUser(ctx context.Context, userID string) (*UserDTO, error)
+	UnsuspendUser(ctx context.Context, userID string) (*UserDTO, error)
+	ForcePasswordReset(ctx context.Context, userID string) error
+	DeleteUser(ctx context.Context, userID string) error
+}
+
+type ListUsersDTO struct {
+	Email  string `form:"email"`
+	Name   string `form:"name"`
+	Status string `form:"status" binding:"omitempty,oneof=active suspended deleted"`
+	Sort   string `form:"sort" binding:"omitempty,oneof=created_at name email"`
+	Order  string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type UsersPageDTO struct {
+	Users      []UserDTO `json:"users"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type UserDTO struct {
+	ID                    string   `json:"id"`
+	Name                  string   `json:"name"`
+	Email                 string   `json:"email"`
+	EmailVerified         bool     `json:"email_verified"`
+	Roles                 []string `json:"roles"`
+	Status                string   `json:"status"`
+	PasswordResetRequired bool     `json:"password_reset_required"`
+	CreatedAt             int64    `json:"created_at"`
+	DeletedAt             *int64   `json:"deleted_at"`
+}
+
+// listCursor is serialized into the opaque next_cursor value,
+// the sort order is included so a cursor can't be reused with a different one.
+type listCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func parseUserToUserDTO(entity users.User, roles []string) UserDTO {
+	dto := UserDTO{
+		ID:                    entity.ID.String(),
+		Name:                  entity.Name,
+		Email:                 entity.Email,
+		EmailVerified:         entity.EmailVerified,
+		Roles:                 roles,
+		Status:                entity.Status,
+		PasswordResetRequired: entity.PasswordResetRequired,
+		CreatedAt:             entity.CreatedAt.Unix(),
+	}
+
+	if entity.DeletedAt != nil {
+		deletedAt := entity.DeletedAt.Unix()
+		dto.DeletedAt = &deletedAt
+		dto.Status = users.StatusDeleted
+	}
+
+	return dto
+}
+
+func cursorValue(user users.User, sort string) string {
+	switch sort {
+	case users.SortByName:
+		return user.Name
+	case users.SortByEmail:
+		return user.Email
+	default:
+		return user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
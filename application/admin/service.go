@@ -0,0 +1,328 @@
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	app_users "github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/mailer"
+	"github.com/the-code-genin/simple-jwt-api-go/common/random"
+	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/password_resets"
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/database/users"
+	"go.uber.org/zap"
+)
+
+const defaultPageSize = 20
+
+type adminService struct {
+	config                      *config.Config
+	usersRepository             users.UsersRepository
+	blacklistedTokensRepository blacklisted_tokens.BlacklistedTokensRepository
+	passwordResetsRepository    password_resets.PasswordResetsRepository
+	rolesRepository             roles.RolesRepository
+	mailer                      mailer.Mailer
+	usersService                app_users.UsersService
+}
+
+func (s *adminService) ListUsers(ctx context.Context, req ListUsersDTO) (*UsersPageDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AdminService/ListUsers"))
+
+	if req.Sort == "" {
+		req.Sort = users.SortByCreatedAt
+	}
+	if req.Order == "" {
+		req.Order = "asc"
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultPageSize
+	}
+
+	query := users.ListQuery{
+		Email:      req.Email,
+		Name:       req.Name,
+		Status:     req.Status,
+		SortBy:     req.Sort,
+		Descending: req.Order == "desc",
+		Limit:      req.Limit + 1,
+	}
+
+	if req.Cursor != "" {
+		after, err := decodeCursor(req.Cursor, req.Sort, req.Order)
+		if err != nil {
+			logger.Error(ctx, "An error occured while decoding the cursor", zap.Error(err))
+			return nil, err
+		}
+		query.After = after
+	}
+
+	entities, err := s.usersRepository.List(ctx, query)
+	if err != nil {
+		logger.Error(ctx, "An error occured while listing users", zap.Error(err))
+		return nil, err
+	}
+
+	// The extra user fetched tells us if there's another page
+	page := &UsersPageDTO{Users: []UserDTO{}}
+	if len(entities) > req.Limit {
+		entities = entities[:req.Limit]
+
+		last := entities[len(entities)-1]
+		page.NextCursor, err = encodeCursor(listCursor{
+			Sort:  req.Sort,
+			Order: req.Order,
+			Value: cursorValue(last, req.Sort),
+			ID:    last.ID.String(),
+		})
+		if err != nil {
+			logger.Error(ctx, "An error occured while encoding the cursor", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(entities))
+	for _, entity := range entities {
+		ids = append(ids, entity.ID)
+	}
+
+	userRoles, err := s.rolesRepository.GetUserRoles(ctx, ids...)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the users' roles", zap.Error(err))
+		return nil, err
+	}
+
+	for _, entity := range entities {
+		page.Users = append(page.Users, parseUserToUserDTO(entity, userRoles[entity.ID]))
+	}
+
+	return page, nil
+}
+
+func (s *adminService) GetUser(ctx context.Context, userID string) (*UserDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AdminService/GetUser"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return nil, err
+	}
+
+	return s.toUserDTO(ctx, *user)
+}
+
+func (s *adminService) SuspendUser(ctx context.Context, userID string) (*UserDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AdminService/SuspendUser"))
+
+	user, err := s.updateStatus(ctx, userID, users.StatusSuspended)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens verified without looking the user up would otherwise stay usable
+	if err := s.revokeUserTokens(ctx, user.ID); err != nil {
+		logger.Error(ctx, "An error occured while revoking the user's access tokens", zap.Error(err))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *adminService) UnsuspendUser(ctx context.Context, userID string) (*UserDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AdminService/UnsuspendUser"))
+	return s.updateStatus(ctx, userID, users.StatusActive)
+}
+
+func (s *adminService) ForcePasswordReset(ctx context.Context, userID string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AdminService/ForcePasswordReset"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return err
+	}
+
+	if user.DeletedAt != nil {
+		err := errors.New("user account deleted")
+		logger.Error(ctx, err.Error())
+		return err
+	}
+
+	if err := s.usersRepository.RequirePasswordReset(ctx, user.ID); err != nil {
+		logger.Error(ctx, "An error occured while requiring a password reset", zap.Error(err))
+		return err
+	}
+
+	if err := s.revokeUserTokens(ctx, user.ID.String()); err != nil {
+		logger.Error(ctx, "An error occured while revoking the user's access tokens", zap.Error(err))
+		return err
+	}
+
+	token, err := random.Token()
+	if err != nil {
+		logger.Error(ctx, "An error occured while generating the password reset token", zap.Error(err))
+		return err
+	}
+
+	ttl := time.Second * time.Duration(s.config.Password.ResetExp)
+	err = s.passwordResetsRepository.Add(
+		ctx,
+		token,
+		password_resets.PasswordReset{UserID: user.ID.String(), ExpiresAt: time.Now().Add(ttl).Unix()},
+		ttl,
+	)
+	if err != nil {
+		logger.Error(ctx, "An error occured while storing the password reset", zap.Error(err))
+		return err
+	}
+
+	err = s.mailer.Send(
+		ctx,
+		user.Email,
+		"Reset your password",
+		fmt.Sprintf("Hi %s, you are required to reset your password. Submit the token %s to %s/reset-password to choose a new one.", user.Name, token, s.config.URL),
+	)
+	if err != nil {
+		logger.Error(ctx, "An error occured while sending the password reset email", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *adminService) DeleteUser(ctx context.Context, userID string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AdminService/DeleteUser"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return err
+	}
+
+	// Deleted users are cleaned up and purged like the self deleted ones
+	if err := s.usersService.DeleteUser(ctx, user.ID.String()); err != nil {
+		logger.Error(ctx, "An error occured while deleting the user", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *adminService) updateStatus(ctx context.Context, userID string, status string) (*UserDTO, error) {
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return nil, err
+	}
+
+	if user.DeletedAt != nil {
+		err := errors.New("user account deleted")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if err := s.usersRepository.UpdateStatus(ctx, user.ID, status); err != nil {
+		logger.Error(ctx, "An error occured while updating the user status", zap.Error(err))
+		return nil, err
+	}
+	user.Status = status
+
+	return s.toUserDTO(ctx, *user)
+}
+
+func (s *adminService) toUserDTO(ctx context.Context, user users.User) (*UserDTO, error) {
+	userRoles, err := s.rolesRepository.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user's roles", zap.Error(err))
+		return nil, err
+	}
+
+	dto := parseUserToUserDTO(user, userRoles[user.ID])
+	return &dto, nil
+}
+
+func (s *adminService) getUserById(ctx context.Context, userID string) (*users.User, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.usersRepository.GetOneById(ctx, userUUID)
+	if err != nil && strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *adminService) revokeUserTokens(ctx context.Context, userID string) error {
+	now := time.Now()
+	return s.blacklistedTokensRepository.RevokeUser(
+		ctx,
+		userID,
+		now.Unix()+1,
+		now.Add(time.Second*time.Duration(s.config.JWT.Exp)).Unix(),
+	)
+}
+
+func encodeCursor(cursor listCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(value, sort, order string) (*users.ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	if cursor.Sort != sort || cursor.Order != order {
+		return nil, errors.New("cursor does not match the requested sort order")
+	}
+
+	id, err := uuid.Parse(cursor.ID)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &users.ListCursor{Value: cursor.Value, ID: id}, nil
+}
+
+func NewAdminService(
+	config *config.Config,
+	usersRepository users.UsersRepository,
+	blacklistedTokensRepository blacklisted_tokens.BlacklistedTokensRepository,
+	passwordResetsRepository password_resets.PasswordResetsRepository,
+	rolesRepository roles.RolesRepository,
+	mailer mailer.Mailer,
+	usersService app_users.UsersService,
+) AdminService {
+	return &adminService{
+		config,
+		usersRepository,
+		blacklistedTokensRepository,
+		passwordResetsRepository,
+		rolesRepository,
+		mailer,
+		usersService,
+	}
+}
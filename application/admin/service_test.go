@@ -0,0 +1,126 @@
+package admin
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	app_users "github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/database/users"
+)
+
+type fakeUsersRepository struct {
+	users.UsersRepository
+	users []users.User
+}
+
+func (r *fakeUsersRepository) GetOneById(ctx context.Context, id uuid.UUID) (*users.User, error) {
+	for _, user := range r.users {
+		if user.ID == id {
+			return &user, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// List only sorts by name, after the cursor's name and id like the keyset query.
+func (r *fakeUsersRepository) List(ctx context.Context, query users.ListQuery) ([]users.User, error) {
+	sorted := append([]users.User{}, r.users...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].ID.String() < sorted[j].ID.String()
+	})
+
+	result := []users.User{}
+	for _, user := range sorted {
+		after := query.After == nil || user.Name > query.After.Value ||
+			(user.Name == query.After.Value && user.ID.String() > query.After.ID.String())
+		if after && len(result) < query.Limit {
+			result = append(result, user)
+		}
+	}
+	return result, nil
+}
+
+type fakeRolesRepository struct {
+	roles.RolesRepository
+}
+
+func (r *fakeRolesRepository) GetUserRoles(ctx context.Context, userIDs ...uuid.UUID) (map[uuid.UUID][]string, error) {
+	return map[uuid.UUID][]string{}, nil
+}
+
+type fakeUsersService struct {
+	app_users.UsersService
+	deleted []string
+}
+
+func (f *fakeUsersService) DeleteUser(ctx context.Context, userID string) error {
+	f.deleted = append(f.deleted, userID)
+	return nil
+}
+
+func newAdminTest(names ...string) (*fakeUsersRepository, *fakeUsersService, AdminService) {
+	usersRepository := &fakeUsersRepository{}
+	for _, name := range names {
+		usersRepository.users = append(usersRepository.users, users.User{ID: uuid.New(), Name: name, Status: users.StatusActive})
+	}
+
+	usersService := &fakeUsersService{}
+	service := NewAdminService(&config.Config{}, usersRepository, nil, nil, &fakeRolesRepository{}, nil, usersService)
+	return usersRepository, usersService, service
+}
+
+func TestListUsersPagination(t *testing.T) {
+	ctx := context.Background()
+	_, _, service := newAdminTest("Erin", "Alice", "Dave", "Bob", "Bob")
+
+	names := []string{}
+	ids := map[string]bool{}
+	req := ListUsersDTO{Sort: users.SortByName, Limit: 2}
+	for pages := 1; ; pages++ {
+		page, err := service.ListUsers(ctx, req)
+		assert.Nil(t, err)
+		for _, user := range page.Users {
+			names = append(names, user.Name)
+			ids[user.ID] = true
+		}
+
+		if page.NextCursor == "" {
+			assert.Equal(t, 3, pages)
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+
+	// Users sharing a name are told apart by their id, so none is skipped or repeated
+	assert.Equal(t, []string{"Alice", "Bob", "Bob", "Dave", "Erin"}, names)
+	assert.Len(t, ids, 5)
+
+	// Cursors are tied to the order they were issued for
+	_, err := service.ListUsers(ctx, ListUsersDTO{Sort: users.SortByEmail, Cursor: req.Cursor})
+	assert.NotNil(t, err)
+	_, err = service.ListUsers(ctx, ListUsersDTO{Sort: users.SortByName, Cursor: "invalid"})
+	assert.NotNil(t, err)
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	usersRepository, usersService, service := newAdminTest("Alice")
+	userID := usersRepository.users[0].ID.String()
+
+	assert.ErrorIs(t, service.DeleteUser(ctx, uuid.New().String()), ErrUserNotFound)
+	assert.ErrorIs(t, service.DeleteUser(ctx, "invalid"), ErrUserNotFound)
+	assert.Empty(t, usersService.deleted)
+
+	// The account is cleaned up like a self deleted one
+	assert.Nil(t, service.DeleteUser(ctx, userID))
+	assert.Equal(t, []string{userID}, usersService.deleted)
+}
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/cache"
+)
+
+var (
+	ErrMissingToken = errors.New("missing access token")
+	ErrInvalidToken = errors.New("invalid access token")
+
+	// ErrDPoPBound is returned for DPoP bound tokens, proxies can't forward the proof they need
+	ErrDPoPBound = errors.New("DPoP bound access tokens can't be used through the proxy")
+)
+
+// CacheStats counts the hits of the decisions cache, see cache.Stats.Publish.
+var CacheStats = &cache.Stats{}
+
+// GatewayService decides whether requests forwarded by a proxy, like nginx or Envoy, are authenticated.
+type GatewayService interface {
+	// Verify allows requests with a valid access token. Allowed tokens are remembered for a short while,
+	// so revocations reach the proxies after at most the cache TTL.
+	Verify(ctx context.Context, token string) (*DecisionDTO, error)
+}
+
+// DecisionDTO describes who the proxy lets the request through as.
+type DecisionDTO struct {
+	UserID    string   `json:"user_id"`
+	UserEmail string   `json:"user_email"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt int64    `json:"expires_at"`
+}
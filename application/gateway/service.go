@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/cache"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+	db_users "github.com/the-code-genin/simple-jwt-api-go/database/users"
+	"go.uber.org/zap"
+)
+
+type gatewayService struct {
+	usersService       users.UsersService
+	verificationPolicy users.VerificationPolicy
+
+	// decisions is nil when they aren't cached
+	decisions *cache.LRU[DecisionDTO]
+	ttl       time.Duration
+}
+
+func (s *gatewayService) Verify(ctx context.Context, token string) (*DecisionDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "GatewayService/Verify"))
+
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	key := decisionKey(token)
+	var generation uint64
+	if s.decisions != nil {
+		if decision, ok := s.decisions.Get(key); ok {
+			return &decision, nil
+		}
+
+		// The decision isn't cached when a revocation comes in while it is being made
+		generation = s.decisions.Generation()
+	}
+
+	accessToken, err := s.usersService.DecodeAccessToken(ctx, token, s.verificationPolicy)
+	if err != nil {
+		logger.Error(ctx, "Unable to decode user access token", zap.Error(err))
+		return nil, ErrInvalidToken
+	}
+
+	if accessToken.Confirmation != nil {
+		logger.Error(ctx, ErrDPoPBound.Error())
+		return nil, ErrDPoPBound
+	}
+
+	decision := DecisionDTO{
+		UserID:    accessToken.User.ID,
+		UserEmail: accessToken.User.Email,
+		Scopes:    accessToken.Scopes,
+		ExpiresAt: accessToken.ExpiresAt,
+	}
+
+	// Tokens are never allowed past their expiry
+	if s.decisions != nil {
+		ttl := time.Until(time.Unix(accessToken.ExpiresAt, 0))
+		if ttl > s.ttl {
+			ttl = s.ttl
+		}
+		if ttl > 0 {
+			s.decisions.SetIfCurrent(key, decision, ttl, generation)
+		}
+	}
+
+	return &decision, nil
+}
+
+// decisionKey keeps decisions by the token's hash, so the cache doesn't hold usable tokens.
+func decisionKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// listen drops every decision whenever a user or token is revoked or a user changes on any instance,
+// a decision doesn't say which token it was made for so they can't be dropped one by one.
+// Revocations are rare next to verifications, and the decisions are only kept for a short while anyway.
+func (s *gatewayService) listen(ctx context.Context, client *redis.Client, channel string) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "GatewayService/listen"))
+	client.Listen(ctx, channel, func(string) { s.decisions.Clear() }, s.decisions.Clear)
+}
+
+// NewGatewayService only caches decisions when the verification policy allows stale results,
+// strictly verified tokens are checked on every request.
+// The cached decisions follow the invalidations of the users and blacklist caches until the context is done,
+// so they are only cached when both of those caches are enabled.
+func NewGatewayService(
+	ctx context.Context,
+	config *config.Config,
+	usersService users.UsersService,
+	verificationPolicy users.VerificationPolicy,
+	client *redis.Client,
+) GatewayService {
+	service := newGatewayService(config, usersService, verificationPolicy)
+	if service.decisions != nil {
+		go service.listen(ctx, client, db_users.InvalidationsChannel)
+		go service.listen(ctx, client, blacklisted_tokens.InvalidationsChannel)
+	}
+	return service
+}
+
+func newGatewayService(
+	config *config.Config,
+	usersService users.UsersService,
+	verificationPolicy users.VerificationPolicy,
+) *gatewayService {
+	service := &gatewayService{
+		usersService:       usersService,
+		verificationPolicy: verificationPolicy,
+		ttl:                time.Duration(config.Cache.DecisionsTTL) * time.Second,
+	}
+
+	invalidated := config.Cache.UsersSize > 0 && config.Cache.BlacklistSize > 0
+	if verificationPolicy != users.VerificationStrict && invalidated && config.Cache.DecisionsSize > 0 && service.ttl > 0 {
+		service.decisions = cache.NewLRU[DecisionDTO](config.Cache.DecisionsSize, CacheStats)
+	}
+	return service
+}
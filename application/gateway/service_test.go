@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+)
+
+type fakeUsersService struct {
+	users.UsersService
+
+	decoded  int
+	tokens   map[string]users.DecodedAccessTokenDTO
+	onDecode func()
+}
+
+func (f *fakeUsersService) DecodeAccessToken(ctx context.Context, token string, policy users.VerificationPolicy) (*users.DecodedAccessTokenDTO, error) {
+	f.decoded++
+	if f.onDecode != nil {
+		f.onDecode()
+	}
+	accessToken, ok := f.tokens[token]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &accessToken, nil
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	usersService := &fakeUsersService{tokens: map[string]users.DecodedAccessTokenDTO{
+		"valid": {User: users.UserDTO{ID: "1", Email: "user@example.com"}, ExpiresAt: expiresAt},
+		"bound": {User: users.UserDTO{ID: "1"}, ExpiresAt: expiresAt, Confirmation: tokens.NewConfirmation("jkt")},
+	}}
+	cfg := &config.Config{Cache: config.CacheConfig{UsersSize: 10, BlacklistSize: 10, DecisionsSize: 10, DecisionsTTL: 30}}
+
+	service := newGatewayService(cfg, usersService, users.VerificationCached)
+
+	_, err := service.Verify(ctx, "")
+	assert.ErrorIs(t, err, ErrMissingToken)
+
+	_, err = service.Verify(ctx, "invalid")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = service.Verify(ctx, "bound")
+	assert.ErrorIs(t, err, ErrDPoPBound)
+
+	// Allowed tokens are only decoded once
+	usersService.decoded = 0
+	for i := 0; i < 2; i++ {
+		decision, err := service.Verify(ctx, "valid")
+		assert.Nil(t, err)
+		assert.Equal(t, "user@example.com", decision.UserEmail)
+	}
+	assert.Equal(t, 1, usersService.decoded)
+
+	// Revocations drop the cached decisions, along with the ones being made meanwhile
+	service.decisions.Clear()
+	usersService.onDecode = service.decisions.Clear
+	_, err = service.Verify(ctx, "valid")
+	assert.Nil(t, err)
+	usersService.onDecode = nil
+	delete(usersService.tokens, "valid")
+	_, err = service.Verify(ctx, "valid")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Strictly verified tokens are decoded on every request
+	usersService.tokens["valid"] = users.DecodedAccessTokenDTO{User: users.UserDTO{ID: "1"}, ExpiresAt: expiresAt}
+	usersService.decoded = 0
+	service = newGatewayService(cfg, usersService, users.VerificationStrict)
+	for i := 0; i < 2; i++ {
+		_, err := service.Verify(ctx, "valid")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, usersService.decoded)
+}
@@ -0,0 +1,292 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/random"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/database/device_authorizations"
+	"go.uber.org/zap"
+)
+
+const (
+	// userCodeAlphabet has no vowels or easily confused characters (RFC 8628 section 6.1)
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+
+	// slowDownIncrement is how much the polling interval grows after a slow_down error
+	slowDownIncrement = 5
+)
+
+func (s *oauthService) DeviceAuthorization(ctx context.Context, req DeviceAuthorizationRequestDTO) (*DeviceAuthorizationDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/DeviceAuthorization"))
+
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the client", zap.Error(err))
+		return nil, err
+	}
+
+	if !contains(client.GrantTypes, GrantTypeDeviceCode) {
+		err := newError(ErrorUnauthorizedClient, "client may not use the device_code grant")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	grantedScopes, err := s.scopes.Resolve(req.Scope, func(scope string) bool {
+		return contains(client.Scopes, scope)
+	})
+	if err != nil {
+		err := newError(ErrorInvalidScope, err.Error())
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	deviceCode, err := random.Token()
+	if err != nil {
+		logger.Error(ctx, "An error occured while generating the device code", zap.Error(err))
+		return nil, err
+	}
+
+	userCode, err := random.String(userCodeAlphabet, userCodeLength)
+	if err != nil {
+		logger.Error(ctx, "An error occured while generating the user code", zap.Error(err))
+		return nil, err
+	}
+
+	ttl := time.Second * time.Duration(s.config.OAuth.DeviceCodeExp)
+	authorization := device_authorizations.DeviceAuthorization{
+		ClientID:  client.ID,
+		Scopes:    grantedScopes,
+		UserCode:  userCode,
+		Status:    device_authorizations.StatusPending,
+		Interval:  s.config.OAuth.DevicePollInterval,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	if err := s.deviceAuthorizationsRepository.Add(ctx, deviceCode, authorization, ttl); err != nil {
+		logger.Error(ctx, "An error occured while storing the device authorization", zap.Error(err))
+		return nil, err
+	}
+
+	verificationURI := s.config.URL + "/oauth/device"
+	formattedUserCode := formatUserCode(userCode)
+	return &DeviceAuthorizationDTO{
+		DeviceCode:              deviceCode,
+		UserCode:                formattedUserCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: withQuery(verificationURI, url.Values{"user_code": {formattedUserCode}}),
+		ExpiresIn:               s.config.OAuth.DeviceCodeExp,
+		Interval:                authorization.Interval,
+	}, nil
+}
+
+func (s *oauthService) GetDeviceConsent(ctx context.Context, userCode string) (*ConsentDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/GetDeviceConsent"))
+
+	_, authorization, err := s.getPendingDeviceAuthorization(ctx, userCode)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the device authorization", zap.Error(err))
+		return nil, err
+	}
+
+	client, err := s.getClient(ctx, authorization.ClientID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the oauth client", zap.Error(err))
+		return nil, err
+	}
+
+	return &ConsentDTO{Client: *client, Scopes: authorization.Scopes}, nil
+}
+
+func (s *oauthService) ApproveDevice(ctx context.Context, req ApproveDeviceDTO) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/ApproveDevice"))
+
+	deviceCode, authorization, err := s.getPendingDeviceAuthorization(ctx, req.UserCode)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the device authorization", zap.Error(err))
+		return err
+	}
+
+	user, err := s.usersService.Authenticate(ctx, req.Email, req.Password)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the user", zap.Error(err))
+		return errors.New("invalid email or password")
+	}
+
+	scope, err := s.ungrantableScope(ctx, *user, authorization.Scopes)
+	if err != nil {
+		logger.Error(ctx, "An error occured while checking the user's scopes", zap.Error(err))
+		return err
+	} else if scope != "" {
+		err := fmt.Errorf("you may not grant %s", scope)
+		logger.Error(ctx, err.Error())
+		return err
+	}
+
+	return s.decideDevice(ctx, deviceCode, device_authorizations.StatusApproved, user.ID)
+}
+
+func (s *oauthService) DenyDevice(ctx context.Context, userCode string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/DenyDevice"))
+
+	deviceCode, _, err := s.getPendingDeviceAuthorization(ctx, userCode)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the device authorization", zap.Error(err))
+		return err
+	}
+
+	return s.decideDevice(ctx, deviceCode, device_authorizations.StatusDenied, "")
+}
+
+func (s *oauthService) redeemDeviceCode(ctx context.Context, req TokenRequestDTO) (*TokenDTO, error) {
+	if req.DeviceCode == "" {
+		err := newError(ErrorInvalidRequest, "device_code is required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the client", zap.Error(err))
+		return nil, err
+	}
+
+	// Decided authorizations are deleted as they are read, so concurrent polls can't both redeem an approval
+	authorization, err := s.deviceAuthorizationsRepository.Redeem(ctx, req.DeviceCode, client.ID)
+	if errors.Is(err, redis.Nil) {
+		err := newError(ErrorExpiredToken, "the device code has expired")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	} else if err != nil {
+		logger.Error(ctx, "An error occured while getting the device authorization", zap.Error(err))
+		return nil, err
+	}
+
+	if authorization.ClientID != client.ID {
+		err := newError(ErrorInvalidGrant, "device code was not issued to this client")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	switch authorization.Status {
+	case device_authorizations.StatusDenied:
+		err := newError(ErrorAccessDenied, "the user denied the request")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	case device_authorizations.StatusPending:
+		return nil, s.pollPendingDevice(ctx, req.DeviceCode, authorization)
+	}
+
+	token, err := s.usersService.IssueAccessToken(ctx, users.IssueAccessTokenDTO{
+		UserID:       authorization.UserID,
+		Scope:        scopes.Format(authorization.Scopes),
+		ClientID:     client.ID,
+		ClientScopes: client.Scopes,
+		JKT:          req.JKT,
+		Device:       req.Device,
+	})
+	if err != nil {
+		logger.Error(ctx, "An error occured while issuing the access token", zap.Error(err))
+		return nil, newError(ErrorInvalidGrant, err.Error())
+	}
+
+	return &TokenDTO{
+		AccessToken: token.AccessToken,
+		TokenType:   tokenType(req.JKT),
+		ExpiresIn:   token.ExpiresIn,
+		Scope:       token.Scope,
+	}, nil
+}
+
+// pollPendingDevice records a poll for an authorization the user hasn't acted on yet,
+// devices polling faster than the interval are told to slow down.
+func (s *oauthService) pollPendingDevice(
+	ctx context.Context,
+	deviceCode string,
+	authorization *device_authorizations.DeviceAuthorization,
+) error {
+	now := time.Now().Unix()
+	tooSoon := now-authorization.LastPolledAt < int64(authorization.Interval)
+
+	if tooSoon {
+		authorization.Interval += slowDownIncrement
+	}
+
+	// Only the poll is recorded so an approval made meanwhile isn't overwritten
+	if err := s.deviceAuthorizationsRepository.RecordPoll(ctx, deviceCode, now, authorization.Interval); err != nil {
+		logger.Error(ctx, "An error occured while recording the device poll", zap.Error(err))
+		return err
+	}
+
+	if tooSoon {
+		err := newError(ErrorSlowDown, fmt.Sprintf("poll at most every %d seconds", authorization.Interval))
+		logger.Error(ctx, err.Error())
+		return err
+	}
+
+	err := newError(ErrorAuthorizationPending, "the user has not approved the request yet")
+	logger.Info(ctx, err.Error())
+	return err
+}
+
+// decideDevice records the user's decision, unless the authorization was decided meanwhile.
+func (s *oauthService) decideDevice(ctx context.Context, deviceCode, status, userID string) error {
+	decided, err := s.deviceAuthorizationsRepository.Decide(ctx, deviceCode, status, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while updating the device authorization", zap.Error(err))
+		return err
+	} else if !decided {
+		logger.Error(ctx, ErrInvalidUserCode.Error())
+		return ErrInvalidUserCode
+	}
+
+	return nil
+}
+
+func (s *oauthService) getPendingDeviceAuthorization(
+	ctx context.Context,
+	userCode string,
+) (string, *device_authorizations.DeviceAuthorization, error) {
+	deviceCode, err := s.deviceAuthorizationsRepository.GetDeviceCode(ctx, normalizeUserCode(userCode))
+	if errors.Is(err, redis.Nil) {
+		return "", nil, ErrInvalidUserCode
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	authorization, err := s.deviceAuthorizationsRepository.Get(ctx, deviceCode)
+	if errors.Is(err, redis.Nil) {
+		return "", nil, ErrInvalidUserCode
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	if authorization.Status != device_authorizations.StatusPending {
+		return "", nil, ErrInvalidUserCode
+	}
+
+	return deviceCode, authorization, nil
+}
+
+// formatUserCode splits the user code in two halves to make it easier to type.
+func formatUserCode(userCode string) string {
+	return userCode[:len(userCode)/2] + "-" + userCode[len(userCode)/2:]
+}
+
+// normalizeUserCode undoes formatUserCode and anything else users might type along with the code.
+func normalizeUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(userCode))
+}
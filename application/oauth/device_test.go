@@ -0,0 +1,182 @@
+package oauth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/database/device_authorizations"
+	"github.com/the-code-genin/simple-jwt-api-go/database/oauth_clients"
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+)
+
+type fakeRolesRepository struct {
+	roles.RolesRepository
+}
+
+func (f *fakeRolesRepository) GetAllPermissions(ctx context.Context) ([]roles.Permission, error) {
+	return []roles.Permission{}, nil
+}
+
+// fakeDeviceAuthorizations applies every operation atomically, like the redis scripts.
+type fakeDeviceAuthorizations struct {
+	device_authorizations.DeviceAuthorizationsRepository
+	mu             sync.Mutex
+	authorizations map[string]device_authorizations.DeviceAuthorization
+}
+
+func (f *fakeDeviceAuthorizations) Add(ctx context.Context, deviceCode string, authorization device_authorizations.DeviceAuthorization, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.authorizations[deviceCode] = authorization
+	return nil
+}
+
+func (f *fakeDeviceAuthorizations) Get(ctx context.Context, deviceCode string) (*device_authorizations.DeviceAuthorization, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	authorization, ok := f.authorizations[deviceCode]
+	if !ok {
+		return nil, redis.Nil
+	}
+	return &authorization, nil
+}
+
+func (f *fakeDeviceAuthorizations) GetDeviceCode(ctx context.Context, userCode string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for deviceCode, authorization := range f.authorizations {
+		if authorization.UserCode == userCode {
+			return deviceCode, nil
+		}
+	}
+	return "", redis.Nil
+}
+
+func (f *fakeDeviceAuthorizations) Decide(ctx context.Context, deviceCode, status, userID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	authorization, ok := f.authorizations[deviceCode]
+	if !ok || authorization.Status != device_authorizations.StatusPending {
+		return false, nil
+	}
+
+	authorization.Status = status
+	authorization.UserID = userID
+	f.authorizations[deviceCode] = authorization
+	return true, nil
+}
+
+func (f *fakeDeviceAuthorizations) RecordPoll(ctx context.Context, deviceCode string, lastPolledAt int64, interval int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if authorization, ok := f.authorizations[deviceCode]; ok {
+		authorization.LastPolledAt = lastPolledAt
+		authorization.Interval = interval
+		f.authorizations[deviceCode] = authorization
+	}
+	return nil
+}
+
+func (f *fakeDeviceAuthorizations) Redeem(ctx context.Context, deviceCode, clientID string) (*device_authorizations.DeviceAuthorization, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	authorization, ok := f.authorizations[deviceCode]
+	if !ok {
+		return nil, redis.Nil
+	}
+
+	if authorization.Status != device_authorizations.StatusPending && authorization.ClientID == clientID {
+		delete(f.authorizations, deviceCode)
+	}
+	return &authorization, nil
+}
+
+func TestDeviceCodeGrant(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{OAuth: config.OAuthConfig{
+		Scopes:             []string{"profile"},
+		DeviceCodeExp:      600,
+		DevicePollInterval: 5,
+	}}
+	devices := &fakeDeviceAuthorizations{authorizations: map[string]device_authorizations.DeviceAuthorization{}}
+	service := NewOAuthService(
+		cfg,
+		scopes.NewRegistry(cfg.OAuth),
+		nil,
+		nil,
+		&fakeOAuthClients{client: oauth_clients.Client{
+			ID:         "client",
+			GrantTypes: []string{GrantTypeDeviceCode},
+			Scopes:     []string{"profile"},
+		}},
+		nil,
+		&fakeRolesRepository{},
+		devices,
+		nil,
+		&fakeUsersService{},
+	)
+
+	authorize := func() *DeviceAuthorizationDTO {
+		authorization, err := service.DeviceAuthorization(ctx, DeviceAuthorizationRequestDTO{ClientID: "client", Scope: "profile"})
+		assert.Nil(t, err)
+		return authorization
+	}
+	poll := func(deviceCode string) (*TokenDTO, string) {
+		token, err := service.Token(ctx, TokenRequestDTO{GrantType: GrantTypeDeviceCode, ClientID: "client", DeviceCode: deviceCode})
+		if err != nil {
+			return nil, err.(*Error).Code
+		}
+		return token, ""
+	}
+
+	authorization := authorize()
+	_, code := poll(authorization.DeviceCode)
+	assert.Equal(t, ErrorAuthorizationPending, code)
+
+	// Polling again right away slows the device down
+	_, code = poll(authorization.DeviceCode)
+	assert.Equal(t, ErrorSlowDown, code)
+	pending, err := devices.Get(ctx, authorization.DeviceCode)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, pending.Interval)
+
+	assert.NotNil(t, service.ApproveDevice(ctx, ApproveDeviceDTO{UserCode: authorization.UserCode, Email: "user@example.com", Password: "wrong"}))
+	assert.Nil(t, service.ApproveDevice(ctx, ApproveDeviceDTO{UserCode: authorization.UserCode, Email: "user@example.com", Password: "password"}))
+	assert.ErrorIs(t, service.DenyDevice(ctx, authorization.UserCode), ErrInvalidUserCode)
+
+	// Concurrent polls redeem the approval once
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	issued, expired := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, code := poll(authorization.DeviceCode)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if token != nil {
+				issued++
+			} else if code == ErrorExpiredToken {
+				expired++
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, issued)
+	assert.Equal(t, 9, expired)
+
+	denied := authorize()
+	assert.Nil(t, service.DenyDevice(ctx, denied.UserCode))
+	_, code = poll(denied.DeviceCode)
+	assert.Equal(t, ErrorAccessDenied, code)
+	_, code = poll(denied.DeviceCode)
+	assert.Equal(t, ErrorExpiredToken, code)
+}
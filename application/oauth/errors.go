@@ -0,0 +1,79 @@
+package oauth
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Error codes from RFC 6749 sections 4.1.2.1 and 5.2, RFC 8628 section 3.5 and RFC 9449 section 5.
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorAccessDenied            = "access_denied"
+	ErrorServerError             = "server_error"
+	ErrorAuthorizationPending    = "authorization_pending"
+	ErrorSlowDown                = "slow_down"
+	ErrorExpiredToken            = "expired_token"
+	ErrorInvalidDPoPProof        = "invalid_dpop_proof"
+)
+
+// Error is an OAuth 2.0 error response.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// RedirectError is an authorization error the client is told about through its redirect URI,
+// it is only returned once the redirect URI has been verified.
+type RedirectError struct {
+	Err         *Error
+	RedirectURI string
+}
+
+func (e *RedirectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RedirectError) Unwrap() error {
+	return e.Err
+}
+
+func newError(code, description string) *Error {
+	return &Error{code, description}
+}
+
+func newRedirectError(req AuthorizationRequestDTO, code, description string) *RedirectError {
+	params := url.Values{}
+	params.Set("error", code)
+	params.Set("error_description", description)
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	return &RedirectError{newError(code, description), withQuery(req.RedirectURI, params)}
+}
+
+// withQuery adds the params to the query of a redirect URI,
+// keeping any query the client registered the redirect URI with.
+func withQuery(redirectURI string, params url.Values) string {
+	uri, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := uri.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	uri.RawQuery = query.Encode()
+	return uri.String()
+}
@@ -0,0 +1,161 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+	"go.uber.org/zap"
+)
+
+// exchangeToken issues a narrower token for the subject of another one (RFC 8693).
+// With an actor token the new token is delegated to the actor and records it in its act claim,
+// without one the client impersonates the subject and the existing delegation chain is kept.
+func (s *oauthService) exchangeToken(ctx context.Context, req TokenRequestDTO) (*TokenDTO, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the client", zap.Error(err))
+		return nil, err
+	}
+
+	if !client.Confidential || !contains(client.GrantTypes, GrantTypeTokenExchange) {
+		err := newError(ErrorUnauthorizedClient, "client may not use the token-exchange grant")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if req.SubjectToken == "" || req.SubjectTokenType != TokenTypeAccessToken {
+		err := newError(ErrorInvalidRequest, "a subject_token of type access_token is required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if req.RequestedTokenType != "" && req.RequestedTokenType != TokenTypeAccessToken {
+		err := newError(ErrorInvalidRequest, "only access tokens can be requested")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	subject, err := s.DecodeAccessToken(ctx, req.SubjectToken)
+	if err != nil {
+		logger.Error(ctx, "An error occured while decoding the subject token", zap.Error(err))
+		return nil, newError(ErrorInvalidGrant, "invalid subject_token")
+	}
+
+	if err := checkTokenBinding(subject.Confirmation, req.JKT); err != nil {
+		logger.Error(ctx, "The subject token is DPoP bound", zap.Error(err))
+		return nil, newError(ErrorInvalidGrant, "subject_token "+err.Error())
+	}
+
+	actor := subject.Actor
+	if req.ActorToken != "" {
+		if req.ActorTokenType != TokenTypeAccessToken {
+			err := newError(ErrorInvalidRequest, "actor_token must be of type access_token")
+			logger.Error(ctx, err.Error())
+			return nil, err
+		}
+
+		actorPrincipal, err := s.DecodeAccessToken(ctx, req.ActorToken)
+		if err != nil {
+			logger.Error(ctx, "An error occured while decoding the actor token", zap.Error(err))
+			return nil, newError(ErrorInvalidGrant, "invalid actor_token")
+		}
+
+		if err := checkTokenBinding(actorPrincipal.Confirmation, req.JKT); err != nil {
+			logger.Error(ctx, "The actor token is DPoP bound", zap.Error(err))
+			return nil, newError(ErrorInvalidGrant, "actor_token "+err.Error())
+		}
+
+		// Clients can't claim to be acting as someone else's client
+		if actorPrincipal.ClientID != client.ID {
+			err := newError(ErrorInvalidGrant, "actor_token was not issued to this client")
+			logger.Error(ctx, err.Error())
+			return nil, err
+		}
+
+		actor = &tokens.Actor{
+			Subject:  actorPrincipal.Subject,
+			ClientID: actorPrincipal.ClientID,
+			Actor:    subject.Actor,
+		}
+	}
+
+	// The new token can only be narrower than the subject token
+	allowedScopes := []string{}
+	for _, scope := range subject.Scopes {
+		if contains(client.Scopes, scope) {
+			allowedScopes = append(allowedScopes, scope)
+		}
+	}
+
+	grantedScopes := allowedScopes
+	if req.Scope != "" {
+		grantedScopes, err = s.scopes.Resolve(req.Scope, func(scope string) bool {
+			return contains(allowedScopes, scope)
+		})
+		if err != nil {
+			err := newError(ErrorInvalidScope, err.Error())
+			logger.Error(ctx, err.Error())
+			return nil, err
+		}
+	}
+
+	if len(grantedScopes) == 0 {
+		err := newError(ErrorInvalidScope, "none of the subject token's scopes can be granted to the client")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	audience, err := resolveAudience(*client, req.Audience)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	var result *TokenDTO
+	if subject.Type == PrincipalTypeClient {
+		result, err = s.generateClientToken(ctx, subject.Subject, grantedScopes, audience, actor, subject.ExpiresAt, req.JKT)
+		if err != nil {
+			logger.Error(ctx, "Unable to generate token for client", zap.Error(err))
+			return nil, newError(ErrorInvalidGrant, err.Error())
+		}
+	} else {
+		token, err := s.usersService.IssueAccessToken(ctx, users.IssueAccessTokenDTO{
+			UserID:        subject.Subject,
+			Scope:         scopes.Format(grantedScopes),
+			ClientID:      client.ID,
+			ClientScopes:  grantedScopes,
+			Audience:      audience,
+			Actor:         actor,
+			ExpiresBefore: subject.ExpiresAt,
+			JKT:           req.JKT,
+			Device:        req.Device,
+		})
+		if err != nil {
+			logger.Error(ctx, "An error occured while issuing the access token", zap.Error(err))
+			return nil, newError(ErrorInvalidGrant, err.Error())
+		}
+
+		result = &TokenDTO{
+			AccessToken: token.AccessToken,
+			TokenType:   tokenType(req.JKT),
+			ExpiresIn:   token.ExpiresIn,
+			Scope:       token.Scope,
+		}
+	}
+
+	result.IssuedTokenType = TokenTypeAccessToken
+	return result, nil
+}
+
+// checkTokenBinding only lets DPoP bound tokens be exchanged with a proof of their key,
+// otherwise a sender-constrained token could be traded for a bearer one.
+func checkTokenBinding(confirmation *tokens.Confirmation, jkt string) error {
+	if confirmation != nil && confirmation.JKT != jkt {
+		return errors.New("is DPoP bound and requires a proof of its key")
+	}
+	return nil
+}
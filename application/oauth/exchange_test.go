@@ -0,0 +1,120 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/oauth_clients"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type fakeOAuthClients struct {
+	oauth_clients.OAuthClientsRepository
+	client oauth_clients.Client
+	others []oauth_clients.Client
+}
+
+func (f *fakeOAuthClients) GetOneById(ctx context.Context, id string) (*oauth_clients.Client, error) {
+	for _, client := range append([]oauth_clients.Client{f.client}, f.others...) {
+		if client.ID == id {
+			return &client, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+type fakeUsersService struct {
+	users.UsersService
+	confirmations map[string]*tokens.Confirmation
+}
+
+func (f *fakeUsersService) DecodeAccessToken(ctx context.Context, token string, policy users.VerificationPolicy) (*users.DecodedAccessTokenDTO, error) {
+	return &users.DecodedAccessTokenDTO{
+		ID:           token,
+		User:         users.UserDTO{ID: "1"},
+		Scopes:       []string{"profile"},
+		ExpiresAt:    time.Now().Add(time.Hour).Unix(),
+		Confirmation: f.confirmations[token],
+	}, nil
+}
+
+func (f *fakeUsersService) Authenticate(ctx context.Context, email, password string) (*users.UserDTO, error) {
+	if password != "password" {
+		return nil, errors.New("invalid password")
+	}
+	return &users.UserDTO{ID: "1", Email: email}, nil
+}
+
+func (f *fakeUsersService) IssueAccessToken(ctx context.Context, req users.IssueAccessTokenDTO) (*users.AccessTokenDTO, error) {
+	return &users.AccessTokenDTO{AccessToken: "exchanged", Scope: req.Scope}, nil
+}
+
+func TestExchangeDPoPBoundToken(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{JWT: config.JWTConfig{Key: "key"}, OAuth: config.OAuthConfig{Scopes: []string{"profile"}}}
+
+	keySet, err := keys.NewKeySet(cfg.JWT)
+	assert.Nil(t, err)
+	codec, err := tokens.NewJWTCodec(cfg.JWT, keySet)
+	assert.Nil(t, err)
+
+	encode := func() string {
+		token, err := codec.Encode(ctx, tokens.Claims{RegisteredClaims: tokens.NewRegisteredClaims("1", time.Hour), UserID: "1"})
+		assert.Nil(t, err)
+		return token
+	}
+	bearerToken, boundToken := encode(), encode()
+
+	secretHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.Nil(t, err)
+	hash := string(secretHash)
+
+	service := NewOAuthService(
+		cfg,
+		scopes.NewRegistry(cfg.OAuth),
+		codec,
+		keySet,
+		&fakeOAuthClients{client: oauth_clients.Client{
+			ID:         "client",
+			SecretHash: &hash,
+			GrantTypes: []string{GrantTypeTokenExchange},
+			Scopes:     []string{"profile"},
+		}},
+		nil,
+		nil,
+		nil,
+		nil,
+		&fakeUsersService{confirmations: map[string]*tokens.Confirmation{boundToken: tokens.NewConfirmation("jkt")}},
+	)
+
+	exchange := func(subjectToken, jkt string) error {
+		_, err := service.Token(ctx, TokenRequestDTO{
+			GrantType:        GrantTypeTokenExchange,
+			ClientID:         "client",
+			ClientSecret:     "secret",
+			SubjectToken:     subjectToken,
+			SubjectTokenType: TokenTypeAccessToken,
+			JKT:              jkt,
+		})
+		return err
+	}
+
+	assert.Nil(t, exchange(bearerToken, ""))
+
+	// Bound tokens can't be traded for bearer tokens or tokens bound to another key
+	err = exchange(boundToken, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrorInvalidGrant, err.(*Error).Code)
+	assert.NotNil(t, exchange(boundToken, "other"))
+
+	assert.Nil(t, exchange(boundToken, "jkt"))
+}
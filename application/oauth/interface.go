@@ -0,0 +1,269 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/oauth_clients"
+)
+
+const (
+	ResponseTypeCode = "code"
+
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	CodeChallengeMethodS256 = "S256"
+
+	PrincipalTypeUser   = "user"
+	PrincipalTypeClient = "client"
+)
+
+var (
+	ErrClientNotFound  = errors.New("oauth client not found")
+	ErrInvalidUserCode = errors.New("invalid or expired user code")
+)
+
+// OAuthService is the OAuth 2.0 authorization server,
+// it also manages the clients registered with it.
+type OAuthService interface {
+	ListClients(ctx context.Context) ([]ClientDTO, error)
+	CreateClient(ctx context.Context, req CreateClientDTO) (*ClientDTO, error)
+	DeleteClient(ctx context.Context, id string) error
+
+	// ValidateAuthorization checks an authorization request before the user is asked for consent.
+	ValidateAuthorization(ctx context.Context, req AuthorizationRequestDTO) (*ConsentDTO, error)
+	// Authorize authenticates the user and returns the redirect URI carrying the authorization code.
+	Authorize(ctx context.Context, req AuthorizeDTO) (redirectURI string, err error)
+	// Deny returns the redirect URI telling the client the user refused the authorization.
+	Deny(ctx context.Context, req AuthorizationRequestDTO) (redirectURI string, err error)
+
+	// DeviceAuthorization starts a device authorization grant (RFC 8628),
+	// the device then polls Token while the user approves the user code.
+	DeviceAuthorization(ctx context.Context, req DeviceAuthorizationRequestDTO) (*DeviceAuthorizationDTO, error)
+	GetDeviceConsent(ctx context.Context, userCode string) (*ConsentDTO, error)
+	ApproveDevice(ctx context.Context, req ApproveDeviceDTO) error
+	DenyDevice(ctx context.Context, userCode string) error
+
+	Token(ctx context.Context, req TokenRequestDTO) (*TokenDTO, error)
+	// DecodeAccessToken verifies an access token issued to either a user or a client.
+	DecodeAccessToken(ctx context.Context, token string) (*PrincipalDTO, error)
+	// Introspect describes a token to an authenticated confidential client (RFC 7662).
+	Introspect(ctx context.Context, req IntrospectDTO) (*IntrospectionDTO, error)
+
+	Discovery(ctx context.Context) *DiscoveryDTO
+	JWKS(ctx context.Context) jose.JSONWebKeySet
+	// UserInfo returns the standard claims the token's scopes allow about its user.
+	UserInfo(ctx context.Context, token users.DecodedAccessTokenDTO) (*UserInfoDTO, error)
+}
+
+type CreateClientDTO struct {
+	Name         string   `json:"name" binding:"required,max=255"`
+	Confidential bool     `json:"confidential"`
+	GrantTypes   []string `json:"grant_types" binding:"required,min=1,dive,oneof=authorization_code client_credentials urn:ietf:params:oauth:grant-type:device_code urn:ietf:params:oauth:grant-type:token-exchange"`
+	RedirectURIs []string `json:"redirect_uris" binding:"dive,url"`
+	Scopes       []string `json:"scopes" binding:"required"`
+	Audiences    []string `json:"audiences"`
+}
+
+type ClientDTO struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Confidential bool   `json:"confidential"`
+
+	// Secret is only returned once, when the client is created
+	Secret string `json:"secret,omitempty"`
+
+	GrantTypes   []string  `json:"grant_types"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Audiences    []string  `json:"audiences"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type AuthorizationRequestDTO struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+}
+
+type AuthorizeDTO struct {
+	AuthorizationRequestDTO
+
+	Email    string `form:"email"`
+	Password string `form:"password"`
+}
+
+// ConsentDTO is what the user is asked to approve.
+type ConsentDTO struct {
+	Client ClientDTO `json:"client"`
+	Scopes []string  `json:"scopes"`
+}
+
+type TokenRequestDTO struct {
+	GrantType    string   `form:"grant_type"`
+	Code         string   `form:"code"`
+	RedirectURI  string   `form:"redirect_uri"`
+	ClientID     string   `form:"client_id"`
+	ClientSecret string   `form:"client_secret"`
+	CodeVerifier string   `form:"code_verifier"`
+	DeviceCode   string   `form:"device_code"`
+	Scope        string   `form:"scope"`
+	Audience     []string `form:"audience"`
+
+	SubjectToken       string `form:"subject_token"`
+	SubjectTokenType   string `form:"subject_token_type"`
+	ActorToken         string `form:"actor_token"`
+	ActorTokenType     string `form:"actor_token_type"`
+	RequestedTokenType string `form:"requested_token_type"`
+
+	// JKT binds the issued token to the key of the request's DPoP proof
+	JKT string `form:"-"`
+
+	Device users.DeviceDTO `form:"-"`
+}
+
+type TokenDTO struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+	IDToken     string `json:"id_token,omitempty"`
+
+	// IssuedTokenType is only set for token exchanges
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+}
+
+type DeviceAuthorizationRequestDTO struct {
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+type DeviceAuthorizationDTO struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type ApproveDeviceDTO struct {
+	UserCode string `form:"user_code"`
+	Email    string `form:"email"`
+	Password string `form:"password"`
+}
+
+// PrincipalDTO is who a verified access token was issued to,
+// User is only set for user principals.
+type PrincipalDTO struct {
+	Type      string         `json:"type"`
+	Subject   string         `json:"sub"`
+	ClientID  string         `json:"client_id,omitempty"`
+	Actor     *tokens.Actor  `json:"act,omitempty"`
+	User      *users.UserDTO `json:"user,omitempty"`
+	TokenID   string         `json:"jti"`
+	Scopes    []string       `json:"scopes"`
+	Audience  []string       `json:"aud,omitempty"`
+	IssuedAt  int64          `json:"iat"`
+	ExpiresAt int64          `json:"exp"`
+
+	Confirmation *tokens.Confirmation `json:"cnf,omitempty"`
+}
+
+type IntrospectDTO struct {
+	Token        string `form:"token"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// IntrospectionDTO is the RFC 7662 introspection response,
+// only Active is set for tokens that failed verification.
+type IntrospectionDTO struct {
+	Active        bool     `json:"active"`
+	PrincipalType string   `json:"principal_type,omitempty"`
+	Scope         string   `json:"scope,omitempty"`
+	ClientID      string   `json:"client_id,omitempty"`
+	Username      string   `json:"username,omitempty"`
+	TokenType     string   `json:"token_type,omitempty"`
+	ExpiresAt     int64    `json:"exp,omitempty"`
+	IssuedAt      int64    `json:"iat,omitempty"`
+	Subject       string   `json:"sub,omitempty"`
+	Audience      []string `json:"aud,omitempty"`
+	TokenID       string   `json:"jti,omitempty"`
+
+	Actor        *tokens.Actor        `json:"act,omitempty"`
+	Confirmation *tokens.Confirmation `json:"cnf,omitempty"`
+}
+
+// DiscoveryDTO is the OpenID Connect provider metadata.
+type DiscoveryDTO struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	DPoPSigningAlgValuesSupported     []string `json:"dpop_signing_alg_values_supported"`
+}
+
+type UserInfoDTO struct {
+	Subject       string `json:"sub"`
+	Name          string `json:"name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+func parseClientToClientDTO(entity oauth_clients.Client) ClientDTO {
+	dto := ClientDTO{
+		ID:           entity.ID,
+		Name:         entity.Name,
+		Confidential: entity.SecretHash != nil,
+		GrantTypes:   entity.GrantTypes,
+		RedirectURIs: entity.RedirectURIs,
+		Scopes:       entity.Scopes,
+		Audiences:    entity.Audiences,
+		CreatedAt:    entity.CreatedAt,
+	}
+
+	if dto.GrantTypes == nil {
+		dto.GrantTypes = []string{}
+	}
+
+	if dto.RedirectURIs == nil {
+		dto.RedirectURIs = []string{}
+	}
+
+	if dto.Scopes == nil {
+		dto.Scopes = []string{}
+	}
+
+	if dto.Audiences == nil {
+		dto.Audiences = []string{}
+	}
+
+	return dto
+}
@@ -0,0 +1,110 @@
+package oauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"go.uber.org/zap"
+)
+
+// idTokenClaims are the claims of an OpenID Connect ID token.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+
+	AuthorizedParty string `json:"azp"`
+	Nonce           string `json:"nonce,omitempty"`
+	AuthTime        int64  `json:"auth_time"`
+	AccessTokenHash string `json:"at_hash"`
+
+	Name          string `json:"name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+func (s *oauthService) Discovery(ctx context.Context) *DiscoveryDTO {
+	issuer := s.config.URL
+	return &DiscoveryDTO{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserInfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		DeviceAuthorizationEndpoint:       issuer + "/oauth/device_authorization",
+		ScopesSupported:                   s.scopes.All(),
+		ResponseTypesSupported:            []string{ResponseTypeCode},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeClientCredentials, GrantTypeDeviceCode, GrantTypeTokenExchange},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{s.keySet.SigningMethod().Alg()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "azp",
+			"name", "email", "email_verified",
+		},
+		DPoPSigningAlgValuesSupported: dpop.SigningAlgorithms,
+	}
+}
+
+func (s *oauthService) JWKS(ctx context.Context) jose.JSONWebKeySet {
+	return s.keySet.JWKS()
+}
+
+func (s *oauthService) UserInfo(ctx context.Context, token users.DecodedAccessTokenDTO) (*UserInfoDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/UserInfo"))
+
+	if !scopes.Contains(token.Scopes, scopes.ScopeOpenID) {
+		err := newError(ErrorInvalidScope, "the openid scope is required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	info := &UserInfoDTO{Subject: token.User.ID}
+	setStandardClaims(token.Scopes, token.User, &info.Name, &info.Email, &info.EmailVerified)
+	return info, nil
+}
+
+// generateIDToken signs an ID token for the user an authorization code was redeemed for.
+func (s *oauthService) generateIDToken(
+	clientID string,
+	user users.UserDTO,
+	grantedScopes []string,
+	nonce string,
+	authTime int64,
+	accessToken string,
+) (string, error) {
+	claims := idTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.config.URL,
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * time.Duration(s.config.JWT.Exp))),
+		},
+		AuthorizedParty: clientID,
+		Nonce:           nonce,
+		AuthTime:        authTime,
+		AccessTokenHash: s.keySet.HashHalf(accessToken),
+	}
+	setStandardClaims(grantedScopes, user, &claims.Name, &claims.Email, &claims.EmailVerified)
+
+	return s.keySet.Sign(claims)
+}
+
+// setStandardClaims fills in the standard claims the profile and email scopes grant access to.
+func setStandardClaims(grantedScopes []string, user users.UserDTO, name, email *string, emailVerified **bool) {
+	if scopes.Contains(grantedScopes, scopes.ScopeProfile) {
+		*name = user.Name
+	}
+
+	if scopes.Contains(grantedScopes, scopes.ScopeEmail) {
+		verified := user.EmailVerified
+		*email, *emailVerified = user.Email, &verified
+	}
+}
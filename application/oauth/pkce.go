@@ -0,0 +1,24 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+var (
+	codeVerifierPattern  = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+	codeChallengePattern = regexp.MustCompile(`^[A-Za-z0-9\-_]{43}$`)
+)
+
+// verifyCodeChallenge checks a PKCE code verifier against its S256 code challenge (RFC 7636).
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if !codeVerifierPattern.MatchString(verifier) {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(digest[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
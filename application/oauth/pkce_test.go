@@ -0,0 +1,19 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// Example from RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.True(t, verifyCodeChallenge(challenge, verifier))
+	assert.False(t, verifyCodeChallenge(challenge, strings.ToUpper(verifier)))
+	assert.False(t, verifyCodeChallenge(challenge, "too-short"))
+	assert.False(t, verifyCodeChallenge(verifier, verifier))
+}
@@ -0,0 +1,700 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/random"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/authorization_codes"
+	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/device_authorizations"
+	"github.com/the-code-genin/simple-jwt-api-go/database/oauth_clients"
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type oauthService struct {
+	config                         *config.Config
+	scopes                         *scopes.Registry
+	tokenCodec                     tokens.Codec
+	keySet                         *keys.KeySet
+	oauthClientsRepository         oauth_clients.OAuthClientsRepository
+	authorizationCodesRepository   authorization_codes.AuthorizationCodesRepository
+	rolesRepository                roles.RolesRepository
+	deviceAuthorizationsRepository device_authorizations.DeviceAuthorizationsRepository
+	blacklistedTokensRepository    blacklisted_tokens.BlacklistedTokensRepository
+	usersService                   users.UsersService
+}
+
+func (s *oauthService) ListClients(ctx context.Context) ([]ClientDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/ListClients"))
+
+	entities, err := s.oauthClientsRepository.GetAll(ctx)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the oauth clients", zap.Error(err))
+		return nil, err
+	}
+
+	result := []ClientDTO{}
+	for _, entity := range entities {
+		result = append(result, parseClientToClientDTO(entity))
+	}
+	return result, nil
+}
+
+func (s *oauthService) CreateClient(ctx context.Context, req CreateClientDTO) (*ClientDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/CreateClient"))
+
+	if contains(req.GrantTypes, GrantTypeAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		err := errors.New("the authorization_code grant requires redirect uris")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	// Only a client that can keep a secret may act on its own behalf
+	if contains(req.GrantTypes, GrantTypeClientCredentials) && !req.Confidential {
+		err := errors.New("the client_credentials grant requires a confidential client")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	for _, redirectURI := range req.RedirectURIs {
+		uri, err := url.Parse(redirectURI)
+		if err != nil || !uri.IsAbs() || uri.Fragment != "" {
+			err := fmt.Errorf("invalid redirect uri %q", redirectURI)
+			logger.Error(ctx, err.Error())
+			return nil, err
+		}
+	}
+
+	for _, scope := range req.Scopes {
+		if !s.scopes.Known(scope) {
+			err := fmt.Errorf("unknown scope %q", scope)
+			logger.Error(ctx, err.Error())
+			return nil, err
+		}
+	}
+
+	client := oauth_clients.Client{
+		ID:           uuid.New().String(),
+		Name:         req.Name,
+		GrantTypes:   req.GrantTypes,
+		RedirectURIs: nonNil(req.RedirectURIs),
+		Scopes:       req.Scopes,
+		Audiences:    nonNil(req.Audiences),
+	}
+
+	var secret string
+	if req.Confidential {
+		var err error
+		secret, err = random.Token()
+		if err != nil {
+			logger.Error(ctx, "An error occured while generating the client secret", zap.Error(err))
+			return nil, err
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			logger.Error(ctx, "An error occured while hashing the client secret", zap.Error(err))
+			return nil, err
+		}
+
+		secretHash := string(hash)
+		client.SecretHash = &secretHash
+	}
+
+	if err := s.oauthClientsRepository.Create(ctx, client); err != nil {
+		logger.Error(ctx, "An error occured while creating the oauth client", zap.Error(err))
+		return nil, err
+	}
+
+	dto, err := s.getClient(ctx, client.ID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the oauth client", zap.Error(err))
+		return nil, err
+	}
+
+	dto.Secret = secret
+	return dto, nil
+}
+
+func (s *oauthService) DeleteClient(ctx context.Context, id string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/DeleteClient"))
+
+	if _, err := s.getClient(ctx, id); err != nil {
+		logger.Error(ctx, "An error occured while getting the oauth client", zap.Error(err))
+		return err
+	}
+
+	if err := s.oauthClientsRepository.Delete(ctx, id); err != nil {
+		logger.Error(ctx, "An error occured while deleting the oauth client", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *oauthService) ValidateAuthorization(ctx context.Context, req AuthorizationRequestDTO) (*ConsentDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/ValidateAuthorization"))
+
+	// Errors are only redirected to the client once the redirect URI is known to belong to it
+	client, err := s.getClient(ctx, req.ClientID)
+	if errors.Is(err, ErrClientNotFound) {
+		err := newError(ErrorInvalidClient, "unknown client")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	} else if err != nil {
+		logger.Error(ctx, "An error occured while getting the oauth client", zap.Error(err))
+		return nil, err
+	}
+
+	if !contains(client.RedirectURIs, req.RedirectURI) {
+		err := newError(ErrorInvalidRequest, "redirect_uri is not registered for the client")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if !contains(client.GrantTypes, GrantTypeAuthorizationCode) {
+		err := newRedirectError(req, ErrorUnauthorizedClient, "client may not use the authorization_code grant")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if req.ResponseType != ResponseTypeCode {
+		err := newRedirectError(req, ErrorUnsupportedResponseType, "response_type must be code")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if req.CodeChallengeMethod != CodeChallengeMethodS256 || !codeChallengePattern.MatchString(req.CodeChallenge) {
+		err := newRedirectError(req, ErrorInvalidRequest, "an S256 code_challenge is required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	grantedScopes, err := s.scopes.Resolve(req.Scope, func(scope string) bool {
+		return contains(client.Scopes, scope)
+	})
+	if err != nil {
+		err := newRedirectError(req, ErrorInvalidScope, err.Error())
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	return &ConsentDTO{Client: *client, Scopes: grantedScopes}, nil
+}
+
+func (s *oauthService) Authorize(ctx context.Context, req AuthorizeDTO) (string, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/Authorize"))
+
+	consent, err := s.ValidateAuthorization(ctx, req.AuthorizationRequestDTO)
+	if err != nil {
+		logger.Error(ctx, "Invalid authorization request", zap.Error(err))
+		return "", err
+	}
+
+	user, err := s.usersService.Authenticate(ctx, req.Email, req.Password)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the user", zap.Error(err))
+		return "", errors.New("invalid email or password")
+	}
+
+	scope, err := s.ungrantableScope(ctx, *user, consent.Scopes)
+	if err != nil {
+		logger.Error(ctx, "An error occured while checking the user's scopes", zap.Error(err))
+		return "", err
+	} else if scope != "" {
+		err := newRedirectError(req.AuthorizationRequestDTO, ErrorInvalidScope, fmt.Sprintf("user may not be granted %s", scope))
+		logger.Error(ctx, err.Error())
+		return "", err
+	}
+
+	code, err := random.Token()
+	if err != nil {
+		logger.Error(ctx, "An error occured while generating the authorization code", zap.Error(err))
+		return "", err
+	}
+
+	ttl := time.Second * time.Duration(s.config.OAuth.AuthorizationCodeExp)
+	authorization := authorization_codes.AuthorizationCode{
+		ClientID:            consent.Client.ID,
+		UserID:              user.ID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              consent.Scopes,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		AuthTime:            time.Now().Unix(),
+		ExpiresAt:           time.Now().Add(ttl).Unix(),
+	}
+	if err := s.authorizationCodesRepository.Add(ctx, code, authorization, ttl); err != nil {
+		logger.Error(ctx, "An error occured while storing the authorization code", zap.Error(err))
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return withQuery(req.RedirectURI, params), nil
+}
+
+func (s *oauthService) Deny(ctx context.Context, req AuthorizationRequestDTO) (string, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/Deny"))
+
+	if _, err := s.ValidateAuthorization(ctx, req); err != nil {
+		logger.Error(ctx, "Invalid authorization request", zap.Error(err))
+		return "", err
+	}
+
+	return newRedirectError(req, ErrorAccessDenied, "the user denied the request").RedirectURI, nil
+}
+
+func (s *oauthService) Token(ctx context.Context, req TokenRequestDTO) (*TokenDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/Token"))
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return s.redeemAuthorizationCode(ctx, req)
+	case GrantTypeClientCredentials:
+		return s.issueClientCredentialsToken(ctx, req)
+	case GrantTypeDeviceCode:
+		return s.redeemDeviceCode(ctx, req)
+	case GrantTypeTokenExchange:
+		return s.exchangeToken(ctx, req)
+	case "":
+		err := newError(ErrorInvalidRequest, "grant_type is required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	default:
+		err := newError(ErrorUnsupportedGrantType, fmt.Sprintf("unsupported grant_type %q", req.GrantType))
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+}
+
+func (s *oauthService) redeemAuthorizationCode(ctx context.Context, req TokenRequestDTO) (*TokenDTO, error) {
+	if req.Code == "" || req.RedirectURI == "" || req.CodeVerifier == "" {
+		err := newError(ErrorInvalidRequest, "code, redirect_uri and code_verifier are required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the client", zap.Error(err))
+		return nil, err
+	}
+
+	if !contains(client.GrantTypes, GrantTypeAuthorizationCode) {
+		err := newError(ErrorUnauthorizedClient, "client may not use the authorization_code grant")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	// The code is consumed even if the rest of the request turns out to be invalid
+	authorization, err := s.authorizationCodesRepository.Take(ctx, req.Code)
+	if errors.Is(err, redis.Nil) {
+		err := newError(ErrorInvalidGrant, "invalid or expired authorization code")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	} else if err != nil {
+		logger.Error(ctx, "An error occured while getting the authorization code", zap.Error(err))
+		return nil, err
+	}
+
+	if time.Now().Unix() > authorization.ExpiresAt {
+		err := newError(ErrorInvalidGrant, "invalid or expired authorization code")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if authorization.ClientID != client.ID || authorization.RedirectURI != req.RedirectURI {
+		err := newError(ErrorInvalidGrant, "authorization code was not issued to this client and redirect_uri")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if !verifyCodeChallenge(authorization.CodeChallenge, req.CodeVerifier) {
+		err := newError(ErrorInvalidGrant, "code_verifier does not match the code_challenge")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	token, err := s.usersService.IssueAccessToken(ctx, users.IssueAccessTokenDTO{
+		UserID:       authorization.UserID,
+		Scope:        scopes.Format(authorization.Scopes),
+		ClientID:     client.ID,
+		ClientScopes: client.Scopes,
+		JKT:          req.JKT,
+		Device:       req.Device,
+	})
+	if err != nil {
+		logger.Error(ctx, "An error occured while issuing the access token", zap.Error(err))
+		return nil, newError(ErrorInvalidGrant, err.Error())
+	}
+
+	result := &TokenDTO{
+		AccessToken: token.AccessToken,
+		TokenType:   tokenType(req.JKT),
+		ExpiresIn:   token.ExpiresIn,
+		Scope:       token.Scope,
+	}
+
+	grantedScopes := scopes.Parse(token.Scope)
+	if scopes.Contains(grantedScopes, scopes.ScopeOpenID) {
+		result.IDToken, err = s.generateIDToken(
+			client.ID,
+			token.User,
+			grantedScopes,
+			authorization.Nonce,
+			authorization.AuthTime,
+			token.AccessToken,
+		)
+		if err != nil {
+			logger.Error(ctx, "Unable to generate ID token", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (s *oauthService) issueClientCredentialsToken(ctx context.Context, req TokenRequestDTO) (*TokenDTO, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the client", zap.Error(err))
+		return nil, err
+	}
+
+	if !client.Confidential || !contains(client.GrantTypes, GrantTypeClientCredentials) {
+		err := newError(ErrorUnauthorizedClient, "client may not use the client_credentials grant")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	grantedScopes, err := s.scopes.Resolve(req.Scope, func(scope string) bool {
+		return contains(client.Scopes, scope)
+	})
+	if err != nil {
+		err := newError(ErrorInvalidScope, err.Error())
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	audience, err := resolveAudience(*client, req.Audience)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	token, err := s.generateClientToken(ctx, client.ID, grantedScopes, audience, nil, 0, req.JKT)
+	if err != nil {
+		logger.Error(ctx, "Unable to generate token for client", zap.Error(err))
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// generateClientToken signs a token for a client acting on its own behalf,
+// its expiry is capped at expiresBefore unless that is zero and it is DPoP bound when jkt is set.
+func (s *oauthService) generateClientToken(
+	ctx context.Context,
+	clientID string,
+	grantedScopes []string,
+	audience []string,
+	actor *tokens.Actor,
+	expiresBefore int64,
+	jkt string,
+) (*TokenDTO, error) {
+	ttl := time.Second * time.Duration(s.config.JWT.Exp)
+	if expiresBefore != 0 {
+		if remaining := time.Until(time.Unix(expiresBefore, 0)); remaining < ttl {
+			ttl = remaining
+		}
+	}
+
+	if ttl <= 0 {
+		return nil, errors.New("token would already be expired")
+	}
+
+	scope := scopes.Format(grantedScopes)
+	registeredClaims := tokens.NewRegisteredClaims(clientID, ttl)
+	registeredClaims.Issuer = s.config.URL
+	registeredClaims.Audience = audience
+	token, err := s.tokenCodec.Encode(ctx, tokens.Claims{
+		RegisteredClaims: registeredClaims,
+		Scope:            &scope,
+		ClientID:         clientID,
+		Actor:            actor,
+		Confirmation:     tokens.NewConfirmation(jkt),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenDTO{
+		AccessToken: token,
+		TokenType:   tokenType(jkt),
+		ExpiresIn:   int(ttl / time.Second),
+		Scope:       scope,
+	}, nil
+}
+
+func (s *oauthService) DecodeAccessToken(ctx context.Context, token string) (*PrincipalDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/DecodeAccessToken"))
+
+	claims, err := s.tokenCodec.Decode(ctx, token)
+	if err != nil {
+		logger.Error(ctx, "An error occured while decoding the access token", zap.Error(err))
+		return nil, err
+	}
+
+	// User tokens go through the user checks, e.g. suspension and revocation
+	if !claims.IsClient() {
+		decoded, err := s.usersService.DecodeAccessToken(ctx, token, users.VerificationStrict)
+		if err != nil {
+			logger.Error(ctx, "An error occured while decoding the user access token", zap.Error(err))
+			return nil, err
+		}
+
+		return &PrincipalDTO{
+			Type:      PrincipalTypeUser,
+			Subject:   decoded.User.ID,
+			ClientID:  decoded.ClientID,
+			Actor:     decoded.Actor,
+			User:      &decoded.User,
+			TokenID:   decoded.ID,
+			Scopes:    decoded.Scopes,
+			Audience:  decoded.Audience,
+			IssuedAt:  decoded.IssuedAt,
+			ExpiresAt: decoded.ExpiresAt,
+
+			Confirmation: decoded.Confirmation,
+		}, nil
+	}
+
+	// Deleting a client revokes its tokens
+	if _, err := s.getClient(ctx, claims.ClientID); err != nil {
+		logger.Error(ctx, "An error occured while getting the oauth client", zap.Error(err))
+		return nil, err
+	}
+
+	blacklisted, err := s.blacklistedTokensRepository.Exists(ctx, token, claims.ID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while checking blacklisted token existence", zap.Error(err))
+		return nil, err
+	}
+
+	if blacklisted {
+		err := errors.New("blacklisted access token")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	principal := &PrincipalDTO{
+		Type:      PrincipalTypeClient,
+		Subject:   claims.ClientID,
+		ClientID:  claims.ClientID,
+		Actor:     claims.Actor,
+		TokenID:   claims.ID,
+		Scopes:    []string{},
+		Audience:  claims.Audience,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+
+		Confirmation: claims.Confirmation,
+	}
+	if claims.Scope != nil {
+		principal.Scopes = scopes.Parse(*claims.Scope)
+	}
+	if claims.IssuedAt != nil {
+		principal.IssuedAt = claims.IssuedAt.Unix()
+	}
+
+	return principal, nil
+}
+
+func (s *oauthService) Introspect(ctx context.Context, req IntrospectDTO) (*IntrospectionDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "OAuthService/Introspect"))
+
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the client", zap.Error(err))
+		return nil, err
+	}
+
+	if !client.Confidential {
+		err := newError(ErrorUnauthorizedClient, "only confidential clients may introspect tokens")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if req.Token == "" {
+		err := newError(ErrorInvalidRequest, "token is required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	principal, err := s.DecodeAccessToken(ctx, req.Token)
+	if err != nil {
+		logger.Info(ctx, "Introspected an inactive token", zap.Error(err))
+		return &IntrospectionDTO{Active: false}, nil
+	}
+
+	introspection := &IntrospectionDTO{
+		Active:        true,
+		PrincipalType: principal.Type,
+		Scope:         scopes.Format(principal.Scopes),
+		ClientID:      principal.ClientID,
+		TokenType:     "Bearer",
+		ExpiresAt:     principal.ExpiresAt,
+		IssuedAt:      principal.IssuedAt,
+		Subject:       principal.Subject,
+		Audience:      principal.Audience,
+		TokenID:       principal.TokenID,
+		Actor:         principal.Actor,
+		Confirmation:  principal.Confirmation,
+	}
+	if principal.Confirmation != nil {
+		introspection.TokenType = dpop.TokenType
+	}
+	if principal.User != nil {
+		introspection.Username = principal.User.Email
+	}
+
+	return introspection, nil
+}
+
+// authenticateClient identifies the client making a token endpoint request,
+// confidential clients must also present their secret.
+func (s *oauthService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*ClientDTO, error) {
+	if clientID == "" {
+		return nil, newError(ErrorInvalidClient, "client authentication is required")
+	}
+
+	client, err := s.oauthClientsRepository.GetOneById(ctx, clientID)
+	if err != nil && strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return nil, newError(ErrorInvalidClient, "unknown client")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if client.SecretHash != nil {
+		if err := bcrypt.CompareHashAndPassword([]byte(*client.SecretHash), []byte(clientSecret)); err != nil {
+			return nil, newError(ErrorInvalidClient, "invalid client credentials")
+		}
+	}
+
+	dto := parseClientToClientDTO(*client)
+	return &dto, nil
+}
+
+func (s *oauthService) getClient(ctx context.Context, id string) (*ClientDTO, error) {
+	client, err := s.oauthClientsRepository.GetOneById(ctx, id)
+	if err != nil && strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return nil, ErrClientNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	dto := parseClientToClientDTO(*client)
+	return &dto, nil
+}
+
+// ungrantableScope returns the first of the scopes the user may not be granted,
+// scopes named after a permission are only granted to users holding it.
+func (s *oauthService) ungrantableScope(ctx context.Context, user users.UserDTO, grantedScopes []string) (string, error) {
+	permissions, err := s.rolesRepository.GetAllPermissions(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, permission := range permissions {
+		if contains(grantedScopes, permission.Name) && !contains(user.Permissions, permission.Name) {
+			return permission.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// resolveAudience validates the audiences requested for a client's token,
+// tokens are valid for every audience of the client unless narrowed down.
+func resolveAudience(client ClientDTO, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return client.Audiences, nil
+	}
+
+	for _, item := range requested {
+		if !contains(client.Audiences, item) {
+			return nil, newError(ErrorInvalidRequest, fmt.Sprintf("audience %q is not allowed for the client", item))
+		}
+	}
+	return requested, nil
+}
+
+// tokenType is the token_type of tokens issued for a request, DPoP when bound to the proof's key.
+func tokenType(jkt string) string {
+	if jkt != "" {
+		return dpop.TokenType
+	}
+	return "Bearer"
+}
+
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
+
+func contains(items []string, item string) bool {
+	for _, value := range items {
+		if value == item {
+			return true
+		}
+	}
+	return false
+}
+
+func NewOAuthService(
+	config *config.Config,
+	scopes *scopes.Registry,
+	tokenCodec tokens.Codec,
+	keySet *keys.KeySet,
+	oauthClientsRepository oauth_clients.OAuthClientsRepository,
+	authorizationCodesRepository authorization_codes.AuthorizationCodesRepository,
+	rolesRepository roles.RolesRepository,
+	deviceAuthorizationsRepository device_authorizations.DeviceAuthorizationsRepository,
+	blacklistedTokensRepository blacklisted_tokens.BlacklistedTokensRepository,
+	usersService users.UsersService,
+) OAuthService {
+	return &oauthService{
+		config,
+		scopes,
+		tokenCodec,
+		keySet,
+		oauthClientsRepository,
+		authorizationCodesRepository,
+		rolesRepository,
+		deviceAuthorizationsRepository,
+		blacklistedTokensRepository,
+		usersService,
+	}
+}
@@ -0,0 +1,138 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/database/authorization_codes"
+	"github.com/the-code-genin/simple-jwt-api-go/database/oauth_clients"
+)
+
+type fakeAuthorizationCodes struct {
+	authorization_codes.AuthorizationCodesRepository
+	codes map[string]authorization_codes.AuthorizationCode
+}
+
+func (f *fakeAuthorizationCodes) Add(ctx context.Context, code string, authorization authorization_codes.AuthorizationCode, ttl time.Duration) error {
+	f.codes[code] = authorization
+	return nil
+}
+
+func (f *fakeAuthorizationCodes) Take(ctx context.Context, code string) (*authorization_codes.AuthorizationCode, error) {
+	authorization, ok := f.codes[code]
+	if !ok {
+		return nil, redis.Nil
+	}
+	delete(f.codes, code)
+	return &authorization, nil
+}
+
+func TestAuthorizationCodeGrant(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{OAuth: config.OAuthConfig{Scopes: []string{"profile"}, AuthorizationCodeExp: 60}}
+	codes := &fakeAuthorizationCodes{codes: map[string]authorization_codes.AuthorizationCode{}}
+	service := NewOAuthService(
+		cfg,
+		scopes.NewRegistry(cfg.OAuth),
+		nil,
+		nil,
+		&fakeOAuthClients{
+			client: oauth_clients.Client{
+				ID:           "client",
+				GrantTypes:   []string{GrantTypeAuthorizationCode},
+				RedirectURIs: []string{"https://client.example.com/callback"},
+				Scopes:       []string{"profile"},
+			},
+			others: []oauth_clients.Client{{
+				ID:           "other",
+				GrantTypes:   []string{GrantTypeAuthorizationCode},
+				RedirectURIs: []string{"https://client.example.com/callback"},
+				Scopes:       []string{"profile"},
+			}},
+		},
+		codes,
+		&fakeRolesRepository{},
+		nil,
+		nil,
+		&fakeUsersService{},
+	)
+
+	// Example from RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	request := AuthorizationRequestDTO{
+		ResponseType:        ResponseTypeCode,
+		ClientID:            "client",
+		RedirectURI:         "https://client.example.com/callback",
+		Scope:               "profile",
+		State:               "state",
+		CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeMethod: CodeChallengeMethodS256,
+	}
+
+	// Unregistered redirect URIs are never redirected to
+	mismatched := request
+	mismatched.RedirectURI = "https://attacker.example.com/callback"
+	_, err := service.ValidateAuthorization(ctx, mismatched)
+	var oauthErr *Error
+	assert.True(t, errors.As(err, &oauthErr))
+	assert.Equal(t, ErrorInvalidRequest, oauthErr.Code)
+	var redirectErr *RedirectError
+	assert.False(t, errors.As(err, &redirectErr))
+
+	consent, err := service.ValidateAuthorization(ctx, request)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"profile"}, consent.Scopes)
+
+	authorize := func() string {
+		redirectURI, err := service.Authorize(ctx, AuthorizeDTO{AuthorizationRequestDTO: request, Email: "user@example.com", Password: "password"})
+		assert.Nil(t, err)
+		uri, err := url.Parse(redirectURI)
+		assert.Nil(t, err)
+		assert.Equal(t, "state", uri.Query().Get("state"))
+		return uri.Query().Get("code")
+	}
+	redeem := func(code, clientID, redirectURI string) (*TokenDTO, error) {
+		return service.Token(ctx, TokenRequestDTO{
+			GrantType:    GrantTypeAuthorizationCode,
+			Code:         code,
+			RedirectURI:  redirectURI,
+			ClientID:     clientID,
+			CodeVerifier: verifier,
+		})
+	}
+	assertInvalidGrant := func(err error) {
+		assert.True(t, errors.As(err, &oauthErr))
+		assert.Equal(t, ErrorInvalidGrant, oauthErr.Code)
+	}
+
+	_, err = service.Authorize(ctx, AuthorizeDTO{AuthorizationRequestDTO: request, Email: "user@example.com", Password: "wrong"})
+	assert.NotNil(t, err)
+	assert.Empty(t, codes.codes)
+
+	// Codes are redeemed once
+	code := authorize()
+	token, err := redeem(code, "client", request.RedirectURI)
+	assert.Nil(t, err)
+	assert.Equal(t, "profile", token.Scope)
+	_, err = redeem(code, "client", request.RedirectURI)
+	assertInvalidGrant(err)
+
+	// Codes are only redeemed by the client and redirect URI they were issued for, and consumed either way
+	code = authorize()
+	_, err = redeem(code, "other", request.RedirectURI)
+	assertInvalidGrant(err)
+	_, err = redeem(code, "client", request.RedirectURI)
+	assertInvalidGrant(err)
+
+	code = authorize()
+	_, err = redeem(code, "client", "https://client.example.com/other")
+	assertInvalidGrant(err)
+	assert.Empty(t, codes.codes)
+}
@@ -0,0 +1,73 @@
+package roles
+
+import (
+	"context"
+	"errors"
+
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// RolesService manages roles, permissions and their assignment to users.
+// Changes are reflected in the claims of newly issued access tokens,
+// the tokens of users losing a role or a permission are revoked.
+type RolesService interface {
+	ListRoles(ctx context.Context) ([]RoleDTO, error)
+	CreateRole(ctx context.Context, req CreateRoleDTO) (*RoleDTO, error)
+	SetRolePermissions(ctx context.Context, name string, req SetRolePermissionsDTO) (*RoleDTO, error)
+	DeleteRole(ctx context.Context, name string) error
+
+	ListPermissions(ctx context.Context) ([]PermissionDTO, error)
+	CreatePermission(ctx context.Context, req CreatePermissionDTO) (*PermissionDTO, error)
+
+	AssignUserRole(ctx context.Context, userID string, req AssignUserRoleDTO) error
+	RemoveUserRole(ctx context.Context, userID string, role string) error
+}
+
+type CreateRoleDTO struct {
+	Name        string   `json:"name" binding:"required,max=64"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+type SetRolePermissionsDTO struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+type CreatePermissionDTO struct {
+	Name        string `json:"name" binding:"required,max=128"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+type AssignUserRoleDTO struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type RoleDTO struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type PermissionDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func parseRoleToRoleDTO(entity roles.Role) RoleDTO {
+	dto := RoleDTO{
+		Name:        entity.Name,
+		Description: entity.Description,
+		Permissions: entity.Permissions,
+	}
+
+	if dto.Permissions == nil {
+		dto.Permissions = []string{}
+	}
+
+	return dto
+}
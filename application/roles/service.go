@@ -0,0 +1,316 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/database/users"
+	"go.uber.org/zap"
+)
+
+type rolesService struct {
+	config                      *config.Config
+	rolesRepository             roles.RolesRepository
+	usersRepository             users.UsersRepository
+	blacklistedTokensRepository blacklisted_tokens.BlacklistedTokensRepository
+}
+
+func (s *rolesService) ListRoles(ctx context.Context) ([]RoleDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "RolesService/ListRoles"))
+
+	entities, err := s.rolesRepository.GetAll(ctx)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the roles", zap.Error(err))
+		return nil, err
+	}
+
+	result := []RoleDTO{}
+	for _, entity := range entities {
+		result = append(result, parseRoleToRoleDTO(entity))
+	}
+	return result, nil
+}
+
+func (s *rolesService) CreateRole(ctx context.Context, req CreateRoleDTO) (*RoleDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "RolesService/CreateRole"))
+
+	existingRole, err := s.rolesRepository.GetOneByName(ctx, req.Name)
+	if err != nil && !strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		logger.Error(ctx, "An error occured while getting the role by name", zap.Error(err))
+		return nil, err
+	}
+
+	if existingRole != nil {
+		err := errors.New("role name taken")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if err := s.validatePermissions(ctx, req.Permissions); err != nil {
+		logger.Error(ctx, "Invalid role permissions", zap.Error(err))
+		return nil, err
+	}
+
+	role := roles.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	if err := s.rolesRepository.Create(ctx, role); err != nil {
+		logger.Error(ctx, "An error occured while creating the role", zap.Error(err))
+		return nil, err
+	}
+
+	return s.getRoleDTO(ctx, req.Name)
+}
+
+func (s *rolesService) SetRolePermissions(ctx context.Context, name string, req SetRolePermissionsDTO) (*RoleDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "RolesService/SetRolePermissions"))
+
+	role, err := s.getRoleDTO(ctx, name)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the role by name", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.validatePermissions(ctx, req.Permissions); err != nil {
+		logger.Error(ctx, "Invalid role permissions", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.rolesRepository.SetPermissions(ctx, name, req.Permissions); err != nil {
+		logger.Error(ctx, "An error occured while setting the role permissions", zap.Error(err))
+		return nil, err
+	}
+
+	// Tokens only lack the permissions added, but they still carry the ones removed
+	if removesPermissions(role.Permissions, req.Permissions) {
+		if err := s.revokeRoleUsers(ctx, name); err != nil {
+			logger.Error(ctx, "An error occured while revoking the access tokens of the role's users", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return s.getRoleDTO(ctx, name)
+}
+
+func (s *rolesService) DeleteRole(ctx context.Context, name string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "RolesService/DeleteRole"))
+
+	if name == roles.RoleAdmin {
+		err := errors.New("the admin role can't be deleted")
+		logger.Error(ctx, err.Error())
+		return err
+	}
+
+	if _, err := s.getRoleDTO(ctx, name); err != nil {
+		logger.Error(ctx, "An error occured while getting the role by name", zap.Error(err))
+		return err
+	}
+
+	// The users are looked up first as the assignments are deleted along with the role
+	userIDs, err := s.rolesRepository.GetRoleUsers(ctx, name)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the role's users", zap.Error(err))
+		return err
+	}
+
+	if err := s.rolesRepository.Delete(ctx, name); err != nil {
+		logger.Error(ctx, "An error occured while deleting the role", zap.Error(err))
+		return err
+	}
+
+	if err := s.revokeUsers(ctx, userIDs...); err != nil {
+		logger.Error(ctx, "An error occured while revoking the access tokens of the role's users", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *rolesService) ListPermissions(ctx context.Context) ([]PermissionDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "RolesService/ListPermissions"))
+
+	entities, err := s.rolesRepository.GetAllPermissions(ctx)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the permissions", zap.Error(err))
+		return nil, err
+	}
+
+	result := []PermissionDTO{}
+	for _, entity := range entities {
+		result = append(result, PermissionDTO{Name: entity.Name, Description: entity.Description})
+	}
+	return result, nil
+}
+
+func (s *rolesService) CreatePermission(ctx context.Context, req CreatePermissionDTO) (*PermissionDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "RolesService/CreatePermission"))
+
+	permission := roles.Permission{Name: req.Name, Description: req.Description}
+	if err := s.rolesRepository.CreatePermission(ctx, permission); err != nil {
+		logger.Error(ctx, "An error occured while creating the permission", zap.Error(err))
+		return nil, err
+	}
+
+	return &PermissionDTO{Name: permission.Name, Description: permission.Description}, nil
+}
+
+func (s *rolesService) AssignUserRole(ctx context.Context, userID string, req AssignUserRoleDTO) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "RolesService/AssignUserRole"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return err
+	}
+
+	if _, err := s.getRoleDTO(ctx, req.Role); err != nil {
+		logger.Error(ctx, "An error occured while getting the role by name", zap.Error(err))
+		return err
+	}
+
+	if err := s.rolesRepository.AssignToUser(ctx, user.ID, req.Role); err != nil {
+		logger.Error(ctx, "An error occured while assigning the role", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *rolesService) RemoveUserRole(ctx context.Context, userID string, role string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "RolesService/RemoveUserRole"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return err
+	}
+
+	if err := s.rolesRepository.RemoveFromUser(ctx, user.ID, role); err != nil {
+		logger.Error(ctx, "An error occured while removing the role", zap.Error(err))
+		return err
+	}
+
+	// Existing tokens still carry the removed role, so they are revoked
+	if err := s.revokeUsers(ctx, user.ID); err != nil {
+		logger.Error(ctx, "An error occured while revoking the user's access tokens", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *rolesService) revokeRoleUsers(ctx context.Context, name string) error {
+	userIDs, err := s.rolesRepository.GetRoleUsers(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return s.revokeUsers(ctx, userIDs...)
+}
+
+// revokeUsers revokes the access tokens issued to the users so far,
+// as they carry roles and permissions the users no longer hold.
+func (s *rolesService) revokeUsers(ctx context.Context, userIDs ...uuid.UUID) error {
+	now := time.Now()
+	for _, userID := range userIDs {
+		err := s.blacklistedTokensRepository.RevokeUser(
+			ctx,
+			userID.String(),
+			now.Unix()+1,
+			now.Add(time.Second*time.Duration(s.config.JWT.Exp)).Unix(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *rolesService) getRoleDTO(ctx context.Context, name string) (*RoleDTO, error) {
+	role, err := s.rolesRepository.GetOneByName(ctx, name)
+	if err != nil && strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return nil, ErrRoleNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	dto := parseRoleToRoleDTO(*role)
+	return &dto, nil
+}
+
+func (s *rolesService) getUserById(ctx context.Context, userID string) (*users.User, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.usersRepository.GetOneById(ctx, userUUID)
+	if err != nil && strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *rolesService) validatePermissions(ctx context.Context, permissions []string) error {
+	existing, err := s.rolesRepository.GetAllPermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(existing))
+	for _, permission := range existing {
+		known[permission.Name] = true
+	}
+
+	for _, permission := range permissions {
+		if !known[permission] {
+			return fmt.Errorf("unknown permission %q", permission)
+		}
+	}
+
+	return nil
+}
+
+// removesPermissions reports whether any of the current permissions is missing from the new ones.
+func removesPermissions(current, permissions []string) bool {
+	kept := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		kept[permission] = true
+	}
+
+	for _, permission := range current {
+		if !kept[permission] {
+			return true
+		}
+	}
+	return false
+}
+
+func NewRolesService(
+	config *config.Config,
+	rolesRepository roles.RolesRepository,
+	usersRepository users.UsersRepository,
+	blacklistedTokensRepository blacklisted_tokens.BlacklistedTokensRepository,
+) RolesService {
+	return &rolesService{
+		config,
+		rolesRepository,
+		usersRepository,
+		blacklistedTokensRepository,
+	}
+}
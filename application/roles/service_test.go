@@ -0,0 +1,141 @@
+package roles
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/database/users"
+)
+
+type fakeRolesRepository struct {
+	roles.RolesRepository
+	roles     map[string]roles.Role
+	userRoles map[uuid.UUID][]string
+}
+
+func (r *fakeRolesRepository) Create(ctx context.Context, role roles.Role) error {
+	r.roles[role.Name] = role
+	return nil
+}
+
+func (r *fakeRolesRepository) Delete(ctx context.Context, name string) error {
+	delete(r.roles, name)
+	for userID, names := range r.userRoles {
+		kept := []string{}
+		for _, role := range names {
+			if role != name {
+				kept = append(kept, role)
+			}
+		}
+		r.userRoles[userID] = kept
+	}
+	return nil
+}
+
+func (r *fakeRolesRepository) SetPermissions(ctx context.Context, name string, permissions []string) error {
+	role := r.roles[name]
+	role.Permissions = permissions
+	r.roles[name] = role
+	return nil
+}
+
+func (r *fakeRolesRepository) GetOneByName(ctx context.Context, name string) (*roles.Role, error) {
+	role, ok := r.roles[name]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &role, nil
+}
+
+func (r *fakeRolesRepository) GetAllPermissions(ctx context.Context) ([]roles.Permission, error) {
+	return []roles.Permission{{Name: roles.PermissionUsersRead}, {Name: roles.PermissionUsersManage}}, nil
+}
+
+func (r *fakeRolesRepository) RemoveFromUser(ctx context.Context, userID uuid.UUID, name string) error {
+	r.userRoles[userID] = nil
+	return nil
+}
+
+func (r *fakeRolesRepository) GetRoleUsers(ctx context.Context, name string) ([]uuid.UUID, error) {
+	result := []uuid.UUID{}
+	for userID, names := range r.userRoles {
+		for _, role := range names {
+			if role == name {
+				result = append(result, userID)
+			}
+		}
+	}
+	return result, nil
+}
+
+type fakeUsersRepository struct {
+	users.UsersRepository
+}
+
+func (r *fakeUsersRepository) GetOneById(ctx context.Context, id uuid.UUID) (*users.User, error) {
+	return &users.User{ID: id}, nil
+}
+
+type fakeBlacklistedTokensRepository struct {
+	blacklisted_tokens.BlacklistedTokensRepository
+	revoked map[string]int64
+}
+
+func (r *fakeBlacklistedTokensRepository) RevokeUser(ctx context.Context, userID string, before int64, expiry int64) error {
+	r.revoked[userID] = before
+	return nil
+}
+
+func TestRolesService(t *testing.T) {
+	ctx := context.Background()
+	holder, other := uuid.New(), uuid.New()
+	rolesRepository := &fakeRolesRepository{
+		roles: map[string]roles.Role{
+			roles.RoleAdmin: {Name: roles.RoleAdmin},
+			"support":       {Name: "support", Permissions: []string{roles.PermissionUsersRead}},
+		},
+		userRoles: map[uuid.UUID][]string{holder: {"support"}, other: {roles.RoleAdmin}},
+	}
+	blacklist := &fakeBlacklistedTokensRepository{revoked: map[string]int64{}}
+	service := NewRolesService(&config.Config{}, rolesRepository, &fakeUsersRepository{}, blacklist)
+
+	_, err := service.CreateRole(ctx, CreateRoleDTO{Name: "support"})
+	assert.NotNil(t, err)
+
+	_, err = service.CreateRole(ctx, CreateRoleDTO{Name: "auditor", Permissions: []string{"unknown"}})
+	assert.NotNil(t, err)
+
+	_, err = service.SetRolePermissions(ctx, "missing", SetRolePermissionsDTO{})
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+
+	// Adding permissions leaves existing tokens alone
+	role, err := service.SetRolePermissions(ctx, "support", SetRolePermissionsDTO{
+		Permissions: []string{roles.PermissionUsersRead, roles.PermissionUsersManage},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, role.Permissions, 2)
+	assert.Empty(t, blacklist.revoked)
+
+	// Removing one revokes the tokens of the role's users only
+	_, err = service.SetRolePermissions(ctx, "support", SetRolePermissionsDTO{Permissions: []string{roles.PermissionUsersRead}})
+	assert.Nil(t, err)
+	assert.Len(t, blacklist.revoked, 1)
+	assert.Greater(t, blacklist.revoked[holder.String()], time.Now().Unix())
+
+	blacklist.revoked = map[string]int64{}
+	assert.NotNil(t, service.DeleteRole(ctx, roles.RoleAdmin))
+	assert.Nil(t, service.DeleteRole(ctx, "support"))
+	assert.Contains(t, blacklist.revoked, holder.String())
+	assert.NotContains(t, blacklist.revoked, other.String())
+
+	blacklist.revoked = map[string]int64{}
+	assert.Nil(t, service.RemoveUserRole(ctx, other.String(), roles.RoleAdmin))
+	assert.Contains(t, blacklist.revoked, other.String())
+}
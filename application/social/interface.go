@@ -0,0 +1,77 @@
+package social
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/database/user_identities"
+)
+
+var (
+	ErrProviderNotFound = errors.New("identity provider not found")
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrInvalidState     = errors.New("invalid or expired login state")
+	ErrEmailNotVerified = errors.New("the identity provider has not verified the email")
+	ErrIdentityTaken    = errors.New("the identity is linked to another user")
+
+	// ErrLinkRequired is returned when an account with the email exists but could not be linked automatically,
+	// the user has to sign in and link the provider themselves.
+	ErrLinkRequired = errors.New("an account with this email exists, sign in to link the identity provider")
+)
+
+// SocialService signs users in with external OAuth and OpenID Connect identity providers.
+type SocialService interface {
+	ListProviders(ctx context.Context) []string
+
+	// Login returns the URL the user is sent to to sign in with the provider.
+	Login(ctx context.Context, provider string) (*AuthorizationURLDTO, error)
+	// Link returns the URL the user is sent to to link the provider to their account.
+	Link(ctx context.Context, userID, provider string) (*AuthorizationURLDTO, error)
+	// Callback completes a login or link once the provider redirects the user back.
+	Callback(ctx context.Context, provider string, req CallbackDTO) (*SocialLoginDTO, error)
+
+	ListIdentities(ctx context.Context, userID string) ([]IdentityDTO, error)
+	Unlink(ctx context.Context, userID, provider string) error
+}
+
+type CallbackDTO struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+
+	// StateCookie is the state stored in the browser the login was started in, it has to match State
+	StateCookie string          `form:"-"`
+	Device      users.DeviceDTO `form:"-"`
+}
+
+type AuthorizationURLDTO struct {
+	URL string `json:"url"`
+
+	// State is stored in a cookie so the callback is only accepted from the same browser
+	State string `json:"-"`
+}
+
+type IdentityDTO struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SocialLoginDTO is the outcome of a callback, Token is only set for logins.
+type SocialLoginDTO struct {
+	Identity IdentityDTO           `json:"identity"`
+	Token    *users.AccessTokenDTO `json:"token,omitempty"`
+}
+
+func parseIdentityToIdentityDTO(entity user_identities.Identity) IdentityDTO {
+	return IdentityDTO{
+		Provider:  entity.Provider,
+		Subject:   entity.Subject,
+		Email:     entity.Email,
+		CreatedAt: entity.CreatedAt,
+	}
+}
@@ -0,0 +1,268 @@
+package social
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+
+	googleIssuer = "https://accounts.google.com"
+	githubAPIURL = "https://api.github.com"
+)
+
+// Provider is an external identity provider users can sign in with.
+type Provider interface {
+	Name() string
+
+	// AuthCodeURL is where the user is sent to sign in with the provider,
+	// the code verifier is only sent as its S256 challenge.
+	AuthCodeURL(state, codeVerifier, nonce string) string
+	// Exchange redeems the authorization code for the user's identity with the provider.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// Identity is who the user is with an identity provider.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type oidcProvider struct {
+	name     string
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func (p *oidcProvider) Name() string {
+	return p.name
+}
+
+func (p *oidcProvider) AuthCodeURL(state, codeVerifier, nonce string) string {
+	return p.oauth.AuthCodeURL(state, append(pkceParams(codeVerifier), oidc.Nonce(nonce))...)
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("no id_token in the token response")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+// NewOIDCProvider discovers the OpenID Connect provider at the issuer.
+func NewOIDCProvider(
+	ctx context.Context,
+	name string,
+	issuer string,
+	clientID string,
+	clientSecret string,
+	redirectURL string,
+	scopes []string,
+) (Provider, error) {
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidcProvider{
+		name: name,
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  redirectURL,
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+	}, nil
+}
+
+// githubProvider signs users in with GitHub which is OAuth 2.0 only,
+// the identity is read from its REST API.
+type githubProvider struct {
+	oauth  oauth2.Config
+	apiURL string
+}
+
+func (p *githubProvider) Name() string {
+	return ProviderGitHub
+}
+
+func (p *githubProvider) AuthCodeURL(state, codeVerifier, nonce string) string {
+	return p.oauth.AuthCodeURL(state, pkceParams(codeVerifier)...)
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+	client := p.oauth.Client(ctx, token)
+
+	var user struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := getJSON(ctx, client, p.apiURL+"/user", &user); err != nil {
+		return nil, err
+	}
+
+	// The profile email is optional, the primary email says whether it was verified
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, client, p.apiURL+"/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject: strconv.FormatInt(user.ID, 10),
+		Name:    user.Name,
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+		}
+	}
+
+	return identity, nil
+}
+
+func NewGitHubProvider(clientID, clientSecret, redirectURL string) Provider {
+	return &githubProvider{
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     github.Endpoint,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+		},
+		apiURL: githubAPIURL,
+	}
+}
+
+// NewProviders creates the identity providers enabled in the configuration.
+func NewProviders(ctx context.Context, cfg *config.Config) ([]Provider, error) {
+	providers := []Provider{}
+
+	if cfg.Social.GoogleClientID != "" {
+		provider, err := NewOIDCProvider(
+			ctx,
+			ProviderGoogle,
+			googleIssuer,
+			cfg.Social.GoogleClientID,
+			cfg.Social.GoogleClientSecret,
+			callbackURL(cfg, ProviderGoogle),
+			[]string{oidc.ScopeOpenID, "profile", "email"},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("google: %w", err)
+		}
+		providers = append(providers, provider)
+	}
+
+	if cfg.Social.GitHubClientID != "" {
+		providers = append(providers, NewGitHubProvider(
+			cfg.Social.GitHubClientID,
+			cfg.Social.GitHubClientSecret,
+			callbackURL(cfg, ProviderGitHub),
+		))
+	}
+
+	if cfg.Social.OIDCClientID != "" {
+		provider, err := NewOIDCProvider(
+			ctx,
+			cfg.Social.OIDCName,
+			cfg.Social.OIDCIssuer,
+			cfg.Social.OIDCClientID,
+			cfg.Social.OIDCClientSecret,
+			callbackURL(cfg, cfg.Social.OIDCName),
+			cfg.Social.OIDCScopes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.Social.OIDCName, err)
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+func callbackURL(cfg *config.Config, provider string) string {
+	return fmt.Sprintf("%s/auth/%s/callback", cfg.URL, provider)
+}
+
+func pkceParams(codeVerifier string) []oauth2.AuthCodeOption {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(hash[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	}
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, url)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
@@ -0,0 +1,335 @@
+package social
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/random"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/database/social_logins"
+	"github.com/the-code-genin/simple-jwt-api-go/database/user_identities"
+	db_users "github.com/the-code-genin/simple-jwt-api-go/database/users"
+	"go.uber.org/zap"
+)
+
+type socialService struct {
+	config                   *config.Config
+	providers                []Provider
+	usersRepository          db_users.UsersRepository
+	userIdentitiesRepository user_identities.UserIdentitiesRepository
+	socialLoginsRepository   social_logins.SocialLoginsRepository
+	usersService             users.UsersService
+}
+
+func (s *socialService) ListProviders(ctx context.Context) []string {
+	names := make([]string, 0, len(s.providers))
+	for _, provider := range s.providers {
+		names = append(names, provider.Name())
+	}
+	return names
+}
+
+func (s *socialService) Login(ctx context.Context, provider string) (*AuthorizationURLDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "SocialService/Login"))
+
+	authURL, err := s.startLogin(ctx, provider, "")
+	if err != nil {
+		logger.Error(ctx, "An error occured while starting the login", zap.Error(err))
+		return nil, err
+	}
+
+	return authURL, nil
+}
+
+func (s *socialService) Link(ctx context.Context, userID, provider string) (*AuthorizationURLDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "SocialService/Link"))
+
+	authURL, err := s.startLogin(ctx, provider, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while starting the link", zap.Error(err))
+		return nil, err
+	}
+
+	return authURL, nil
+}
+
+func (s *socialService) Callback(ctx context.Context, providerName string, req CallbackDTO) (*SocialLoginDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "SocialService/Callback"))
+
+	provider, err := s.getProvider(providerName)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	// A callback opened in another browser, e.g. from a link an attacker sent, is rejected
+	if subtle.ConstantTimeCompare([]byte(req.State), []byte(req.StateCookie)) != 1 {
+		logger.Error(ctx, "The state doesn't match the browser's state cookie")
+		return nil, ErrInvalidState
+	}
+
+	// The state can only be used once, even when the provider reports an error
+	login, err := s.socialLoginsRepository.Take(ctx, req.State)
+	if errors.Is(err, redis.Nil) {
+		logger.Error(ctx, ErrInvalidState.Error())
+		return nil, ErrInvalidState
+	} else if err != nil {
+		logger.Error(ctx, "An error occured while getting the login", zap.Error(err))
+		return nil, err
+	}
+
+	if login.Provider != provider.Name() || login.ExpiresAt < time.Now().Unix() {
+		logger.Error(ctx, ErrInvalidState.Error())
+		return nil, ErrInvalidState
+	}
+
+	if req.Error != "" {
+		err := fmt.Errorf("%s: %s %s", provider.Name(), req.Error, req.ErrorDescription)
+		logger.Error(ctx, "The identity provider returned an error", zap.Error(err))
+		return nil, err
+	}
+
+	identity, err := provider.Exchange(ctx, req.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		logger.Error(ctx, "An error occured while exchanging the code with the identity provider", zap.Error(err))
+		return nil, err
+	}
+
+	existing, err := s.userIdentitiesRepository.GetOne(ctx, provider.Name(), identity.Subject)
+	if err != nil && !strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		logger.Error(ctx, "An error occured while getting the identity", zap.Error(err))
+		return nil, err
+	}
+
+	if login.LinkUserID != "" {
+		linked, err := s.linkIdentity(ctx, login.LinkUserID, provider.Name(), *identity, existing)
+		if err != nil {
+			logger.Error(ctx, "An error occured while linking the identity", zap.Error(err))
+			return nil, err
+		}
+
+		return &SocialLoginDTO{Identity: parseIdentityToIdentityDTO(*linked)}, nil
+	}
+
+	if existing == nil {
+		existing, err = s.signUp(ctx, provider.Name(), *identity)
+		if err != nil {
+			logger.Error(ctx, "An error occured while signing up the user", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	token, err := s.usersService.IssueAccessToken(ctx, users.IssueAccessTokenDTO{
+		UserID: existing.UserID.String(),
+		Device: req.Device,
+	})
+	if err != nil {
+		logger.Error(ctx, "An error occured while issuing the access token", zap.Error(err))
+		return nil, err
+	}
+
+	return &SocialLoginDTO{
+		Identity: parseIdentityToIdentityDTO(*existing),
+		Token:    token,
+	}, nil
+}
+
+func (s *socialService) ListIdentities(ctx context.Context, userID string) ([]IdentityDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "SocialService/ListIdentities"))
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Error(ctx, "Invalid user id", zap.Error(err))
+		return nil, err
+	}
+
+	identities, err := s.userIdentitiesRepository.GetAllByUserId(ctx, userUUID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user identities", zap.Error(err))
+		return nil, err
+	}
+
+	result := make([]IdentityDTO, 0, len(identities))
+	for _, identity := range identities {
+		result = append(result, parseIdentityToIdentityDTO(identity))
+	}
+
+	return result, nil
+}
+
+func (s *socialService) Unlink(ctx context.Context, userID, provider string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "SocialService/Unlink"))
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Error(ctx, "Invalid user id", zap.Error(err))
+		return err
+	}
+
+	if err := s.userIdentitiesRepository.Delete(ctx, userUUID, provider); err != nil {
+		logger.Error(ctx, "An error occured while deleting the identity", zap.Error(err))
+		return ErrIdentityNotFound
+	}
+
+	return nil
+}
+
+// startLogin stores the state of a login with the provider and returns the URL to send the user to,
+// the user is linking the provider to their account when userID is set.
+func (s *socialService) startLogin(ctx context.Context, providerName, userID string) (*AuthorizationURLDTO, error) {
+	provider, err := s.getProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := random.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	codeVerifier, err := random.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := random.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Second * time.Duration(s.config.Social.LoginExp)
+	login := social_logins.SocialLogin{
+		Provider:     provider.Name(),
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		LinkUserID:   userID,
+		ExpiresAt:    time.Now().Add(ttl).Unix(),
+	}
+	if err := s.socialLoginsRepository.Add(ctx, state, login, ttl); err != nil {
+		return nil, err
+	}
+
+	return &AuthorizationURLDTO{URL: provider.AuthCodeURL(state, codeVerifier, nonce), State: state}, nil
+}
+
+// linkIdentity links the identity to the user unless it already belongs to someone else.
+func (s *socialService) linkIdentity(
+	ctx context.Context,
+	userID string,
+	provider string,
+	identity Identity,
+	existing *user_identities.Identity,
+) (*user_identities.Identity, error) {
+	if existing != nil {
+		if existing.UserID.String() != userID {
+			return nil, ErrIdentityTaken
+		}
+		return existing, nil
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := user_identities.Identity{
+		Provider:  provider,
+		Subject:   identity.Subject,
+		UserID:    userUUID,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	}
+	if err := s.userIdentitiesRepository.Create(ctx, linked); err != nil {
+		return nil, err
+	}
+
+	return &linked, nil
+}
+
+// signUp links a new identity to the user with the same email, or to a new user if there is none.
+// Only verified emails are trusted on both sides, otherwise whoever registered the email first
+// could take over the account.
+func (s *socialService) signUp(ctx context.Context, provider string, identity Identity) (*user_identities.Identity, error) {
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	user, err := s.usersRepository.GetOneByEmail(ctx, identity.Email)
+	if err != nil && !strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return nil, err
+	}
+
+	if user != nil {
+		if !user.EmailVerified || user.DeletedAt != nil {
+			return nil, ErrLinkRequired
+		}
+		return s.linkIdentity(ctx, user.ID.String(), provider, identity, nil)
+	}
+
+	// Social users can't sign in with a password until they set one through a password reset
+	password, err := random.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := users.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = strings.Split(identity.Email, "@")[0]
+	}
+
+	newUser := db_users.User{
+		ID:            uuid.New(),
+		Name:          name,
+		Email:         identity.Email,
+		EmailVerified: true,
+		Password:      hashedPassword,
+		Status:        db_users.StatusActive,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.usersRepository.Create(ctx, newUser); err != nil {
+		return nil, err
+	}
+
+	return s.linkIdentity(ctx, newUser.ID.String(), provider, identity, nil)
+}
+
+func (s *socialService) getProvider(name string) (Provider, error) {
+	for _, provider := range s.providers {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, ErrProviderNotFound
+}
+
+func NewSocialService(
+	config *config.Config,
+	providers []Provider,
+	usersRepository db_users.UsersRepository,
+	userIdentitiesRepository user_identities.UserIdentitiesRepository,
+	socialLoginsRepository social_logins.SocialLoginsRepository,
+	usersService users.UsersService,
+) SocialService {
+	return &socialService{
+		config,
+		providers,
+		usersRepository,
+		userIdentitiesRepository,
+		socialLoginsRepository,
+		usersService,
+	}
+}
@@ -0,0 +1,359 @@
+package social
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/database/social_logins"
+	"github.com/the-code-genin/simple-jwt-api-go/database/user_identities"
+	db_users "github.com/the-code-genin/simple-jwt-api-go/database/users"
+)
+
+// mockOIDCProvider is a local OpenID Connect provider which signs in whichever account it is told to.
+type mockOIDCProvider struct {
+	*httptest.Server
+
+	t      *testing.T
+	keySet *keys.KeySet
+	mu     sync.Mutex
+	codes  map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	account       Identity
+	codeChallenge string
+	nonce         string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+
+	mock := &mockOIDCProvider{t: t, keySet: keySet, codes: map[string]mockAuthorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                mock.URL,
+			"authorization_endpoint":                mock.URL + "/authorize",
+			"token_endpoint":                        mock.URL + "/token",
+			"jwks_uri":                              mock.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{keySet.SigningMethod().Alg()},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, keySet.JWKS())
+	})
+	mux.HandleFunc("/token", mock.token)
+	mock.Server = httptest.NewServer(mux)
+	t.Cleanup(mock.Close)
+
+	return mock
+}
+
+// authorize signs the account in at the authorization URL and returns the code the provider redirects with.
+func (m *mockOIDCProvider) authorize(authURL string, account Identity) (code, state string) {
+	parsed, err := url.Parse(authURL)
+	assert.Nil(m.t, err)
+	query := parsed.Query()
+	assert.Equal(m.t, "S256", query.Get("code_challenge_method"))
+
+	code = uuid.NewString()
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{account, query.Get("code_challenge"), query.Get("nonce")}
+	m.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func (m *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	authorization, ok := m.codes[r.FormValue("code")]
+	delete(m.codes, r.FormValue("code"))
+	m.mu.Unlock()
+
+	hash := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(hash[:]) != authorization.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := m.keySet.Sign(jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            authorization.account.Subject,
+		"aud":            "client",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"nonce":          authorization.nonce,
+		"email":          authorization.account.Email,
+		"email_verified": authorization.account.EmailVerified,
+		"name":           authorization.account.Name,
+	})
+	assert.Nil(m.t, err)
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": uuid.NewString(),
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+type fakeSocialLogins struct {
+	logins map[string]social_logins.SocialLogin
+}
+
+func (f *fakeSocialLogins) Add(ctx context.Context, state string, login social_logins.SocialLogin, ttl time.Duration) error {
+	f.logins[state] = login
+	return nil
+}
+
+func (f *fakeSocialLogins) Take(ctx context.Context, state string) (*social_logins.SocialLogin, error) {
+	login, ok := f.logins[state]
+	if !ok {
+		return nil, redis.Nil
+	}
+	delete(f.logins, state)
+	return &login, nil
+}
+
+type fakeUserIdentities struct {
+	identities []user_identities.Identity
+}
+
+func (f *fakeUserIdentities) Create(ctx context.Context, identity user_identities.Identity) error {
+	f.identities = append(f.identities, identity)
+	return nil
+}
+
+func (f *fakeUserIdentities) Delete(ctx context.Context, userID uuid.UUID, provider string) error {
+	for i, identity := range f.identities {
+		if identity.UserID == userID && identity.Provider == provider {
+			f.identities = append(f.identities[:i], f.identities[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (f *fakeUserIdentities) GetOne(ctx context.Context, provider, subject string) (*user_identities.Identity, error) {
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeUserIdentities) GetAllByUserId(ctx context.Context, userID uuid.UUID) ([]user_identities.Identity, error) {
+	result := []user_identities.Identity{}
+	for _, identity := range f.identities {
+		if identity.UserID == userID {
+			result = append(result, identity)
+		}
+	}
+	return result, nil
+}
+
+type fakeUsersRepository struct {
+	db_users.UsersRepository
+	users []db_users.User
+}
+
+func (f *fakeUsersRepository) Create(ctx context.Context, user db_users.User) error {
+	f.users = append(f.users, user)
+	return nil
+}
+
+func (f *fakeUsersRepository) GetOneByEmail(ctx context.Context, email string) (*db_users.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+type fakeUsersService struct {
+	users.UsersService
+}
+
+func (f *fakeUsersService) IssueAccessToken(ctx context.Context, req users.IssueAccessTokenDTO) (*users.AccessTokenDTO, error) {
+	return &users.AccessTokenDTO{User: users.UserDTO{ID: req.UserID}, AccessToken: "token"}, nil
+}
+
+type socialTest struct {
+	provider   *mockOIDCProvider
+	logins     *fakeSocialLogins
+	identities *fakeUserIdentities
+	users      *fakeUsersRepository
+	service    SocialService
+}
+
+func newSocialTest(t *testing.T) *socialTest {
+	ctx := context.Background()
+	mock := newMockOIDCProvider(t)
+
+	provider, err := NewOIDCProvider(ctx, "mock", mock.URL, "client", "secret", "http://localhost/auth/mock/callback", []string{"openid", "email"})
+	assert.Nil(t, err)
+
+	test := &socialTest{
+		provider:   mock,
+		logins:     &fakeSocialLogins{map[string]social_logins.SocialLogin{}},
+		identities: &fakeUserIdentities{},
+		users:      &fakeUsersRepository{},
+	}
+	test.service = NewSocialService(
+		&config.Config{Social: config.SocialConfig{LoginExp: 60}},
+		[]Provider{provider},
+		test.users,
+		test.identities,
+		test.logins,
+		&fakeUsersService{},
+	)
+	return test
+}
+
+// login signs the account in with the mock provider and handles the callback.
+func (st *socialTest) login(t *testing.T, account Identity) (*SocialLoginDTO, error) {
+	ctx := context.Background()
+
+	authURL, err := st.service.Login(ctx, "mock")
+	assert.Nil(t, err)
+
+	code, state := st.provider.authorize(authURL.URL, account)
+	return st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: state, StateCookie: state})
+}
+
+func TestCallbackSignsUpNewUser(t *testing.T) {
+	st := newSocialTest(t)
+	account := Identity{Subject: "alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}
+
+	result, err := st.login(t, account)
+	assert.Nil(t, err)
+	assert.Len(t, st.users.users, 1)
+	assert.True(t, st.users.users[0].EmailVerified)
+	assert.Equal(t, "Alice", st.users.users[0].Name)
+
+	// The random password is stored like any other, so it can be replaced through a password reset
+	_, err = hex.DecodeString(st.users.users[0].Password)
+	assert.Nil(t, err)
+	assert.Equal(t, st.users.users[0].ID.String(), result.Token.User.ID)
+	assert.Equal(t, "alice", result.Identity.Subject)
+
+	// Signing in again finds the linked identity
+	result, err = st.login(t, account)
+	assert.Nil(t, err)
+	assert.Len(t, st.users.users, 1)
+	assert.Len(t, st.identities.identities, 1)
+	assert.Equal(t, st.users.users[0].ID.String(), result.Token.User.ID)
+}
+
+func TestCallbackLinksByVerifiedEmail(t *testing.T) {
+	st := newSocialTest(t)
+	user := db_users.User{ID: uuid.New(), Email: "bob@example.com", EmailVerified: true}
+	st.users.users = append(st.users.users, user)
+
+	result, err := st.login(t, Identity{Subject: "bob", Email: user.Email, EmailVerified: true})
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID.String(), result.Token.User.ID)
+	assert.Len(t, st.users.users, 1)
+
+	// Accounts whose email was never verified have to be linked explicitly
+	st.users.users[0].EmailVerified = false
+	_, err = st.login(t, Identity{Subject: "mallory", Email: user.Email, EmailVerified: true})
+	assert.ErrorIs(t, err, ErrLinkRequired)
+
+	_, err = st.login(t, Identity{Subject: "eve", Email: "eve@example.com"})
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+}
+
+func TestCallbackRejectsInvalidState(t *testing.T) {
+	st := newSocialTest(t)
+	ctx := context.Background()
+	account := Identity{Subject: "alice", Email: "alice@example.com", EmailVerified: true}
+
+	authURL, err := st.service.Login(ctx, "mock")
+	assert.Nil(t, err)
+	code, state := st.provider.authorize(authURL.URL, account)
+
+	_, err = st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: "unknown", StateCookie: "unknown"})
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	// The callback has to come from the browser the login was started in
+	_, err = st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: state})
+	assert.ErrorIs(t, err, ErrInvalidState)
+	_, err = st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: state, StateCookie: "other"})
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	_, err = st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: state, StateCookie: state})
+	assert.Nil(t, err)
+
+	_, err = st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: state, StateCookie: state})
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	// The ID token has to carry the nonce sent with the login
+	authURL, err = st.service.Login(ctx, "mock")
+	assert.Nil(t, err)
+	code, state = st.provider.authorize(authURL.URL, account)
+	login := st.logins.logins[state]
+	login.Nonce = "other"
+	st.logins.logins[state] = login
+
+	_, err = st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: state, StateCookie: state})
+	assert.NotNil(t, err)
+
+	_, err = st.service.Login(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+}
+
+func TestLinkIdentity(t *testing.T) {
+	st := newSocialTest(t)
+	ctx := context.Background()
+	userID := uuid.NewString()
+
+	authURL, err := st.service.Link(ctx, userID, "mock")
+	assert.Nil(t, err)
+	code, state := st.provider.authorize(authURL.URL, Identity{Subject: "carol", Email: "carol@example.com"})
+
+	result, err := st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: state, StateCookie: state})
+	assert.Nil(t, err)
+	assert.Nil(t, result.Token)
+	assert.Equal(t, "carol", result.Identity.Subject)
+
+	identities, err := st.service.ListIdentities(ctx, userID)
+	assert.Nil(t, err)
+	assert.Len(t, identities, 1)
+
+	// An identity can't be linked to two users
+	authURL, err = st.service.Link(ctx, uuid.NewString(), "mock")
+	assert.Nil(t, err)
+	code, state = st.provider.authorize(authURL.URL, Identity{Subject: "carol"})
+	_, err = st.service.Callback(ctx, "mock", CallbackDTO{Code: code, State: state, StateCookie: state})
+	assert.ErrorIs(t, err, ErrIdentityTaken)
+
+	assert.Nil(t, st.service.Unlink(ctx, userID, "mock"))
+	assert.ErrorIs(t, st.service.Unlink(ctx, userID, "mock"), ErrIdentityNotFound)
+}
@@ -2,42 +2,274 @@ package users
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
 	"github.com/the-code-genin/simple-jwt-api-go/database/users"
 )
 
+// VerificationPolicy is how thoroughly an access token is verified.
+type VerificationPolicy string
+
+const (
+	// VerificationStrict checks the user, blacklist and session against postgres and redis
+	VerificationStrict VerificationPolicy = "strict"
+	// VerificationCached makes the same checks but serves the user and blacklist from the in-process caches
+	VerificationCached VerificationPolicy = "cached"
+	// VerificationStateless only checks the token's signature and claims, revoked tokens are accepted until they expire
+	VerificationStateless VerificationPolicy = "stateless"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
 type UsersService interface {
 	Register(ctx context.Context, req RegisterUserDTO) (*UserDTO, error)
 
-	GenerateAccessToken(ctx context.Context, req GenerateUserAccessTokenDTO) (user *UserDTO, token string, err error)
-	DecodeAccessToken(ctx context.Context, token string) (*UserDTO, error)
+	// Authenticate verifies the user's credentials without issuing a token.
+	Authenticate(ctx context.Context, email, password string) (*UserDTO, error)
+	GenerateAccessToken(ctx context.Context, req GenerateUserAccessTokenDTO) (*AccessTokenDTO, error)
+	// IssueAccessToken issues a token for an already authenticated user,
+	// e.g. once an OAuth authorization code has been redeemed.
+	IssueAccessToken(ctx context.Context, req IssueAccessTokenDTO) (*AccessTokenDTO, error)
+	DecodeAccessToken(ctx context.Context, token string, policy VerificationPolicy) (*DecodedAccessTokenDTO, error)
 	BlacklistAccessToken(ctx context.Context, token string) error
+
+	UpdateProfile(ctx context.Context, userID string, req UpdateProfileDTO) (*UserDTO, error)
+	ChangePassword(ctx context.Context, userID string, req ChangePasswordDTO) (*AccessTokenDTO, error)
+	VerifyEmail(ctx context.Context, req VerifyEmailDTO) (*UserDTO, error)
+	// RequestPasswordReset emails a reset token to the user with the email, if any, so users can set a password
+	// without knowing the current one, e.g. when they only ever signed in with an identity provider.
+	RequestPasswordReset(ctx context.Context, req RequestPasswordResetDTO) error
+	ResetPassword(ctx context.Context, req ResetPasswordDTO) error
+
+	DeleteAccount(ctx context.Context, userID string, req DeleteAccountDTO) error
+	// DeleteUser deletes the account like DeleteAccount without the user's password, for administrators.
+	DeleteUser(ctx context.Context, userID string) error
+	PurgeDeletedUsers(ctx context.Context) (int64, error)
+	ExportData(ctx context.Context, userID string) (*UserDataExportDTO, error)
+
+	// ListSessions returns the user's active sessions, flagging the one with the current session ID.
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]SessionDTO, error)
+	// RevokeSession ends the session, its token is rejected from then on.
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	PurgeExpiredSessions(ctx context.Context) (int64, error)
 }
 
 type RegisterUserDTO struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 type GenerateUserAccessTokenDTO struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	Scope    string `json:"scope"`
+
+	// DeviceLabel names the session, it is derived from the user agent when empty
+	DeviceLabel string `json:"device_label" binding:"max=255"`
+
+	// JKT binds the token to the key of the request's DPoP proof
+	JKT string `json:"-"`
+
+	Device DeviceDTO `json:"-"`
+}
+
+type IssueAccessTokenDTO struct {
+	UserID       string
+	Scope        string
+	ClientID     string
+	ClientScopes []string
+	Audience     []string
+
+	// Actor records who the token is delegated to, see tokens.Actor
+	Actor *tokens.Actor
+
+	// ExpiresBefore caps the token's expiry, e.g. at the expiry of the token it was exchanged for
+	ExpiresBefore int64
+
+	// JKT binds the token to a DPoP key, the token is a bearer token when it is empty
+	JKT string
+
+	// Device is recorded on the session the token starts
+	Device DeviceDTO
+}
+
+// DeviceDTO describes the device a session was started from.
+type DeviceDTO struct {
+	IPAddress string
+	UserAgent string
+	Label     string
+}
+
+type AccessTokenDTO struct {
+	User        UserDTO `json:"user"`
+	AccessToken string  `json:"access_token"`
+	Type        string  `json:"type"`
+	Scope       string  `json:"scope"`
+	ExpiresIn   int     `json:"expires_in"`
+}
+
+// DecodedAccessTokenDTO is a verified access token and the user it was issued to.
+type DecodedAccessTokenDTO struct {
+	ID        string        `json:"id"`
+	ClientID  string        `json:"client_id,omitempty"`
+	Actor     *tokens.Actor `json:"act,omitempty"`
+	User      UserDTO       `json:"user"`
+	Audience  []string      `json:"audience,omitempty"`
+	Scopes    []string      `json:"scopes"`
+	IssuedAt  int64         `json:"issued_at"`
+	ExpiresAt int64         `json:"expires_at"`
+
+	// Confirmation is set for DPoP bound tokens, they are only usable with a proof signed by the key
+	Confirmation *tokens.Confirmation `json:"cnf,omitempty"`
+
+	// SessionID is empty for tokens issued before sessions were recorded
+	SessionID string `json:"session_id,omitempty"`
+}
+
+type UpdateProfileDTO struct {
+	Name  *string `json:"name" binding:"omitempty,min=1"`
+	Email *string `json:"email" binding:"omitempty,email"`
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword     string `json:"current_password" binding:"required"`
+	NewPassword         string `json:"new_password" binding:"required"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+
+	// Token is the access token the request was made with,
+	// the token reissued when the other sessions are revoked keeps its scopes and DPoP binding
+	Token  DecodedAccessTokenDTO `json:"-"`
+	Device DeviceDTO             `json:"-"`
+}
+
+type VerifyEmailDTO struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type RequestPasswordResetDTO struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type DeleteAccountDTO struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UserDataExportDTO holds everything stored about a user.
+type UserDataExportDTO struct {
+	ExportedAt          int64                         `json:"exported_at"`
+	Profile             ExportedProfileDTO            `json:"profile"`
+	EmailVerification   *ExportedEmailVerificationDTO `json:"email_verification"`
+	Roles               []string                      `json:"roles"`
+	TokensRevokedBefore *int64                        `json:"tokens_revoked_before"`
+
+	PasswordReset *ExportedPasswordResetDTO `json:"password_reset"`
+	Sessions      []ExportedSessionDTO      `json:"sessions"`
+	Identities    []ExportedIdentityDTO     `json:"identities"`
+}
+
+type ExportedProfileDTO struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Status        string `json:"status"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+type ExportedEmailVerificationDTO struct {
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type ExportedPasswordResetDTO struct {
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+type ExportedSessionDTO struct {
+	ID          string `json:"id"`
+	IPAddress   string `json:"ip_address"`
+	UserAgent   string `json:"user_agent"`
+	DeviceLabel string `json:"device_label"`
+	CreatedAt   int64  `json:"created_at"`
+	LastSeenAt  int64  `json:"last_seen_at"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+// ExportedIdentityDTO is an account with an external identity provider linked to the user.
+type ExportedIdentityDTO struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type SessionDTO struct {
+	ID          string `json:"id"`
+	IPAddress   string `json:"ip_address"`
+	UserAgent   string `json:"user_agent"`
+	DeviceLabel string `json:"device_label"`
+	Current     bool   `json:"current"`
+	CreatedAt   int64  `json:"created_at"`
+	LastSeenAt  int64  `json:"last_seen_at"`
+	ExpiresAt   int64  `json:"expires_at"`
 }
 
 type UserDTO struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+	Permissions   []string `json:"permissions"`
+}
+
+// ValidationErrors maps request fields to the rules they failed.
+type ValidationErrors map[string][]string
+
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field, violations := range e {
+		fields = append(fields, fmt.Sprintf("%s %s", field, strings.Join(violations, ", ")))
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, "; ")
+}
+
+// ParseVerificationPolicy validates the configured verification policy,
+// stateless verification describes users from claims which are only issued in confidential tokens.
+func ParseVerificationPolicy(cfg config.JWTConfig) (VerificationPolicy, error) {
+	switch policy := VerificationPolicy(cfg.VerificationPolicy); policy {
+	case VerificationStrict, VerificationCached:
+		return policy, nil
+	case VerificationStateless:
+		if !tokens.Confidential(cfg) {
+			return "", errors.New("stateless token verification requires encrypted, paseto-local or opaque tokens")
+		}
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown token verification policy %s", policy)
+	}
 }
 
 func parseUserToUserDTO(entity users.User) (*UserDTO, error) {
 	dto := UserDTO{
-		ID:    entity.ID.String(),
-		Name:  entity.Name,
-		Email: entity.Email,
+		ID:            entity.ID.String(),
+		Name:          entity.Name,
+		Email:         entity.Email,
+		EmailVerified: entity.EmailVerified,
+		Roles:         []string{},
+		Permissions:   []string{},
 	}
 
 	if strings.EqualFold(dto.ID, "") {
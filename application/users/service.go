@@ -8,26 +8,57 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/common/cache"
 	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
 	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/mailer"
+	"github.com/the-code-genin/simple-jwt-api-go/common/password"
+	"github.com/the-code-genin/simple-jwt-api-go/common/random"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
 	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/email_verifications"
+	"github.com/the-code-genin/simple-jwt-api-go/database/password_resets"
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/database/sessions"
+	"github.com/the-code-genin/simple-jwt-api-go/database/user_identities"
 	"github.com/the-code-genin/simple-jwt-api-go/database/users"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// lastSeenInterval limits how often a session's last seen time is written
+const lastSeenInterval = time.Minute
+
 type usersService struct {
-	config                      *config.Config
-	usersRepository             users.UsersRepository
-	blacklistedTokensRepository blacklisted_tokens.BlacklistedTokensRepository
+	config                       *config.Config
+	usersRepository              users.UsersRepository
+	blacklistedTokensRepository  blacklisted_tokens.BlacklistedTokensRepository
+	emailVerificationsRepository email_verifications.EmailVerificationsRepository
+	passwordResetsRepository     password_resets.PasswordResetsRepository
+	rolesRepository              roles.RolesRepository
+	sessionsRepository           sessions.SessionsRepository
+	userIdentitiesRepository     user_identities.UserIdentitiesRepository
+	passwordPolicy               *password.Policy
+	scopes                       *scopes.Registry
+	tokenCodec                   tokens.Codec
+	mailer                       mailer.Mailer
 }
 
 func (s *usersService) Register(ctx context.Context, req RegisterUserDTO) (*UserDTO, error) {
 	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/Register"))
 
+	// Enforce the password policy
+	if violations := s.passwordPolicy.Validate(req.Password, req.Name, req.Email); len(violations) != 0 {
+		err := ValidationErrors{"password": violations}
+		logger.Error(ctx, "Password does not satisfy the password policy", zap.Error(err))
+		return nil, err
+	}
+
 	// Check if the email is taken
 	existingUser, err := s.usersRepository.GetOneByEmail(ctx, req.Email)
 	if err != nil && !strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
@@ -42,7 +73,7 @@ func (s *usersService) Register(ctx context.Context, req RegisterUserDTO) (*User
 	}
 
 	// Hash the user's password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), 10)
+	hashedPassword, err := HashPassword(req.Password)
 	if err != nil {
 		logger.Error(ctx, "An error occured while hashing user password", zap.Error(err))
 		return nil, err
@@ -50,93 +81,182 @@ func (s *usersService) Register(ctx context.Context, req RegisterUserDTO) (*User
 
 	// Create the user record
 	user := users.User{
-		ID:       uuid.New(),
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: hex.EncodeToString(hashedPassword),
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Email:     req.Email,
+		Password:  hashedPassword,
+		Status:    users.StatusActive,
+		CreatedAt: time.Now(),
 	}
 	if err := s.usersRepository.Create(ctx, user); err != nil {
 		logger.Error(ctx, "An error occured while creating user", zap.Error(err))
 		return nil, err
 	}
 
+	// Ask the user to verify their email
+	if err := s.sendEmailVerification(ctx, user, user.Email); err != nil {
+		logger.Error(ctx, "An error occured while sending the email verification", zap.Error(err))
+		return nil, err
+	}
+
 	return parseUserToUserDTO(user)
 }
 
-func (s *usersService) GenerateAccessToken(ctx context.Context, req GenerateUserAccessTokenDTO) (*UserDTO, string, error) {
-	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/GenerateAccessToken"))
+func (s *usersService) Authenticate(ctx context.Context, email, password string) (*UserDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/Authenticate"))
 
 	// Get the user and verify the password
-	user, err := s.usersRepository.GetOneByEmail(ctx, req.Email)
+	user, err := s.usersRepository.GetOneByEmail(ctx, email)
 	if err != nil {
 		logger.Error(ctx, "An error occured while getting the user by email", zap.Error(err))
-		return nil, "", err
+		return nil, err
+	}
+
+	// The account's state is only revealed to someone who knows its password
+	if err := verifyPassword(user.Password, password); err != nil {
+		logger.Error(ctx, "Error while comparing hash and password", zap.Error(err))
+		return nil, err
+	}
+
+	if user.DeletedAt != nil {
+		err := errors.New("user account deleted")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if user.Status == users.StatusSuspended {
+		err := errors.New("user account suspended")
+		logger.Error(ctx, err.Error())
+		return nil, err
 	}
 
-	hashedPassword, err := hex.DecodeString(user.Password)
+	if user.PasswordResetRequired {
+		err := errors.New("password reset required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	dto, err := s.toUserDTO(ctx, *user)
 	if err != nil {
-		logger.Error(ctx, "An error occured while decoding the user password", zap.Error(err))
-		return nil, "", err
+		logger.Error(ctx, "Unable to parse user DTO", zap.Error(err))
+		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(req.Password)); err != nil {
-		logger.Error(ctx, "Error while comparing hash and password", zap.Error(err))
-		return nil, "", err
+	return dto, nil
+}
+
+func (s *usersService) GenerateAccessToken(ctx context.Context, req GenerateUserAccessTokenDTO) (*AccessTokenDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/GenerateAccessToken"))
+
+	user, err := s.Authenticate(ctx, req.Email, req.Password)
+	if err != nil {
+		logger.Error(ctx, "An error occured while authenticating the user", zap.Error(err))
+		return nil, err
 	}
 
-	// Generate JWT token
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":    user.ID.String(),
-		"user_email": user.Email,
-		"exp":        time.Now().Add(time.Second * time.Duration(s.config.JWT.Exp)).Unix(),
-	}).SignedString([]byte(s.config.JWT.Key))
+	device := req.Device
+	if req.DeviceLabel != "" {
+		device.Label = req.DeviceLabel
+	}
+
+	return s.issueAccessToken(ctx, *user, IssueAccessTokenDTO{Scope: req.Scope, JKT: req.JKT, Device: device})
+}
+
+func (s *usersService) IssueAccessToken(ctx context.Context, req IssueAccessTokenDTO) (*AccessTokenDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/IssueAccessToken"))
+
+	user, err := s.getUserById(ctx, req.UserID)
 	if err != nil {
-		logger.Error(ctx, "Unable to generate token for user", zap.Error(err))
-		return nil, "", err
+		logger.Error(ctx, "An error occured while getting the user", zap.Error(err))
+		return nil, err
 	}
 
-	dto, err := parseUserToUserDTO(*user)
+	if user.Status == users.StatusSuspended {
+		err := errors.New("user account suspended")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if user.PasswordResetRequired {
+		err := errors.New("password reset required")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	dto, err := s.toUserDTO(ctx, *user)
 	if err != nil {
 		logger.Error(ctx, "Unable to parse user DTO", zap.Error(err))
-		return nil, "", err
+		return nil, err
 	}
 
-	return dto, token, nil
+	return s.issueAccessToken(ctx, *dto, req)
 }
 
-func (s *usersService) DecodeAccessToken(ctx context.Context, token string) (*UserDTO, error) {
+func (s *usersService) DecodeAccessToken(ctx context.Context, token string, policy VerificationPolicy) (*DecodedAccessTokenDTO, error) {
 	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/DecodeAccessToken"))
 
-	// Parse JWT token
-	jwtToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWT.Key), nil
-	})
+	claims, err := s.tokenCodec.Decode(ctx, token)
 	if err != nil {
-		logger.Error(ctx, "An error occured while parsing the JWT token", zap.Error(err))
+		logger.Error(ctx, "An error occured while decoding the access token", zap.Error(err))
 		return nil, err
 	}
 
-	// Verify JWT token claims
-	claims, ok := jwtToken.Claims.(jwt.MapClaims)
-	if !ok || !jwtToken.Valid {
-		err := errors.New("invalid JWT claims")
+	// Tokens issued to clients acting on their own behalf don't represent a user
+	if claims.IsClient() || claims.UserID == "" || claims.UserEmail == "" {
+		err := errors.New("not a user access token")
 		logger.Error(ctx, err.Error())
 		return nil, err
 	}
 
-	userID, userIDOk := claims["user_id"].(string)
-	userEmail, userEmailOk := claims["user_email"].(string)
-	exp, expOk := claims["exp"].(float64)
-	if !userIDOk || !userEmailOk || !expOk {
-		err := errors.New("invalid/incomplete JWT claims")
+	// Tokens issued before the iat claim was introduced are treated as issued at the epoch
+	var iat int64
+	if claims.IssuedAt != nil {
+		iat = claims.IssuedAt.Unix()
+	}
+
+	// Tokens issued before scopes were introduced get the default scopes
+	grantedScopes := s.scopes.Defaults()
+	if claims.Scope != nil {
+		grantedScopes = scopes.Parse(*claims.Scope)
+	}
+
+	switch policy {
+	case VerificationStrict:
+		ctx = cache.Skip(ctx)
+	case VerificationCached:
+	case VerificationStateless:
+		// The user is described by the token alone
+		if time.Now().After(claims.ExpiresAt.Time) {
+			err := errors.New("expired access token")
+			logger.Error(ctx, err.Error())
+			return nil, err
+		}
+
+		dto := UserDTO{
+			ID:          claims.UserID,
+			Name:        claims.UserName,
+			Email:       claims.UserEmail,
+			Roles:       claims.Roles,
+			Permissions: claims.Permissions,
+		}
+		if claims.EmailVerified != nil {
+			dto.EmailVerified = *claims.EmailVerified
+		}
+		if dto.Roles == nil {
+			dto.Roles = []string{}
+		}
+		if dto.Permissions == nil {
+			dto.Permissions = []string{}
+		}
+
+		return newDecodedAccessTokenDTO(*claims, dto, grantedScopes, iat), nil
+	default:
+		err := fmt.Errorf("unknown token verification policy %s", policy)
 		logger.Error(ctx, err.Error())
 		return nil, err
 	}
 
-	userUUID, err := uuid.Parse(userID)
+	userUUID, err := uuid.Parse(claims.UserID)
 	if err != nil {
 		logger.Error(ctx, "An error occured while parsing the userID from JWT token", zap.Error(err))
 		return nil, err
@@ -149,20 +269,32 @@ func (s *usersService) DecodeAccessToken(ctx context.Context, token string) (*Us
 		return nil, err
 	}
 
-	if !strings.EqualFold(user.Email, userEmail) {
+	if user.DeletedAt != nil {
+		err := errors.New("user account deleted")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if user.Status == users.StatusSuspended {
+		err := errors.New("user account suspended")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if !strings.EqualFold(user.Email, claims.UserEmail) {
 		err := errors.New("invalid JWT claims, email doesn't match")
 		logger.Error(ctx, err.Error())
 		return nil, err
 	}
 
-	if time.Now().After(time.Unix(int64(exp), 0)) {
+	if time.Now().After(claims.ExpiresAt.Time) {
 		err := errors.New("expired access token")
 		logger.Error(ctx, err.Error())
 		return nil, err
 	}
 
 	// Ensure token is not blacklisted
-	blacklisted, err := s.blacklistedTokensRepository.Exists(ctx, token)
+	blacklisted, err := s.blacklistedTokensRepository.Exists(ctx, token, claims.ID)
 	if err != nil {
 		logger.Error(ctx, "An error occured while checking blacklisted token existence", zap.Error(err))
 		return nil, err
@@ -174,13 +306,73 @@ func (s *usersService) DecodeAccessToken(ctx context.Context, token string) (*Us
 		return nil, err
 	}
 
-	return parseUserToUserDTO(*user)
+	revokedBefore, err := s.blacklistedTokensRepository.UserRevokedBefore(ctx, claims.UserID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while checking the user's token revocation time", zap.Error(err))
+		return nil, err
+	}
+
+	if iat < revokedBefore {
+		err := errors.New("revoked access token")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	// Tokens are rejected once their session is removed
+	if claims.SessionID != "" {
+		if err := s.checkSession(ctx, claims.SessionID, user.ID); err != nil {
+			logger.Error(ctx, "An error occured while checking the token's session", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	// Authorization decisions are based on the roles the token was issued with
+	dto, err := parseUserToUserDTO(*user)
+	if err != nil {
+		logger.Error(ctx, "Unable to parse user DTO", zap.Error(err))
+		return nil, err
+	}
+	if claims.Roles != nil {
+		dto.Roles = claims.Roles
+	}
+	if claims.Permissions != nil {
+		dto.Permissions = claims.Permissions
+	}
+
+	return newDecodedAccessTokenDTO(*claims, *dto, grantedScopes, iat), nil
 }
 
 func (s *usersService) BlacklistAccessToken(ctx context.Context, token string) error {
 	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/BlacklistAccessToken"))
 
-	err := s.blacklistedTokensRepository.Add(ctx, token, int64(s.config.JWT.Exp))
+	claims, err := s.tokenCodec.Decode(ctx, token)
+	if err != nil {
+		logger.Error(ctx, "An error occured while decoding the access token", zap.Error(err))
+		return err
+	}
+
+	// The token's session goes along with it
+	if claims.SessionID != "" {
+		if userUUID, err := uuid.Parse(claims.UserID); err == nil {
+			if err := s.sessionsRepository.Delete(ctx, userUUID, claims.SessionID); err != nil {
+				logger.Error(ctx, "An error occured while deleting the token's session", zap.Error(err))
+			}
+		}
+	}
+
+	// Tokens that can be revoked directly don't need to be blacklisted
+	if revoker, ok := s.tokenCodec.(tokens.Revoker); ok {
+		err := revoker.Revoke(ctx, token)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, tokens.ErrNotRevocable) {
+			logger.Error(ctx, "Unable to revoke access token", zap.Error(err))
+			return err
+		}
+	}
+
+	// The entry only has to outlive the token
+	err = s.blacklistedTokensRepository.Add(ctx, token, claims.ID, claims.ExpiresAt.Time)
 	if err != nil {
 		logger.Error(ctx, "Unable to blacklist access token", zap.Error(err))
 		return err
@@ -189,10 +381,827 @@ func (s *usersService) BlacklistAccessToken(ctx context.Context, token string) e
 	return nil
 }
 
+func (s *usersService) UpdateProfile(ctx context.Context, userID string, req UpdateProfileDTO) (*UserDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/UpdateProfile"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			err := ValidationErrors{"name": {"must not be blank"}}
+			logger.Error(ctx, "Invalid profile name", zap.Error(err))
+			return nil, err
+		}
+		user.Name = name
+	}
+
+	emailChanged := req.Email != nil && !strings.EqualFold(*req.Email, user.Email)
+	if emailChanged {
+		if err := s.checkEmailAvailable(ctx, *req.Email, user.ID); err != nil {
+			logger.Error(ctx, "The new email is not available", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	if err := s.usersRepository.Update(ctx, *user); err != nil {
+		logger.Error(ctx, "An error occured while updating the user", zap.Error(err))
+		return nil, err
+	}
+
+	// The new email stays pending until it is verified so the user's tokens keep working
+	if emailChanged {
+		// Only the most recent change can be verified
+		if err := s.emailVerificationsRepository.DeleteByUserID(ctx, user.ID.String()); err != nil {
+			logger.Error(ctx, "An error occured while deleting the pending email verification", zap.Error(err))
+			return nil, err
+		}
+
+		if err := s.sendEmailVerification(ctx, *user, *req.Email); err != nil {
+			logger.Error(ctx, "An error occured while sending the email verification", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return s.toUserDTO(ctx, *user)
+}
+
+func (s *usersService) ChangePassword(ctx context.Context, userID string, req ChangePasswordDTO) (*AccessTokenDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/ChangePassword"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return nil, err
+	}
+
+	if err := verifyPassword(user.Password, req.CurrentPassword); err != nil {
+		logger.Error(ctx, "Error while comparing hash and password", zap.Error(err))
+		return nil, errors.New("current password is incorrect")
+	}
+
+	// Enforce the password policy
+	if violations := s.passwordPolicy.Validate(req.NewPassword, user.Name, user.Email); len(violations) != 0 {
+		err := ValidationErrors{"new_password": violations}
+		logger.Error(ctx, "Password does not satisfy the password policy", zap.Error(err))
+		return nil, err
+	}
+
+	hashedPassword, err := HashPassword(req.NewPassword)
+	if err != nil {
+		logger.Error(ctx, "An error occured while hashing user password", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.usersRepository.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		logger.Error(ctx, "An error occured while updating the user password", zap.Error(err))
+		return nil, err
+	}
+
+	if !req.RevokeOtherSessions {
+		return nil, nil
+	}
+
+	// Revoke every existing token and issue a fresh one for the current session,
+	// the cutoff is the current second so the token issued below stays valid
+	now := time.Now()
+	err = s.blacklistedTokensRepository.RevokeUser(
+		ctx,
+		user.ID.String(),
+		now.Unix(),
+		now.Add(time.Second*time.Duration(s.config.JWT.Exp)).Unix(),
+	)
+	if err != nil {
+		logger.Error(ctx, "An error occured while revoking the user's access tokens", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.sessionsRepository.DeleteAllByUserId(ctx, user.ID); err != nil {
+		logger.Error(ctx, "An error occured while deleting the user's sessions", zap.Error(err))
+		return nil, err
+	}
+
+	dto, err := s.toUserDTO(ctx, *user)
+	if err != nil {
+		logger.Error(ctx, "Unable to parse user DTO", zap.Error(err))
+		return nil, err
+	}
+
+	// The new token replaces the current one, so it keeps its grant rather than the default scopes
+	jkt := ""
+	if req.Token.Confirmation != nil {
+		jkt = req.Token.Confirmation.JKT
+	}
+	issue := IssueAccessTokenDTO{
+		ClientID: req.Token.ClientID,
+		Audience: req.Token.Audience,
+		Actor:    req.Token.Actor,
+		JKT:      jkt,
+		Device:   req.Device,
+	}
+	if req.Token.Actor != nil {
+		// Delegated tokens never outlive the token they were exchanged for
+		issue.ExpiresBefore = req.Token.ExpiresAt
+	}
+	token, expiresIn, err := s.generateAccessToken(ctx, *dto, req.Token.Scopes, issue)
+	if err != nil {
+		logger.Error(ctx, "Unable to generate token for user", zap.Error(err))
+		return nil, err
+	}
+
+	tokenType := "bearer"
+	if jkt != "" {
+		tokenType = dpop.TokenType
+	}
+
+	return &AccessTokenDTO{
+		User:        *dto,
+		AccessToken: token,
+		Type:        tokenType,
+		Scope:       scopes.Format(req.Token.Scopes),
+		ExpiresIn:   expiresIn,
+	}, nil
+}
+
+func (s *usersService) VerifyEmail(ctx context.Context, req VerifyEmailDTO) (*UserDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/VerifyEmail"))
+
+	verification, err := s.emailVerificationsRepository.Get(ctx, req.Token)
+	if errors.Is(err, redis.Nil) {
+		err := errors.New("invalid or expired verification token")
+		logger.Error(ctx, err.Error())
+		return nil, err
+	} else if err != nil {
+		logger.Error(ctx, "An error occured while getting the email verification", zap.Error(err))
+		return nil, err
+	}
+
+	user, err := s.getUserById(ctx, verification.UserID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return nil, err
+	}
+
+	// Verifying a pending email change replaces the current email,
+	// the user's existing tokens stop verifying and they have to sign in again
+	if !strings.EqualFold(user.Email, verification.Email) {
+		if err := s.checkEmailAvailable(ctx, verification.Email, user.ID); err != nil {
+			logger.Error(ctx, "The new email is not available", zap.Error(err))
+			return nil, err
+		}
+		user.Email = verification.Email
+	}
+
+	user.EmailVerified = true
+	if err := s.usersRepository.Update(ctx, *user); err != nil {
+		logger.Error(ctx, "An error occured while updating the user", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.emailVerificationsRepository.Delete(ctx, req.Token); err != nil {
+		logger.Error(ctx, "An error occured while deleting the email verification", zap.Error(err))
+		return nil, err
+	}
+
+	return s.toUserDTO(ctx, *user)
+}
+
+func (s *usersService) RequestPasswordReset(ctx context.Context, req RequestPasswordResetDTO) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/RequestPasswordReset"))
+
+	// Unknown emails aren't reported so the registered ones can't be enumerated
+	user, err := s.usersRepository.GetOneByEmail(ctx, req.Email)
+	if err != nil && strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return nil
+	} else if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by email", zap.Error(err))
+		return err
+	}
+
+	// Only verified emails are trusted with access to the account
+	if !user.EmailVerified || user.DeletedAt != nil || user.Status != users.StatusActive {
+		logger.Info(ctx, "Password reset not sent to an inactive or unverified account", zap.String("userId", user.ID.String()))
+		return nil
+	}
+
+	token, err := random.Token()
+	if err != nil {
+		logger.Error(ctx, "An error occured while generating the password reset token", zap.Error(err))
+		return err
+	}
+
+	ttl := time.Second * time.Duration(s.config.Password.ResetExp)
+	err = s.passwordResetsRepository.Add(
+		ctx,
+		token,
+		password_resets.PasswordReset{UserID: user.ID.String(), ExpiresAt: time.Now().Add(ttl).Unix()},
+		ttl,
+	)
+	if err != nil {
+		logger.Error(ctx, "An error occured while storing the password reset", zap.Error(err))
+		return err
+	}
+
+	err = s.mailer.Send(
+		ctx,
+		user.Email,
+		"Reset your password",
+		fmt.Sprintf("Hi %s, submit the token %s to %s/reset-password to choose a new password.", user.Name, token, s.config.URL),
+	)
+	if err != nil {
+		logger.Error(ctx, "An error occured while sending the password reset email", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *usersService) ResetPassword(ctx context.Context, req ResetPasswordDTO) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/ResetPassword"))
+
+	reset, err := s.passwordResetsRepository.Get(ctx, req.Token)
+	if errors.Is(err, redis.Nil) {
+		err := errors.New("invalid or expired password reset token")
+		logger.Error(ctx, err.Error())
+		return err
+	} else if err != nil {
+		logger.Error(ctx, "An error occured while getting the password reset", zap.Error(err))
+		return err
+	}
+
+	user, err := s.getUserById(ctx, reset.UserID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return err
+	}
+
+	// Enforce the password policy
+	if violations := s.passwordPolicy.Validate(req.Password, user.Name, user.Email); len(violations) != 0 {
+		err := ValidationErrors{"password": violations}
+		logger.Error(ctx, "Password does not satisfy the password policy", zap.Error(err))
+		return err
+	}
+
+	hashedPassword, err := HashPassword(req.Password)
+	if err != nil {
+		logger.Error(ctx, "An error occured while hashing user password", zap.Error(err))
+		return err
+	}
+
+	if err := s.usersRepository.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		logger.Error(ctx, "An error occured while updating the user password", zap.Error(err))
+		return err
+	}
+
+	if err := s.passwordResetsRepository.Delete(ctx, req.Token); err != nil {
+		logger.Error(ctx, "An error occured while deleting the password reset", zap.Error(err))
+		return err
+	}
+
+	// Sessions started with the old password are no longer trusted,
+	// tokens issued within the current second are revoked too as no new token is issued
+	now := time.Now()
+	err = s.blacklistedTokensRepository.RevokeUser(
+		ctx,
+		user.ID.String(),
+		now.Unix()+1,
+		now.Add(time.Second*time.Duration(s.config.JWT.Exp)).Unix(),
+	)
+	if err != nil {
+		logger.Error(ctx, "An error occured while revoking the user's access tokens", zap.Error(err))
+		return err
+	}
+
+	if err := s.sessionsRepository.DeleteAllByUserId(ctx, user.ID); err != nil {
+		logger.Error(ctx, "An error occured while deleting the user's sessions", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *usersService) DeleteAccount(ctx context.Context, userID string, req DeleteAccountDTO) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/DeleteAccount"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return err
+	}
+
+	if err := verifyPassword(user.Password, req.Password); err != nil {
+		logger.Error(ctx, "Error while comparing hash and password", zap.Error(err))
+		return errors.New("password is incorrect")
+	}
+
+	return s.deleteUser(ctx, user)
+}
+
+func (s *usersService) DeleteUser(ctx context.Context, userID string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/DeleteUser"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return err
+	}
+
+	return s.deleteUser(ctx, user)
+}
+
+// deleteUser disables the account and drops everything that still grants access to it,
+// the record is kept until the grace period elapses and it is purged.
+func (s *usersService) deleteUser(ctx context.Context, user *users.User) error {
+	now := time.Now()
+	if err := s.usersRepository.SoftDelete(ctx, user.ID, now); err != nil {
+		logger.Error(ctx, "An error occured while deleting the user", zap.Error(err))
+		return err
+	}
+
+	err := s.blacklistedTokensRepository.RevokeUser(
+		ctx,
+		user.ID.String(),
+		now.Unix()+1,
+		now.Add(time.Second*time.Duration(s.config.JWT.Exp)).Unix(),
+	)
+	if err != nil {
+		logger.Error(ctx, "An error occured while revoking the user's access tokens", zap.Error(err))
+		return err
+	}
+
+	if err := s.sessionsRepository.DeleteAllByUserId(ctx, user.ID); err != nil {
+		logger.Error(ctx, "An error occured while deleting the user's sessions", zap.Error(err))
+		return err
+	}
+
+	if err := s.emailVerificationsRepository.DeleteByUserID(ctx, user.ID.String()); err != nil {
+		logger.Error(ctx, "An error occured while deleting the user's email verifications", zap.Error(err))
+		return err
+	}
+
+	if err := s.passwordResetsRepository.DeleteByUserID(ctx, user.ID.String()); err != nil {
+		logger.Error(ctx, "An error occured while deleting the user's password resets", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *usersService) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/PurgeDeletedUsers"))
+
+	deletedBefore := time.Now().Add(-time.Second * time.Duration(s.config.Account.DeletionGracePeriod))
+	count, err := s.usersRepository.PurgeDeleted(ctx, deletedBefore)
+	if err != nil {
+		logger.Error(ctx, "An error occured while purging deleted users", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (s *usersService) ExportData(ctx context.Context, userID string) (*UserDataExportDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/ExportData"))
+
+	user, err := s.getUserById(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user by id", zap.Error(err))
+		return nil, err
+	}
+
+	userRoles, err := s.rolesRepository.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user's roles", zap.Error(err))
+		return nil, err
+	}
+
+	export := &UserDataExportDTO{
+		ExportedAt: time.Now().Unix(),
+		Profile: ExportedProfileDTO{
+			ID:            user.ID.String(),
+			Name:          user.Name,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			Status:        user.Status,
+			CreatedAt:     user.CreatedAt.Unix(),
+		},
+		Roles: userRoles[user.ID],
+	}
+
+	verification, err := s.emailVerificationsRepository.GetByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.Error(ctx, "An error occured while getting the user's email verification", zap.Error(err))
+		return nil, err
+	} else if verification != nil {
+		export.EmailVerification = &ExportedEmailVerificationDTO{
+			Email:     verification.Email,
+			ExpiresAt: verification.ExpiresAt,
+		}
+	}
+
+	reset, err := s.passwordResetsRepository.GetByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.Error(ctx, "An error occured while getting the user's password reset", zap.Error(err))
+		return nil, err
+	} else if reset != nil {
+		export.PasswordReset = &ExportedPasswordResetDTO{ExpiresAt: reset.ExpiresAt}
+	}
+
+	userSessions, err := s.sessionsRepository.GetAllByUserId(ctx, user.ID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user's sessions", zap.Error(err))
+		return nil, err
+	}
+
+	export.Sessions = make([]ExportedSessionDTO, 0, len(userSessions))
+	for _, session := range userSessions {
+		export.Sessions = append(export.Sessions, ExportedSessionDTO{
+			ID:          session.ID,
+			IPAddress:   session.IPAddress,
+			UserAgent:   session.UserAgent,
+			DeviceLabel: session.DeviceLabel,
+			CreatedAt:   session.CreatedAt.Unix(),
+			LastSeenAt:  session.LastSeenAt.Unix(),
+			ExpiresAt:   session.ExpiresAt.Unix(),
+		})
+	}
+
+	identities, err := s.userIdentitiesRepository.GetAllByUserId(ctx, user.ID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user's linked identities", zap.Error(err))
+		return nil, err
+	}
+
+	export.Identities = make([]ExportedIdentityDTO, 0, len(identities))
+	for _, identity := range identities {
+		export.Identities = append(export.Identities, ExportedIdentityDTO{
+			Provider:  identity.Provider,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt.Unix(),
+		})
+	}
+
+	revokedBefore, err := s.blacklistedTokensRepository.UserRevokedBefore(ctx, userID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user's token revocation time", zap.Error(err))
+		return nil, err
+	} else if revokedBefore != 0 {
+		export.TokensRevokedBefore = &revokedBefore
+	}
+
+	return export, nil
+}
+
+func (s *usersService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]SessionDTO, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/ListSessions"))
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Error(ctx, "Invalid user id", zap.Error(err))
+		return nil, err
+	}
+
+	userSessions, err := s.sessionsRepository.GetAllByUserId(ctx, userUUID)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user's sessions", zap.Error(err))
+		return nil, err
+	}
+
+	result := make([]SessionDTO, 0, len(userSessions))
+	for _, session := range userSessions {
+		result = append(result, SessionDTO{
+			ID:          session.ID,
+			IPAddress:   session.IPAddress,
+			UserAgent:   session.UserAgent,
+			DeviceLabel: session.DeviceLabel,
+			Current:     session.ID == currentSessionID,
+			CreatedAt:   session.CreatedAt.Unix(),
+			LastSeenAt:  session.LastSeenAt.Unix(),
+			ExpiresAt:   session.ExpiresAt.Unix(),
+		})
+	}
+
+	return result, nil
+}
+
+func (s *usersService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/RevokeSession"))
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Error(ctx, "Invalid user id", zap.Error(err))
+		return err
+	}
+
+	err = s.sessionsRepository.Delete(ctx, userUUID, sessionID)
+	if err != nil && strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		logger.Error(ctx, "Session not found", zap.Error(err))
+		return ErrSessionNotFound
+	} else if err != nil {
+		logger.Error(ctx, "An error occured while deleting the session", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *usersService) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "UsersService/PurgeExpiredSessions"))
+
+	count, err := s.sessionsRepository.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		logger.Error(ctx, "An error occured while purging expired sessions", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// checkSession ensures the token's session hasn't been removed and records that it was used.
+func (s *usersService) checkSession(ctx context.Context, sessionID string, userID uuid.UUID) error {
+	session, err := s.sessionsRepository.GetOne(ctx, sessionID)
+	if err != nil && strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return errors.New("revoked access token, the session was removed")
+	} else if err != nil {
+		return err
+	}
+
+	if session.UserID != userID {
+		return errors.New("invalid JWT claims, session doesn't match")
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt) >= lastSeenInterval {
+		return s.sessionsRepository.Touch(ctx, session.ID, now)
+	}
+	return nil
+}
+
+// getUserById returns the user if they exist and have not deleted their account.
+func (s *usersService) getUserById(ctx context.Context, userID string) (*users.User, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.usersRepository.GetOneById(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DeletedAt != nil {
+		return nil, errors.New("user account deleted")
+	}
+
+	return user, nil
+}
+
+// toUserDTO parses the user along with their current roles and permissions.
+func (s *usersService) toUserDTO(ctx context.Context, user users.User) (*UserDTO, error) {
+	dto, err := parseUserToUserDTO(user)
+	if err != nil {
+		return nil, err
+	}
+
+	userRoles, err := s.rolesRepository.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	dto.Roles = userRoles[user.ID]
+
+	dto.Permissions, err = s.rolesRepository.GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+// issueAccessToken grants the user the requested scopes and signs a token for them,
+// the client scopes restrict the grant further when the token is issued to an OAuth client.
+func (s *usersService) issueAccessToken(ctx context.Context, user UserDTO, req IssueAccessTokenDTO) (*AccessTokenDTO, error) {
+	grantedScopes, err := s.resolveScopes(ctx, user, req.Scope, req.ClientScopes)
+	if err != nil {
+		logger.Error(ctx, "An error occured while resolving the requested scopes", zap.Error(err))
+		return nil, err
+	}
+
+	// Generate JWT token
+	token, expiresIn, err := s.generateAccessToken(ctx, user, grantedScopes, req)
+	if err != nil {
+		logger.Error(ctx, "Unable to generate token for user", zap.Error(err))
+		return nil, err
+	}
+
+	tokenType := "bearer"
+	if req.JKT != "" {
+		tokenType = dpop.TokenType
+	}
+
+	return &AccessTokenDTO{
+		User:        user,
+		AccessToken: token,
+		Type:        tokenType,
+		Scope:       scopes.Format(grantedScopes),
+		ExpiresIn:   expiresIn,
+	}, nil
+}
+
+// resolveScopes validates the requested scopes for the user,
+// scopes named after a permission are only granted to users holding it.
+func (s *usersService) resolveScopes(ctx context.Context, user UserDTO, requested string, clientScopes []string) ([]string, error) {
+	permissions, err := s.rolesRepository.GetAllPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isPermission := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		isPermission[permission.Name] = true
+	}
+
+	return s.scopes.Resolve(requested, func(scope string) bool {
+		if clientScopes != nil && !scopes.Contains(clientScopes, scope) {
+			return false
+		}
+		return !isPermission[scope] || scopes.Contains(user.Permissions, scope)
+	})
+}
+
+func (s *usersService) generateAccessToken(
+	ctx context.Context,
+	user UserDTO,
+	grantedScopes []string,
+	req IssueAccessTokenDTO,
+) (token string, expiresIn int, err error) {
+	ttl := time.Second * time.Duration(s.config.JWT.Exp)
+	if req.ExpiresBefore != 0 {
+		if remaining := time.Until(time.Unix(req.ExpiresBefore, 0)); remaining < ttl {
+			ttl = remaining
+		}
+	}
+
+	if ttl <= 0 {
+		return "", 0, errors.New("token would already be expired")
+	}
+
+	userUUID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	registeredClaims := tokens.NewRegisteredClaims(user.ID, ttl)
+	registeredClaims.Issuer = s.config.URL
+	registeredClaims.Audience = req.Audience
+
+	// Every token is recorded as a session the user can see and revoke
+	label := req.Device.Label
+	if label == "" {
+		label = deviceLabel(req.Device.UserAgent)
+	}
+	err = s.sessionsRepository.Create(ctx, sessions.Session{
+		ID:          registeredClaims.ID,
+		UserID:      userUUID,
+		IPAddress:   req.Device.IPAddress,
+		UserAgent:   req.Device.UserAgent,
+		DeviceLabel: label,
+		CreatedAt:   registeredClaims.IssuedAt.Time,
+		LastSeenAt:  registeredClaims.IssuedAt.Time,
+		ExpiresAt:   registeredClaims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	scope := scopes.Format(grantedScopes)
+	claims := tokens.Claims{
+		RegisteredClaims: registeredClaims,
+		UserID:           user.ID,
+		UserEmail:        user.Email,
+		Roles:            user.Roles,
+		Permissions:      user.Permissions,
+		Scope:            &scope,
+		ClientID:         req.ClientID,
+		Actor:            req.Actor,
+		Confirmation:     tokens.NewConfirmation(req.JKT),
+		SessionID:        registeredClaims.ID,
+	}
+
+	// The rest of the profile is only put in tokens their holders can't read
+	if tokens.Confidential(s.config.JWT) {
+		claims.UserName = user.Name
+		claims.EmailVerified = &user.EmailVerified
+	}
+
+	token, err = s.tokenCodec.Encode(ctx, claims)
+	return token, int(ttl / time.Second), err
+}
+
+// sendEmailVerification asks the user to verify the email, it is applied to the user when it differs from theirs.
+func (s *usersService) sendEmailVerification(ctx context.Context, user users.User, email string) error {
+	token, err := random.Token()
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Second * time.Duration(s.config.Email.VerificationExp)
+	err = s.emailVerificationsRepository.Add(
+		ctx,
+		token,
+		email_verifications.EmailVerification{
+			UserID:    user.ID.String(),
+			Email:     email,
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+		},
+		ttl,
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(
+		ctx,
+		email,
+		"Verify your email",
+		fmt.Sprintf("Hi %s, verify your email by visiting %s/verify-email?token=%s", user.Name, s.config.URL, token),
+	)
+}
+
+func (s *usersService) checkEmailAvailable(ctx context.Context, email string, userID uuid.UUID) error {
+	existingUser, err := s.usersRepository.GetOneByEmail(ctx, email)
+	if err != nil && !strings.Contains(err.Error(), pgx.ErrNoRows.Error()) {
+		return err
+	}
+
+	if existingUser != nil && existingUser.ID != userID {
+		return errors.New("email taken")
+	}
+	return nil
+}
+
+func newDecodedAccessTokenDTO(claims tokens.Claims, user UserDTO, grantedScopes []string, iat int64) *DecodedAccessTokenDTO {
+	return &DecodedAccessTokenDTO{
+		ID:        claims.ID,
+		ClientID:  claims.ClientID,
+		Actor:     claims.Actor,
+		User:      user,
+		Audience:  claims.Audience,
+		Scopes:    grantedScopes,
+		IssuedAt:  iat,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+
+		Confirmation: claims.Confirmation,
+		SessionID:    claims.SessionID,
+	}
+}
+
+// HashPassword returns the hex encoded bcrypt hash users' passwords are stored as.
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hashedPassword), nil
+}
+
+func verifyPassword(hash, password string) error {
+	hashedPassword, err := hex.DecodeString(hash)
+	if err != nil {
+		return err
+	}
+	return bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+}
+
 func NewUsersService(
 	config *config.Config,
 	usersRepository users.UsersRepository,
 	blacklistedTokensRepository blacklisted_tokens.BlacklistedTokensRepository,
+	emailVerificationsRepository email_verifications.EmailVerificationsRepository,
+	passwordResetsRepository password_resets.PasswordResetsRepository,
+	rolesRepository roles.RolesRepository,
+	sessionsRepository sessions.SessionsRepository,
+	userIdentitiesRepository user_identities.UserIdentitiesRepository,
+	passwordPolicy *password.Policy,
+	scopes *scopes.Registry,
+	tokenCodec tokens.Codec,
+	mailer mailer.Mailer,
 ) UsersService {
-	return &usersService{config, usersRepository, blacklistedTokensRepository}
+	return &usersService{
+		config,
+		usersRepository,
+		blacklistedTokensRepository,
+		emailVerificationsRepository,
+		passwordResetsRepository,
+		rolesRepository,
+		sessionsRepository,
+		userIdentitiesRepository,
+		passwordPolicy,
+		scopes,
+		tokenCodec,
+		mailer,
+	}
 }
@@ -0,0 +1,348 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+	"github.com/the-code-genin/simple-jwt-api-go/common/password"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/email_verifications"
+	"github.com/the-code-genin/simple-jwt-api-go/database/password_resets"
+	"github.com/the-code-genin/simple-jwt-api-go/database/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/database/sessions"
+	"github.com/the-code-genin/simple-jwt-api-go/database/users"
+)
+
+func TestStatelessDecodeAccessToken(t *testing.T) {
+	ctx := context.Background()
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+	codec, err := tokens.NewJWTCodec(config.JWTConfig{Key: "key"}, keySet)
+	assert.Nil(t, err)
+
+	// Stateless verification never reaches the repositories
+	service := NewUsersService(&config.Config{}, nil, nil, nil, nil, nil, nil, nil, nil, scopes.NewRegistry(config.OAuthConfig{}), codec, nil)
+
+	verified, scope := true, "profile"
+	token, err := codec.Encode(ctx, tokens.Claims{
+		RegisteredClaims: tokens.NewRegisteredClaims("user", time.Minute),
+		UserID:           "8f7a4d2e-3c1b-4f5a-9e6d-2b8c7a1f0e3d",
+		UserEmail:        "user@example.com",
+		UserName:         "User",
+		EmailVerified:    &verified,
+		Roles:            []string{"admin"},
+		Scope:            &scope,
+	})
+	assert.Nil(t, err)
+
+	decoded, err := service.DecodeAccessToken(ctx, token, VerificationStateless)
+	assert.Nil(t, err)
+	assert.Equal(t, "User", decoded.User.Name)
+	assert.Equal(t, "user@example.com", decoded.User.Email)
+	assert.True(t, decoded.User.EmailVerified)
+	assert.Equal(t, []string{"admin"}, decoded.User.Roles)
+	assert.Equal(t, []string{}, decoded.User.Permissions)
+	assert.Equal(t, []string{"profile"}, decoded.Scopes)
+
+	_, err = service.DecodeAccessToken(ctx, token, "unknown")
+	assert.NotNil(t, err)
+
+	_, err = ParseVerificationPolicy(config.JWTConfig{VerificationPolicy: "unknown"})
+	assert.NotNil(t, err)
+
+	// Signed tokens can be read by anyone holding them, so they don't carry the profile stateless verification needs
+	_, err = ParseVerificationPolicy(config.JWTConfig{VerificationPolicy: "stateless"})
+	assert.NotNil(t, err)
+	policy, err := ParseVerificationPolicy(config.JWTConfig{VerificationPolicy: "stateless", Format: tokens.FormatOpaque})
+	assert.Nil(t, err)
+	assert.Equal(t, VerificationStateless, policy)
+}
+
+type fakeUsersRepository struct {
+	users.UsersRepository
+	users map[uuid.UUID]users.User
+}
+
+func (r *fakeUsersRepository) GetOneById(ctx context.Context, id uuid.UUID) (*users.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &user, nil
+}
+
+func (r *fakeUsersRepository) GetOneByEmail(ctx context.Context, email string) (*users.User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *fakeUsersRepository) Update(ctx context.Context, user users.User) error {
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *fakeUsersRepository) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	user := r.users[id]
+	user.Password = password
+	r.users[id] = user
+	return nil
+}
+
+type fakeEmailVerificationsRepository struct {
+	email_verifications.EmailVerificationsRepository
+	verifications map[string]email_verifications.EmailVerification
+}
+
+func (r *fakeEmailVerificationsRepository) Add(ctx context.Context, token string, verification email_verifications.EmailVerification, ttl time.Duration) error {
+	r.verifications[token] = verification
+	return nil
+}
+
+func (r *fakeEmailVerificationsRepository) Get(ctx context.Context, token string) (*email_verifications.EmailVerification, error) {
+	verification, ok := r.verifications[token]
+	if !ok {
+		return nil, redis.Nil
+	}
+	return &verification, nil
+}
+
+func (r *fakeEmailVerificationsRepository) Delete(ctx context.Context, token string) error {
+	delete(r.verifications, token)
+	return nil
+}
+
+func (r *fakeEmailVerificationsRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	for token, verification := range r.verifications {
+		if verification.UserID == userID {
+			delete(r.verifications, token)
+		}
+	}
+	return nil
+}
+
+type fakeRolesRepository struct {
+	roles.RolesRepository
+}
+
+func (r *fakeRolesRepository) GetUserRoles(ctx context.Context, userIDs ...uuid.UUID) (map[uuid.UUID][]string, error) {
+	return map[uuid.UUID][]string{}, nil
+}
+
+func (r *fakeRolesRepository) GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return []string{}, nil
+}
+
+type fakeMailer struct {
+	sent []string
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+func TestUpdateProfileEmail(t *testing.T) {
+	ctx := context.Background()
+	user := users.User{ID: uuid.New(), Name: "User", Email: "user@example.com", EmailVerified: true, Status: users.StatusActive}
+	other := users.User{ID: uuid.New(), Name: "Other", Email: "other@example.com", Status: users.StatusActive}
+	usersRepository := &fakeUsersRepository{users: map[uuid.UUID]users.User{user.ID: user, other.ID: other}}
+	verificationsRepository := &fakeEmailVerificationsRepository{verifications: map[string]email_verifications.EmailVerification{}}
+	mailer := &fakeMailer{}
+	service := NewUsersService(
+		&config.Config{}, usersRepository, nil, verificationsRepository, nil, &fakeRolesRepository{}, nil, nil, nil,
+		scopes.NewRegistry(config.OAuthConfig{}), nil, mailer,
+	)
+
+	blank := "   "
+	_, err := service.UpdateProfile(ctx, user.ID.String(), UpdateProfileDTO{Name: &blank})
+	assert.IsType(t, ValidationErrors{}, err)
+
+	taken := "other@example.com"
+	_, err = service.UpdateProfile(ctx, user.ID.String(), UpdateProfileDTO{Email: &taken})
+	assert.NotNil(t, err)
+
+	// The new email is only applied once it is verified
+	email := "new@example.com"
+	dto, err := service.UpdateProfile(ctx, user.ID.String(), UpdateProfileDTO{Email: &email})
+	assert.Nil(t, err)
+	assert.Equal(t, "user@example.com", dto.Email)
+	assert.True(t, dto.EmailVerified)
+	assert.Equal(t, []string{email}, mailer.sent)
+	assert.Len(t, verificationsRepository.verifications, 1)
+
+	for token := range verificationsRepository.verifications {
+		dto, err = service.VerifyEmail(ctx, VerifyEmailDTO{Token: token})
+		assert.Nil(t, err)
+		assert.Equal(t, email, dto.Email)
+		assert.True(t, dto.EmailVerified)
+	}
+}
+
+type fakeSessionsRepository struct {
+	sessions.SessionsRepository
+	err error
+}
+
+func (r *fakeSessionsRepository) Create(ctx context.Context, session sessions.Session) error {
+	return nil
+}
+
+func (r *fakeSessionsRepository) Delete(ctx context.Context, userID uuid.UUID, id string) error {
+	return r.err
+}
+
+func (r *fakeSessionsRepository) DeleteAllByUserId(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	sessionsRepository := &fakeSessionsRepository{}
+	service := NewUsersService(&config.Config{}, nil, nil, nil, nil, nil, sessionsRepository, nil, nil, scopes.NewRegistry(config.OAuthConfig{}), nil, nil)
+	userID := uuid.New().String()
+
+	assert.Nil(t, service.RevokeSession(ctx, userID, "session"))
+
+	sessionsRepository.err = pgx.ErrNoRows
+	assert.ErrorIs(t, service.RevokeSession(ctx, userID, "session"), ErrSessionNotFound)
+
+	// Database failures aren't reported as a missing session
+	sessionsRepository.err = errors.New("connection reset")
+	err := service.RevokeSession(ctx, userID, "session")
+	assert.NotNil(t, err)
+	assert.NotErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestAuthenticateDeletedUser(t *testing.T) {
+	ctx := context.Background()
+	hashedPassword, err := HashPassword("password")
+	assert.Nil(t, err)
+
+	deletedAt := time.Now()
+	user := users.User{ID: uuid.New(), Email: "user@example.com", Password: hashedPassword, DeletedAt: &deletedAt}
+	usersRepository := &fakeUsersRepository{users: map[uuid.UUID]users.User{user.ID: user}}
+	service := NewUsersService(&config.Config{}, usersRepository, nil, nil, nil, nil, nil, nil, nil, scopes.NewRegistry(config.OAuthConfig{}), nil, nil)
+
+	// Deleted accounts aren't revealed without their password
+	_, err = service.Authenticate(ctx, user.Email, "wrong")
+	assert.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "deleted")
+
+	_, err = service.Authenticate(ctx, user.Email, "password")
+	assert.EqualError(t, err, "user account deleted")
+}
+
+type fakePasswordResetsRepository struct {
+	password_resets.PasswordResetsRepository
+	resets map[string]password_resets.PasswordReset
+}
+
+func (r *fakePasswordResetsRepository) Add(ctx context.Context, token string, reset password_resets.PasswordReset, ttl time.Duration) error {
+	r.resets[token] = reset
+	return nil
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	user := users.User{ID: uuid.New(), Name: "User", Email: "user@example.com", EmailVerified: true, Status: users.StatusActive}
+	unverified := users.User{ID: uuid.New(), Email: "unverified@example.com", Status: users.StatusActive}
+	usersRepository := &fakeUsersRepository{users: map[uuid.UUID]users.User{user.ID: user, unverified.ID: unverified}}
+	resetsRepository := &fakePasswordResetsRepository{resets: map[string]password_resets.PasswordReset{}}
+	mailer := &fakeMailer{}
+	service := NewUsersService(
+		&config.Config{}, usersRepository, nil, nil, resetsRepository, nil, nil, nil, nil,
+		scopes.NewRegistry(config.OAuthConfig{}), nil, mailer,
+	)
+
+	// Unknown and unverified emails get nothing, without it being reported
+	assert.Nil(t, service.RequestPasswordReset(ctx, RequestPasswordResetDTO{Email: "unknown@example.com"}))
+	assert.Nil(t, service.RequestPasswordReset(ctx, RequestPasswordResetDTO{Email: unverified.Email}))
+	assert.Empty(t, mailer.sent)
+
+	assert.Nil(t, service.RequestPasswordReset(ctx, RequestPasswordResetDTO{Email: user.Email}))
+	assert.Equal(t, []string{user.Email}, mailer.sent)
+	assert.Len(t, resetsRepository.resets, 1)
+	for _, reset := range resetsRepository.resets {
+		assert.Equal(t, user.ID.String(), reset.UserID)
+	}
+}
+
+type fakeBlacklistedTokensRepository struct {
+	blacklisted_tokens.BlacklistedTokensRepository
+}
+
+func (r *fakeBlacklistedTokensRepository) RevokeUser(ctx context.Context, userID string, before int64, expiry int64) error {
+	return nil
+}
+
+func TestChangePasswordReissuesToken(t *testing.T) {
+	ctx := context.Background()
+	hashedPassword, err := HashPassword("password")
+	assert.Nil(t, err)
+	user := users.User{ID: uuid.New(), Name: "User", Email: "user@example.com", Password: hashedPassword, Status: users.StatusActive}
+
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+	codec, err := tokens.NewJWTCodec(config.JWTConfig{Key: "key"}, keySet)
+	assert.Nil(t, err)
+	policy, err := password.NewPolicy(config.PasswordConfig{})
+	assert.Nil(t, err)
+
+	cfg := &config.Config{JWT: config.JWTConfig{Exp: 3600}, OAuth: config.OAuthConfig{Scopes: []string{"profile", "admin"}}}
+	service := NewUsersService(
+		cfg,
+		&fakeUsersRepository{users: map[uuid.UUID]users.User{user.ID: user}},
+		&fakeBlacklistedTokensRepository{},
+		nil,
+		nil,
+		&fakeRolesRepository{},
+		&fakeSessionsRepository{},
+		nil,
+		policy,
+		scopes.NewRegistry(cfg.OAuth),
+		codec,
+		nil,
+	)
+
+	// Keeping the other sessions issues nothing
+	token, err := service.ChangePassword(ctx, user.ID.String(), ChangePasswordDTO{CurrentPassword: "password", NewPassword: "a new password"})
+	assert.Nil(t, err)
+	assert.Nil(t, token)
+
+	// The reissued token keeps the current token's grant and DPoP binding
+	token, err = service.ChangePassword(ctx, user.ID.String(), ChangePasswordDTO{
+		CurrentPassword:     "a new password",
+		NewPassword:         "another new password",
+		RevokeOtherSessions: true,
+		Token: DecodedAccessTokenDTO{
+			ClientID:     "client",
+			Scopes:       []string{"admin"},
+			Confirmation: tokens.NewConfirmation("thumbprint"),
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, dpop.TokenType, token.Type)
+	assert.Equal(t, "admin", token.Scope)
+
+	claims, err := codec.Decode(ctx, token.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", *claims.Scope)
+	assert.Equal(t, "client", claims.ClientID)
+	assert.Equal(t, "thumbprint", claims.Confirmation.JKT)
+}
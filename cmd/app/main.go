@@ -4,15 +4,40 @@ import (
 	"context"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/the-code-genin/simple-jwt-api-go/application/admin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/gateway"
+	"github.com/the-code-genin/simple-jwt-api-go/application/oauth"
+	app_roles "github.com/the-code-genin/simple-jwt-api-go/application/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/application/social"
 	app_users "github.com/the-code-genin/simple-jwt-api-go/application/users"
 	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
 	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/mailer"
+	"github.com/the-code-genin/simple-jwt-api-go/common/password"
 	"github.com/the-code-genin/simple-jwt-api-go/common/postgres"
 	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/authorization_codes"
 	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/device_authorizations"
+	"github.com/the-code-genin/simple-jwt-api-go/database/dpop_proofs"
+	"github.com/the-code-genin/simple-jwt-api-go/database/email_verifications"
+	"github.com/the-code-genin/simple-jwt-api-go/database/oauth_clients"
+	"github.com/the-code-genin/simple-jwt-api-go/database/opaque_tokens"
+	"github.com/the-code-genin/simple-jwt-api-go/database/password_resets"
+	db_roles "github.com/the-code-genin/simple-jwt-api-go/database/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/database/sessions"
+	"github.com/the-code-genin/simple-jwt-api-go/database/social_logins"
+	"github.com/the-code-genin/simple-jwt-api-go/database/user_identities"
 	db_users "github.com/the-code-genin/simple-jwt-api-go/database/users"
+	"github.com/the-code-genin/simple-jwt-api-go/services/grpc"
 	"github.com/the-code-genin/simple-jwt-api-go/services/http"
+	"github.com/the-code-genin/simple-jwt-api-go/services/scheduler"
 	"go.uber.org/zap"
 )
 
@@ -42,21 +67,162 @@ func main() {
 	}
 	logger.Info(ctx, "Connected to redis")
 
+	migrated, err := blacklisted_tokens.MigrateLegacyKeys(ctx, redisClient, time.Second*time.Duration(config.JWT.Exp))
+	if err != nil {
+		logger.Error(ctx, "An error occured while migrating the blacklisted tokens", zap.Error(err))
+		os.Exit(1)
+	}
+	logger.Info(ctx, "Migrated blacklisted tokens", zap.Int("count", migrated))
+
 	// Create db repositories
-	usersRepo := db_users.NewUsersRepository(pqConn)
-	blacklistedTokensRepo := blacklisted_tokens.NewBlacklistedTokensRepository(redisClient)
+	usersRepo := db_users.NewCachedUsersRepository(ctx, db_users.NewUsersRepository(pqConn), redisClient, config.Cache)
+	db_users.CacheStats.Publish("users_cache")
+	blacklistedTokensRepo := blacklisted_tokens.NewCachedBlacklistedTokensRepository(
+		ctx,
+		blacklisted_tokens.NewBlacklistedTokensRepository(redisClient),
+		redisClient,
+		config.Cache,
+	)
+	blacklisted_tokens.CacheStats.Publish("blacklist_cache")
+	emailVerificationsRepo := email_verifications.NewEmailVerificationsRepository(redisClient)
+	passwordResetsRepo := password_resets.NewPasswordResetsRepository(redisClient)
+	rolesRepo := db_roles.NewRolesRepository(pqConn)
+	oauthClientsRepo := oauth_clients.NewOAuthClientsRepository(pqConn)
+	authorizationCodesRepo := authorization_codes.NewAuthorizationCodesRepository(redisClient)
+	deviceAuthorizationsRepo := device_authorizations.NewDeviceAuthorizationsRepository(redisClient)
+	userIdentitiesRepo := user_identities.NewUserIdentitiesRepository(pqConn)
+	socialLoginsRepo := social_logins.NewSocialLoginsRepository(redisClient)
+	dpopProofsRepo := dpop_proofs.NewDPoPProofsRepository(redisClient)
+	opaqueTokensRepo := opaque_tokens.NewOpaqueTokensRepository(redisClient)
+	sessionsRepo := sessions.NewSessionsRepository(pqConn)
 
 	// Create application services
-	usersService := app_users.NewUsersService(config, usersRepo, blacklistedTokensRepo)
+	passwordPolicy, err := password.NewPolicy(config.Password)
+	if err != nil {
+		logger.Error(ctx, "An error occured while loading the password policy", zap.Error(err))
+		os.Exit(1)
+	}
+
+	mailSender := mailer.NewLogMailer()
+	scopeRegistry := scopes.NewRegistry(config.OAuth)
+
+	if config.JWT.PrivateKeyPath == "" && scopeRegistry.Known(scopes.ScopeOpenID) {
+		// ID tokens signed with an ephemeral key stop verifying on every restart
+		if config.IsProduction() {
+			logger.Error(ctx, "JWT_PRIVATE_KEY_PATH is required to serve OpenID Connect in production")
+			os.Exit(1)
+		}
+		logger.Warn(ctx, "No private key configured, ID tokens will be signed with an ephemeral key")
+	}
+	keySet, err := keys.NewKeySet(config.JWT)
+	if err != nil {
+		logger.Error(ctx, "An error occured while loading the signing keys", zap.Error(err))
+		os.Exit(1)
+	}
+
+	tokenCodec, err := tokens.NewCodec(config, keySet, opaqueTokensRepo)
+	if err != nil {
+		logger.Error(ctx, "An error occured while creating the token codec", zap.Error(err))
+		os.Exit(1)
+	}
+
+	usersService := app_users.NewUsersService(
+		config,
+		usersRepo,
+		blacklistedTokensRepo,
+		emailVerificationsRepo,
+		passwordResetsRepo,
+		rolesRepo,
+		sessionsRepo,
+		userIdentitiesRepo,
+		passwordPolicy,
+		scopeRegistry,
+		tokenCodec,
+		mailSender,
+	)
+	adminService := admin.NewAdminService(
+		config,
+		usersRepo,
+		blacklistedTokensRepo,
+		passwordResetsRepo,
+		rolesRepo,
+		mailSender,
+		usersService,
+	)
+	rolesService := app_roles.NewRolesService(config, rolesRepo, usersRepo, blacklistedTokensRepo)
+	oauthService := oauth.NewOAuthService(
+		config,
+		scopeRegistry,
+		tokenCodec,
+		keySet,
+		oauthClientsRepo,
+		authorizationCodesRepo,
+		rolesRepo,
+		deviceAuthorizationsRepo,
+		blacklistedTokensRepo,
+		usersService,
+	)
+
+	identityProviders, err := social.NewProviders(ctx, config)
+	if err != nil {
+		logger.Error(ctx, "An error occured while loading the identity providers", zap.Error(err))
+		os.Exit(1)
+	}
+	socialService := social.NewSocialService(
+		config,
+		identityProviders,
+		usersRepo,
+		userIdentitiesRepo,
+		socialLoginsRepo,
+		usersService,
+	)
+
+	dpopVerifier := dpop.NewVerifier(config.DPoP, config.URL, dpopProofsRepo)
+
+	verificationPolicy, err := app_users.ParseVerificationPolicy(config.JWT)
+	if err != nil {
+		logger.Error(ctx, "An error occured while parsing the token verification policy", zap.Error(err))
+		os.Exit(1)
+	}
+
+	gatewayService := gateway.NewGatewayService(ctx, config, usersService, verificationPolicy, redisClient)
+	gateway.CacheStats.Publish("decisions_cache")
 
 	// Create system services
-	httpServer, err := http.NewServer(config.IsProduction(), usersService)
+	httpServer, err := http.NewServer(
+		config.IsProduction(),
+		config.MetricsEnabled,
+		usersService,
+		adminService,
+		rolesService,
+		oauthService,
+		socialService,
+		gatewayService,
+		dpopVerifier,
+		verificationPolicy,
+	)
 	if err != nil {
 		logger.Error(ctx, "An error occured while creating http server", zap.Error(err))
 		os.Exit(1)
 	}
 	logger.Info(ctx, "Created HTTP server")
 
+	grpcServer, err := grpc.NewServer(usersService, gatewayService, verificationPolicy)
+	if err != nil {
+		logger.Error(ctx, "An error occured while creating grpc server", zap.Error(err))
+		os.Exit(1)
+	}
+	logger.Info(ctx, "Created gRPC server")
+
+	jobScheduler, err := scheduler.NewScheduler(
+		usersService,
+		time.Second*time.Duration(config.Account.PurgeInterval),
+	)
+	if err != nil {
+		logger.Error(ctx, "An error occured while creating the scheduler, check ACCOUNT_PURGE_INTERVAL", zap.Error(err))
+		os.Exit(1)
+	}
+
 	// Run system services
 	var wg sync.WaitGroup
 
@@ -68,5 +234,21 @@ func main() {
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := grpcServer.Run(config.GRPCPort); err != nil {
+			logger.Error(ctx, "An error occured while running grpc server", zap.Error(err))
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := jobScheduler.Run(ctx); err != nil {
+			logger.Error(ctx, "An error occured while running the job scheduler", zap.Error(err))
+		}
+	}()
+
 	wg.Wait()
 }
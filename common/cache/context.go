@@ -0,0 +1,17 @@
+package cache
+
+import "context"
+
+type skipKey struct{}
+
+// Skip makes the lookups made with the context bypass the in-process caches,
+// their results are still cached for the lookups that don't.
+func Skip(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// Skipped reports whether lookups made with the context must bypass the in-process caches.
+func Skipped(ctx context.Context) bool {
+	skipped, _ := ctx.Value(skipKey{}).(bool)
+	return skipped
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// LRU is a bounded cache safe for concurrent use, the least recently used entry is evicted
+// once it is full and entries expire after their own TTL.
+type LRU[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+
+	// generation counts the deletions so values read before one aren't cached after it
+	generation uint64
+
+	// stats may be nil
+	stats *Stats
+}
+
+func (c *LRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		c.stats.miss()
+		return zero, false
+	}
+
+	cached := element.Value.(*entry[V])
+	if time.Now().After(cached.expiresAt) {
+		c.removeElement(element)
+		c.stats.miss()
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.hit()
+	return cached.value, true
+}
+
+func (c *LRU[V]) Set(key string, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl)
+}
+
+func (c *LRU[V]) set(key string, value V, ttl time.Duration) {
+	expiresAt := time.Now().Add(ttl)
+	if element, ok := c.entries[key]; ok {
+		cached := element.Value.(*entry[V])
+		cached.value, cached.expiresAt = value, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[V]{key, value, expiresAt})
+	if c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Generation is taken before reading a value from its source, see SetIfCurrent.
+func (c *LRU[V]) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// SetIfCurrent sets the value unless an entry was deleted or the cache cleared since the generation was taken,
+// so a value read before an invalidation can't repopulate the cache after it.
+func (c *LRU[V]) SetIfCurrent(key string, value V, ttl time.Duration, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return false
+	}
+	c.set(key, value, ttl)
+	return true
+}
+
+func (c *LRU[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+func (c *LRU[V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+func (c *LRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU[V]) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry[V]).key)
+}
+
+// NewLRU returns a cache holding at most capacity entries, the stats are optional.
+func NewLRU[V any](capacity int, stats *Stats) *LRU[V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &LRU[V]{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element, capacity),
+		order:    list.New(),
+		stats:    stats,
+	}
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	stats := &Stats{}
+	lru := NewLRU[int](2, stats)
+
+	lru.Set("a", 1, time.Minute)
+	lru.Set("b", 2, time.Minute)
+
+	// Reading a makes b the least recently used entry
+	value, ok := lru.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	lru.Set("c", 3, time.Minute)
+	assert.Equal(t, 2, lru.Len())
+	_, ok = lru.Get("b")
+	assert.False(t, ok)
+
+	lru.Delete("a")
+	_, ok = lru.Get("a")
+	assert.False(t, ok)
+
+	lru.Set("d", 4, -time.Second)
+	_, ok = lru.Get("d")
+	assert.False(t, ok)
+
+	assert.Equal(t, int64(1), stats.Hits())
+	assert.Equal(t, int64(3), stats.Misses())
+	assert.Equal(t, 0.25, stats.HitRate())
+
+	lru.Clear()
+	assert.Equal(t, 0, lru.Len())
+
+	// Stats are optional
+	NewLRU[bool](1, nil).Get("a")
+}
+
+func TestLRUGeneration(t *testing.T) {
+	lru := NewLRU[int](2, nil)
+
+	generation := lru.Generation()
+	assert.True(t, lru.SetIfCurrent("a", 1, time.Minute, generation))
+
+	// A value read before the invalidation isn't cached after it
+	generation = lru.Generation()
+	lru.Delete("a")
+	assert.False(t, lru.SetIfCurrent("a", 0, time.Minute, generation))
+	_, ok := lru.Get("a")
+	assert.False(t, ok)
+
+	generation = lru.Generation()
+	lru.Clear()
+	assert.False(t, lru.SetIfCurrent("a", 0, time.Minute, generation))
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// Stats counts a cache's hits and misses.
+type Stats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func (s *Stats) Hits() int64 {
+	return s.hits.Load()
+}
+
+func (s *Stats) Misses() int64 {
+	return s.misses.Load()
+}
+
+// HitRate is the share of lookups that were hits, it is 0 before the first lookup.
+func (s *Stats) HitRate() float64 {
+	hits, misses := s.Hits(), s.Misses()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// Publish exposes the stats as an expvar variable with the name,
+// it panics if the name is already taken.
+func (s *Stats) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return map[string]interface{}{
+			"hits":     s.Hits(),
+			"misses":   s.Misses(),
+			"hit_rate": s.HitRate(),
+		}
+	}))
+}
+
+func (s *Stats) hit() {
+	if s != nil {
+		s.hits.Add(1)
+	}
+}
+
+func (s *Stats) miss() {
+	if s != nil {
+		s.misses.Add(1)
+	}
+}
@@ -12,10 +12,25 @@ import (
 type Config struct {
 	Environment constants.ENV `envconfig:"ENV"`
 	Port        int           `envconfig:"HTTP_PORT"`
+	URL         string        `envconfig:"APP_URL" default:"http://localhost:9000"`
 
-	JWT   JWTConfig
-	DB    DatabaseConfig
-	Redis RedisConfig
+	// GRPCPort is where the gRPC API is served, next to the HTTP one
+	GRPCPort int `envconfig:"GRPC_PORT" default:"9090"`
+
+	// MetricsEnabled serves the expvar metrics at /debug/vars in production, they always are otherwise
+	MetricsEnabled bool `envconfig:"METRICS_ENABLED"`
+
+	JWT      JWTConfig
+	PASETO   PASETOConfig
+	DB       DatabaseConfig
+	Redis    RedisConfig
+	Cache    CacheConfig
+	Password PasswordConfig
+	Email    EmailConfig
+	Account  AccountConfig
+	OAuth    OAuthConfig
+	Social   SocialConfig
+	DPoP     DPoPConfig
 }
 
 func (c *Config) IsProduction() bool {
@@ -25,6 +40,35 @@ func (c *Config) IsProduction() bool {
 type JWTConfig struct {
 	Key string `envconfig:"JWT_KEY"`
 	Exp int    `envconfig:"JWT_EXP"`
+
+	// Format is jwt or paseto-public/paseto-local for self-contained tokens,
+	// or opaque for reference tokens whose claims are kept in redis.
+	Format string `envconfig:"TOKEN_FORMAT" default:"jwt"`
+
+	// VerificationPolicy is how tokens are verified on routes that don't require a stricter policy,
+	// strict by default, cached or stateless trade revocation latency for fewer lookups.
+	// Stateless requires tokens whose claims only the issuer can read, see tokens.Confidential.
+	VerificationPolicy string `envconfig:"TOKEN_VERIFICATION_POLICY" default:"strict"`
+
+	// Algorithm is HS256 to sign access tokens with Key,
+	// or the algorithm of the private key to sign them asymmetrically.
+	Algorithm string `envconfig:"JWT_ALGORITHM" default:"HS256"`
+
+	// PrivateKeyPath is a PEM encoded RSA or P-256 key, ID tokens are always signed with it
+	PrivateKeyPath string `envconfig:"JWT_PRIVATE_KEY_PATH"`
+
+	// Encryption is dir or RSA-OAEP-256 to nest signed tokens in an A256GCM JWE,
+	// dir uses the hex encoded 32 byte EncryptionKey and RSA-OAEP-256 the PEM encoded RSA key at EncryptionKeyPath.
+	Encryption        string `envconfig:"JWT_ENCRYPTION"`
+	EncryptionKey     string `envconfig:"JWT_ENCRYPTION_KEY"`
+	EncryptionKeyPath string `envconfig:"JWT_ENCRYPTION_KEY_PATH"`
+}
+
+// PASETOConfig holds the hex encoded PASETO v4 keys,
+// an Ed25519 secret key for v4.public tokens and a 32 byte key for v4.local tokens.
+type PASETOConfig struct {
+	SecretKey string `envconfig:"PASETO_SECRET_KEY"`
+	LocalKey  string `envconfig:"PASETO_LOCAL_KEY"`
 }
 
 type RedisConfig struct {
@@ -33,6 +77,73 @@ type RedisConfig struct {
 	Prefix   string `envconfig:"REDIS_PREFIX"`
 }
 
+// CacheConfig bounds the in-process caches in front of redis, they are disabled when the size is 0.
+// Negative results are only cached for NegativeTTL seconds in case an invalidation message is missed.
+type CacheConfig struct {
+	BlacklistSize int `envconfig:"CACHE_BLACKLIST_SIZE" default:"10000"`
+	UsersSize     int `envconfig:"CACHE_USERS_SIZE" default:"10000"`
+	TTL           int `envconfig:"CACHE_TTL" default:"300"`
+	NegativeTTL   int `envconfig:"CACHE_NEGATIVE_TTL" default:"30"`
+
+	// DecisionsSize and DecisionsTTL bound the forward auth decisions remembered,
+	// they are dropped on revocations so they are only remembered when the users and blacklist caches are enabled
+	DecisionsSize int `envconfig:"CACHE_DECISIONS_SIZE" default:"10000"`
+	DecisionsTTL  int `envconfig:"CACHE_DECISIONS_TTL" default:"30"`
+}
+
+type PasswordConfig struct {
+	MinLength        int    `envconfig:"PASSWORD_MIN_LENGTH" default:"8"`
+	MaxLength        int    `envconfig:"PASSWORD_MAX_LENGTH" default:"72"`
+	RequireUpper     bool   `envconfig:"PASSWORD_REQUIRE_UPPER" default:"true"`
+	RequireLower     bool   `envconfig:"PASSWORD_REQUIRE_LOWER" default:"true"`
+	RequireDigit     bool   `envconfig:"PASSWORD_REQUIRE_DIGIT" default:"true"`
+	RequireSymbol    bool   `envconfig:"PASSWORD_REQUIRE_SYMBOL" default:"false"`
+	BreachedListPath string `envconfig:"PASSWORD_BREACHED_LIST_PATH"` // a file of hashes or a directory of HIBP range files
+	ResetExp         int    `envconfig:"PASSWORD_RESET_EXP" default:"3600"`
+}
+
+type EmailConfig struct {
+	VerificationExp int `envconfig:"EMAIL_VERIFICATION_EXP" default:"86400"`
+}
+
+type AccountConfig struct {
+	DeletionGracePeriod int `envconfig:"ACCOUNT_DELETION_GRACE_PERIOD" default:"2592000"`
+	PurgeInterval       int `envconfig:"ACCOUNT_PURGE_INTERVAL" default:"3600"`
+}
+
+type OAuthConfig struct {
+	// Scopes named after a permission are only granted to users holding that permission
+	Scopes               []string `envconfig:"OAUTH_SCOPES" default:"openid,profile,email,users:read,users:manage,roles:manage,clients:manage"`
+	DefaultScopes        []string `envconfig:"OAUTH_DEFAULT_SCOPES" default:"profile,email"`
+	AuthorizationCodeExp int      `envconfig:"OAUTH_AUTHORIZATION_CODE_EXP" default:"60"`
+	DeviceCodeExp        int      `envconfig:"OAUTH_DEVICE_CODE_EXP" default:"600"`
+	DevicePollInterval   int      `envconfig:"OAUTH_DEVICE_POLL_INTERVAL" default:"5"`
+}
+
+// SocialConfig holds the external identity providers users can sign in with,
+// a provider is enabled once its client id is set.
+type SocialConfig struct {
+	LoginExp int `envconfig:"SOCIAL_LOGIN_EXP" default:"600"`
+
+	GoogleClientID     string `envconfig:"GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string `envconfig:"GOOGLE_CLIENT_SECRET"`
+
+	GitHubClientID     string `envconfig:"GITHUB_CLIENT_ID"`
+	GitHubClientSecret string `envconfig:"GITHUB_CLIENT_SECRET"`
+
+	// OIDC is any other OpenID Connect provider, its endpoints are found through the issuer's discovery document
+	OIDCName         string   `envconfig:"OIDC_PROVIDER_NAME" default:"oidc"`
+	OIDCIssuer       string   `envconfig:"OIDC_ISSUER"`
+	OIDCClientID     string   `envconfig:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string   `envconfig:"OIDC_CLIENT_SECRET"`
+	OIDCScopes       []string `envconfig:"OIDC_SCOPES" default:"openid,profile,email"`
+}
+
+type DPoPConfig struct {
+	// ProofLifetime is how many seconds a proof's iat may differ from the current time
+	ProofLifetime int `envconfig:"DPOP_PROOF_LIFETIME" default:"60"`
+}
+
 type DatabaseConfig struct {
 	URL string `envconfig:"DATABASE_URL"`
 }
@@ -0,0 +1,194 @@
+package dpop
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+)
+
+const (
+	// HeaderName is the request header carrying the proof.
+	HeaderName = "DPoP"
+	// TokenType is the token type and authorization scheme of DPoP bound tokens.
+	TokenType = "DPoP"
+
+	proofType = "dpop+jwt"
+)
+
+// SigningAlgorithms are the asymmetric algorithms proofs may be signed with.
+var SigningAlgorithms = []string{
+	string(jose.RS256),
+	string(jose.PS256),
+	string(jose.ES256),
+	string(jose.EdDSA),
+}
+
+var ErrReplayedProof = errors.New("dpop proof was already used")
+
+// ReplayCache remembers the proofs that were already presented.
+type ReplayCache interface {
+	// Add records the proof id for ttl, it returns false if the proof id was already recorded.
+	Add(ctx context.Context, id string, ttl time.Duration) (bool, error)
+}
+
+// Proof is a verified DPoP proof (RFC 9449).
+type Proof struct {
+	ID       string
+	IssuedAt time.Time
+
+	// JKT is the SHA-256 thumbprint of the key the proof was signed with
+	JKT string
+}
+
+type proofClaims struct {
+	ID       string `json:"jti"`
+	Method   string `json:"htm"`
+	URL      string `json:"htu"`
+	IssuedAt int64  `json:"iat"`
+	ATH      string `json:"ath"`
+}
+
+// Verifier checks DPoP proofs sent to this API.
+type Verifier struct {
+	baseURL     string
+	lifetime    time.Duration
+	replayCache ReplayCache
+}
+
+// Verify checks that the proof was signed for this request with the key embedded in it and was not used before,
+// the proof must also be bound to the access token unless it is empty.
+func (v *Verifier) Verify(ctx context.Context, proof, method, path, accessToken string) (*Proof, error) {
+	if proof == "" {
+		return nil, errors.New("missing dpop proof")
+	}
+
+	object, err := jose.ParseSigned(proof)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(object.Signatures) != 1 {
+		return nil, errors.New("dpop proof must have exactly one signature")
+	}
+	header := object.Signatures[0].Protected
+
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != proofType {
+		return nil, fmt.Errorf("dpop proof type must be %s", proofType)
+	}
+
+	if !contains(SigningAlgorithms, header.Algorithm) {
+		return nil, fmt.Errorf("unsupported dpop proof algorithm: %s", header.Algorithm)
+	}
+
+	// The key must be embedded, and only its public half
+	if header.JSONWebKey == nil || !header.JSONWebKey.Valid() || !header.JSONWebKey.IsPublic() {
+		return nil, errors.New("dpop proof must embed a public jwk")
+	}
+
+	payload, err := object.Verify(header.JSONWebKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims proofClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+
+	if claims.ID == "" {
+		return nil, errors.New("dpop proof has no jti")
+	}
+
+	if claims.Method != method {
+		return nil, errors.New("dpop proof htm does not match the request method")
+	}
+
+	if !sameURL(claims.URL, v.baseURL+path) {
+		return nil, errors.New("dpop proof htu does not match the request url")
+	}
+
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	if age := time.Since(issuedAt); age > v.lifetime || age < -v.lifetime {
+		return nil, errors.New("dpop proof iat is outside the acceptable window")
+	}
+
+	if accessToken != "" && claims.ATH != AccessTokenHash(accessToken) {
+		return nil, errors.New("dpop proof ath does not match the access token")
+	}
+
+	jkt, err := Thumbprint(*header.JSONWebKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Proofs are remembered for as long as they could be accepted
+	fresh, err := v.replayCache.Add(ctx, jkt+":"+claims.ID, 2*v.lifetime)
+	if err != nil {
+		return nil, err
+	} else if !fresh {
+		return nil, ErrReplayedProof
+	}
+
+	return &Proof{ID: claims.ID, IssuedAt: issuedAt, JKT: jkt}, nil
+}
+
+// Thumbprint is the jkt of the key, its base64url encoded SHA-256 JWK thumbprint (RFC 7638).
+func Thumbprint(key jose.JSONWebKey) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// AccessTokenHash is the ath claim of proofs sent with the access token.
+func AccessTokenHash(accessToken string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// sameURL compares the URLs without their query and fragment,
+// the scheme and host are case insensitive.
+func sameURL(a, b string) bool {
+	parsedA, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+
+	parsedB, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(parsedA.Scheme, parsedB.Scheme) &&
+		strings.EqualFold(parsedA.Host, parsedB.Host) &&
+		parsedA.Path == parsedB.Path
+}
+
+func contains(items []string, item string) bool {
+	for _, value := range items {
+		if value == item {
+			return true
+		}
+	}
+	return false
+}
+
+// NewVerifier creates a verifier for proofs sent to the API served at baseURL.
+func NewVerifier(cfg config.DPoPConfig, baseURL string, replayCache ReplayCache) *Verifier {
+	return &Verifier{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		lifetime:    time.Second * time.Duration(cfg.ProofLifetime),
+		replayCache: replayCache,
+	}
+}
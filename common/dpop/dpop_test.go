@@ -0,0 +1,112 @@
+package dpop
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+)
+
+type memoryReplayCache map[string]bool
+
+func (m memoryReplayCache) Add(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	if m[id] {
+		return false, nil
+	}
+	m[id] = true
+	return true, nil
+}
+
+func signProof(t *testing.T, key *ecdsa.PrivateKey, jwk jose.JSONWebKey, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.ES256, Key: key},
+		&jose.SignerOptions{ExtraHeaders: map[jose.HeaderKey]interface{}{
+			jose.HeaderType: proofType,
+			"jwk":           jwk,
+		}},
+	)
+	assert.Nil(t, err)
+
+	payload, err := json.Marshal(claims)
+	assert.Nil(t, err)
+
+	object, err := signer.Sign(payload)
+	assert.Nil(t, err)
+
+	proof, err := object.CompactSerialize()
+	assert.Nil(t, err)
+	return proof
+}
+
+func TestVerifier(t *testing.T) {
+	ctx := context.Background()
+	verifier := NewVerifier(config.DPoPConfig{ProofLifetime: 60}, "https://api.example.com/", memoryReplayCache{})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	publicJWK := jose.JSONWebKey{Key: key.Public()}
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"jti": uuid.NewString(),
+			"htm": "GET",
+			"htu": "https://API.example.com/me?ignored=true",
+			"iat": time.Now().Unix(),
+			"ath": AccessTokenHash("token"),
+		}
+		for name, value := range overrides {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	proof := signProof(t, key, publicJWK, claims(nil))
+	result, err := verifier.Verify(ctx, proof, "GET", "/me", "token")
+	assert.Nil(t, err)
+
+	jkt, err := Thumbprint(publicJWK)
+	assert.Nil(t, err)
+	assert.Equal(t, jkt, result.JKT)
+
+	// Proofs can only be used once
+	_, err = verifier.Verify(ctx, proof, "GET", "/me", "token")
+	assert.ErrorIs(t, err, ErrReplayedProof)
+
+	_, err = verifier.Verify(ctx, signProof(t, key, publicJWK, claims(nil)), "POST", "/me", "token")
+	assert.NotNil(t, err)
+
+	_, err = verifier.Verify(ctx, signProof(t, key, publicJWK, claims(nil)), "GET", "/admin/users", "token")
+	assert.NotNil(t, err)
+
+	_, err = verifier.Verify(ctx, signProof(t, key, publicJWK, claims(nil)), "GET", "/me", "other token")
+	assert.NotNil(t, err)
+
+	stale := claims(map[string]interface{}{"iat": time.Now().Add(-2 * time.Minute).Unix()})
+	_, err = verifier.Verify(ctx, signProof(t, key, publicJWK, stale), "GET", "/me", "token")
+	assert.NotNil(t, err)
+
+	// Proofs for the token endpoint aren't bound to a token yet
+	unbound := claims(map[string]interface{}{"ath": nil})
+	_, err = verifier.Verify(ctx, signProof(t, key, publicJWK, unbound), "GET", "/me", "")
+	assert.Nil(t, err)
+
+	// The embedded key has to be the one the proof is signed with, and never a private key
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	_, err = verifier.Verify(ctx, signProof(t, otherKey, publicJWK, claims(nil)), "GET", "/me", "token")
+	assert.NotNil(t, err)
+
+	_, err = verifier.Verify(ctx, signProof(t, key, jose.JSONWebKey{Key: key}, claims(nil)), "GET", "/me", "token")
+	assert.NotNil(t, err)
+
+	_, err = verifier.Verify(ctx, "", "GET", "/me", "token")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,94 @@
+package keys
+
+import (
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+)
+
+// EncryptionKey is the key signed tokens are nested in a JWE with,
+// so their claims are only readable by the server.
+type EncryptionKey struct {
+	algorithm jose.KeyAlgorithm
+
+	// The keys are the same secret for dir encryption
+	encryptionKey interface{}
+	decryptionKey interface{}
+}
+
+// Encrypt nests the signed token in an A256GCM encrypted JWE.
+func (k *EncryptionKey) Encrypt(token string) (string, error) {
+	options := (&jose.EncrypterOptions{}).WithContentType("JWT").WithType("JWT")
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: k.algorithm, Key: k.encryptionKey}, options)
+	if err != nil {
+		return "", err
+	}
+
+	object, err := encrypter.Encrypt([]byte(token))
+	if err != nil {
+		return "", err
+	}
+	return object.CompactSerialize()
+}
+
+// Decrypt returns the signed token nested in the JWE.
+func (k *EncryptionKey) Decrypt(token string) (string, error) {
+	object, err := jose.ParseEncrypted(token)
+	if err != nil {
+		return "", err
+	}
+
+	if object.Header.Algorithm != string(k.algorithm) {
+		return "", fmt.Errorf("unexpected key management algorithm: %v", object.Header.Algorithm)
+	}
+
+	// Tokens are only ever encrypted with A256GCM, dir keys could otherwise be used with a weaker cipher
+	if enc := object.Header.ExtraHeaders["enc"]; enc != string(jose.A256GCM) {
+		return "", fmt.Errorf("unexpected content encryption algorithm: %v", enc)
+	}
+
+	plaintext, err := object.Decrypt(k.decryptionKey)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// newEncryptionKey loads the key for JWT_ENCRYPTION, it is nil when tokens aren't encrypted.
+// RSA-OAEP-256 keys are loaded like the signing key from JWT_ENCRYPTION_KEY_PATH.
+func newEncryptionKey(cfg config.JWTConfig) (*EncryptionKey, error) {
+	switch jose.KeyAlgorithm(cfg.Encryption) {
+	case "":
+		return nil, nil
+	case jose.DIRECT:
+		key, err := hex.DecodeString(cfg.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_ENCRYPTION_KEY: %w", err)
+		} else if len(key) != 32 {
+			return nil, fmt.Errorf("JWT_ENCRYPTION_KEY must be 32 bytes for A256GCM, got %d", len(key))
+		}
+		return &EncryptionKey{algorithm: jose.DIRECT, encryptionKey: key, decryptionKey: key}, nil
+	case jose.RSA_OAEP_256:
+		// Tokens encrypted to an ephemeral key stop decrypting on every restart
+		if cfg.EncryptionKeyPath == "" {
+			return nil, errors.New("JWT_ENCRYPTION_KEY_PATH is required for RSA-OAEP-256")
+		}
+
+		key, err := loadPrivateKey(cfg.EncryptionKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("RSA-OAEP-256 requires an RSA key, got %T", key)
+		}
+		return &EncryptionKey{algorithm: jose.RSA_OAEP_256, encryptionKey: &privateKey.PublicKey, decryptionKey: privateKey}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT encryption %s", cfg.Encryption)
+	}
+}
@@ -0,0 +1,183 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+)
+
+// KeySet holds the asymmetric key tokens are signed with,
+// relying parties verify them with the public half published as a JWKS.
+type KeySet struct {
+	signingMethod jwt.SigningMethod
+	keyID         string
+	privateKey    crypto.Signer
+
+	// encryptionKey is nil unless access tokens are encrypted
+	encryptionKey *EncryptionKey
+}
+
+// SigningMethod is the JWT signing method for the key, RS256 for RSA keys and ES256 for P-256 keys.
+func (k *KeySet) SigningMethod() jwt.SigningMethod {
+	return k.signingMethod
+}
+
+func (k *KeySet) KeyID() string {
+	return k.keyID
+}
+
+func (k *KeySet) PrivateKey() crypto.Signer {
+	return k.privateKey
+}
+
+func (k *KeySet) PublicKey() crypto.PublicKey {
+	return k.privateKey.Public()
+}
+
+// EncryptionKey is the key access tokens are encrypted with, it is nil when they're only signed.
+func (k *KeySet) EncryptionKey() *EncryptionKey {
+	return k.encryptionKey
+}
+
+// Sign signs the claims as a JWT carrying the key ID.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	token.Header["kid"] = k.keyID
+	return token.SignedString(k.privateKey)
+}
+
+// Verify parses a JWT signed by the key into the claims.
+func (k *KeySet) Verify(token string, claims jwt.Claims) error {
+	jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != k.signingMethod.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return k.PublicKey(), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !jwtToken.Valid {
+		return errors.New("invalid JWT token")
+	}
+	return nil
+}
+
+// HashHalf returns the base64url encoded left half of the value's hash,
+// as used by the at_hash and c_hash ID token claims.
+func (k *KeySet) HashHalf(value string) string {
+	// Both supported signing methods use SHA-256
+	digest := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(digest[:len(digest)/2])
+}
+
+// JWKS returns the public keys as a JSON Web Key Set.
+func (k *KeySet) JWKS() jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       k.PublicKey(),
+		KeyID:     k.keyID,
+		Algorithm: k.signingMethod.Alg(),
+		Use:       "sig",
+	}}}
+}
+
+// NewKeySet loads the PEM encoded private key at JWT_PRIVATE_KEY_PATH and the JWT_ENCRYPTION key.
+// An ephemeral RSA key is only generated for HS256 deployments, where it signs nothing but ID tokens.
+func NewKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	var privateKey crypto.Signer
+	if cfg.PrivateKeyPath == "" {
+		// Access tokens signed with an ephemeral key stop verifying on every restart
+		if cfg.Algorithm != "" && cfg.Algorithm != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_PATH is required for the %s algorithm", cfg.Algorithm)
+		}
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	} else {
+		key, err := loadPrivateKey(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	}
+
+	var signingMethod jwt.SigningMethod
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		signingMethod = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 EC keys are supported")
+		}
+		signingMethod = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+
+	// The key ID is the RFC 7638 thumbprint so it changes whenever the key does
+	thumbprint, err := (&jose.JSONWebKey{Key: privateKey.Public()}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptionKey, err := newEncryptionKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySet{
+		signingMethod: signingMethod,
+		keyID:         base64.RawURLEncoding.EncodeToString(thumbprint),
+		privateKey:    privateKey,
+		encryptionKey: encryptionKey,
+	}, nil
+}
+
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in the private key file")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
@@ -56,3 +56,15 @@ func Info(ctx context.Context, msg string, fields ...zap.Field) {
 
 	logger.Info(msg, storedFields...)
 }
+
+func Warn(ctx context.Context, msg string, fields ...zap.Field) {
+	data := ctx.Value(loggerfields)
+
+	var storedFields = []zap.Field{}
+	if data != nil {
+		storedFields = data.([]zap.Field)
+	}
+	storedFields = append(storedFields, fields...)
+
+	logger.Warn(msg, storedFields...)
+}
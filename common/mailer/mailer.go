@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
+)
+
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// logMailer writes outgoing mail to the application log,
+// it is meant for development until a real mail provider is configured.
+type logMailer struct{}
+
+func (m *logMailer) Send(ctx context.Context, to, subject, body string) error {
+	logger.Info(
+		ctx,
+		"Sending mail",
+		zap.String("to", to),
+		zap.String("subject", subject),
+		zap.String("body", body),
+	)
+	return nil
+}
+
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
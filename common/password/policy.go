@@ -0,0 +1,253 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+)
+
+// bcrypt silently ignores every byte after the 72nd.
+const bcryptMaxLength = 72
+
+// rangePrefixLength is the length of the hash prefixes HIBP range files are named after.
+const rangePrefixLength = 5
+
+// rangeFileCount is how many range files a complete download holds, one for every prefix.
+var rangeFileCount = 1 << (4 * rangePrefixLength)
+
+// Policy validates passwords against the configured rules
+// and an optional list of breached password hashes.
+type Policy struct {
+	config   config.PasswordConfig
+	breached map[[sha1.Size]byte]struct{}
+
+	// rangesDir holds HIBP range files which are read on demand instead of loading the list
+	rangesDir string
+}
+
+// Validate returns a list of human readable violations of the policy.
+// Identifiers such as the user's name and email may not appear in the password.
+func (p *Policy) Validate(password string, identifiers ...string) []string {
+	violations := []string{}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.config.MinLength))
+	}
+
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.config.MaxLength))
+	}
+
+	// Multi-byte characters reach bcrypt's limit before the character limit
+	if len(password) > bcryptMaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", bcryptMaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.config.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.config.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.config.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, fragment := range identifierFragments(identifiers) {
+		if strings.Contains(lowered, fragment) {
+			violations = append(violations, "must not contain your name or email")
+			break
+		}
+	}
+
+	if breached, err := p.IsBreached(password); err != nil {
+		violations = append(violations, "could not be checked against known data breaches, try again later")
+	} else if breached {
+		violations = append(violations, "has appeared in a known data breach")
+	}
+
+	return violations
+}
+
+// IsBreached reports whether the password's SHA-1 hash is in the breach list,
+// an error is only returned when a range file is missing or can't be read.
+func (p *Policy) IsBreached(password string) (bool, error) {
+	hash := sha1.Sum([]byte(password))
+	if p.rangesDir != "" {
+		return isInRange(p.rangesDir, hash)
+	}
+
+	_, ok := p.breached[hash]
+	return ok, nil
+}
+
+// identifierFragments splits names and emails into the lowercase
+// fragments which should not be reused in a password.
+func identifierFragments(identifiers []string) []string {
+	fragments := []string{}
+	for _, identifier := range identifiers {
+		identifier = strings.ToLower(strings.TrimSpace(identifier))
+		if local, _, ok := strings.Cut(identifier, "@"); ok {
+			identifier = local
+		}
+
+		parts := strings.FieldsFunc(identifier, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, part := range parts {
+			if len(part) >= 3 {
+				fragments = append(fragments, part)
+			}
+		}
+	}
+	return fragments
+}
+
+// isInRange looks the hash up in the HIBP range file named after its prefix, e.g. 21BD1.txt,
+// which lists the suffixes of the breached hashes starting with it as "<suffix>:<count>".
+func isInRange(dir string, hash [sha1.Size]byte) (bool, error) {
+	encoded := strings.ToUpper(hex.EncodeToString(hash[:]))
+	prefix, suffix := encoded[:rangePrefixLength], encoded[rangePrefixLength:]
+
+	// Every prefix has a file, so a missing one means the hash can't be checked
+	file, err := os.Open(filepath.Join(dir, prefix+".txt"))
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// checkRanges makes sure the directory holds a range file for every prefix,
+// a wrong path or a partial download would otherwise fail every lookup.
+func checkRanges(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	count := 0
+	for {
+		entries, err := file.ReadDir(1024)
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() && len(name) == rangePrefixLength+len(".txt") && strings.HasSuffix(name, ".txt") {
+				count++
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if count < rangeFileCount {
+		return fmt.Errorf("%s holds %d of the %d HIBP range files", dir, count, rangeFileCount)
+	}
+	return nil
+}
+
+// loadBreachedList reads a file in the HIBP "ordered by hash" format,
+// i.e. one upper or lower case hex SHA-1 hash per line optionally followed by ":<count>".
+func loadBreachedList(path string) (map[[sha1.Size]byte]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hashes := make(map[[sha1.Size]byte]struct{})
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		decoded, err := hex.DecodeString(hash)
+		if err != nil || len(decoded) != sha1.Size {
+			return nil, fmt.Errorf("invalid SHA-1 hash on line %d of %s", line, path)
+		}
+
+		var key [sha1.Size]byte
+		copy(key[:], decoded)
+		hashes[key] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	policy := &Policy{config: cfg}
+
+	if cfg.BreachedListPath == "" {
+		return policy, nil
+	}
+
+	info, err := os.Stat(cfg.BreachedListPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// A directory holds the HIBP range files, which are too large to load in memory
+	if info.IsDir() {
+		if err := checkRanges(cfg.BreachedListPath); err != nil {
+			return nil, err
+		}
+
+		policy.rangesDir = cfg.BreachedListPath
+		return policy, nil
+	}
+
+	breached, err := loadBreachedList(cfg.BreachedListPath)
+	if err != nil {
+		return nil, err
+	}
+	policy.breached = breached
+
+	return policy, nil
+}
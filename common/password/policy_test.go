@@ -0,0 +1,117 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+)
+
+func TestPolicy(t *testing.T) {
+	cfg := config.PasswordConfig{
+		MinLength:    8,
+		MaxLength:    72,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+	}
+
+	breached := sha1.Sum([]byte("Password123"))
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	err := os.WriteFile(path, []byte(fmt.Sprintf(
+		"%s:52256\n%s:3\n",
+		strings.ToUpper(hex.EncodeToString(breached[:])),
+		strings.Repeat("A", 40),
+	)), 0600)
+	assert.NoError(t, err)
+
+	cfg.BreachedListPath = path
+	policy, err := NewPolicy(cfg)
+	assert.NoError(t, err)
+
+	t.Run("TestPolicyAcceptsStrongPassword", func(t *testing.T) {
+		assert.Empty(t, policy.Validate("Tr0ub4dor&3x", "John Doe", "john@example.com"))
+	})
+
+	t.Run("TestPolicyCharacterClasses", func(t *testing.T) {
+		violations := policy.Validate("alllowercase")
+		assert.Contains(t, violations, "must contain an uppercase letter")
+		assert.Contains(t, violations, "must contain a digit")
+		assert.NotContains(t, violations, "must contain a lowercase letter")
+	})
+
+	t.Run("TestPolicyLength", func(t *testing.T) {
+		assert.Contains(t, policy.Validate("Ab1"), "must be at least 8 characters long")
+		assert.Contains(t, policy.Validate("Ab1"+strings.Repeat("x", 70)), "must be at most 72 characters long")
+
+		// Lengths are counted in characters, bcrypt's limit in bytes
+		assert.Contains(t, policy.Validate("Ab1éééé"), "must be at least 8 characters long")
+		violations := policy.Validate("Ab1" + strings.Repeat("é", 40))
+		assert.NotContains(t, violations, "must be at most 72 characters long")
+		assert.Contains(t, violations, "must be at most 72 bytes long")
+	})
+
+	t.Run("TestPolicyIdentifiers", func(t *testing.T) {
+		violations := policy.Validate("Johnny2000x", "John Doe", "jd@example.com")
+		assert.Contains(t, violations, "must not contain your name or email")
+
+		violations = policy.Validate("Xjdoe2000", "Jane", "jdoe@example.com")
+		assert.Contains(t, violations, "must not contain your name or email")
+	})
+
+	t.Run("TestPolicyBreachedList", func(t *testing.T) {
+		breached, err := policy.IsBreached("Password123")
+		assert.NoError(t, err)
+		assert.True(t, breached)
+		assert.Contains(t, policy.Validate("Password123"), "has appeared in a known data breach")
+
+		breached, err = policy.IsBreached("Tr0ub4dor&3x")
+		assert.NoError(t, err)
+		assert.False(t, breached)
+	})
+
+	t.Run("TestPolicyBreachedRanges", func(t *testing.T) {
+		dir := t.TempDir()
+		encoded := strings.ToUpper(hex.EncodeToString(breached[:]))
+		err := os.WriteFile(filepath.Join(dir, encoded[:5]+".txt"), []byte(fmt.Sprintf(
+			"%s:3\r\n%s:52256\r\n",
+			strings.Repeat("0", 35),
+			encoded[5:],
+		)), 0600)
+		assert.NoError(t, err)
+
+		// Directories missing range files are rejected up front
+		_, err = NewPolicy(config.PasswordConfig{BreachedListPath: t.TempDir()})
+		assert.Error(t, err)
+
+		defer func(count int) { rangeFileCount = count }(rangeFileCount)
+		rangeFileCount = 1
+
+		policy, err := NewPolicy(config.PasswordConfig{BreachedListPath: dir})
+		assert.NoError(t, err)
+
+		isBreached, err := policy.IsBreached("Password123")
+		assert.NoError(t, err)
+		assert.True(t, isBreached)
+
+		// A range file going missing fails the check instead of letting the password through
+		isBreached, err = policy.IsBreached("Tr0ub4dor&3x")
+		assert.Error(t, err)
+		assert.False(t, isBreached)
+		assert.Contains(t, policy.Validate("Tr0ub4dor&3x"), "could not be checked against known data breaches, try again later")
+	})
+
+	t.Run("TestPolicyInvalidBreachedList", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "invalid.txt")
+		assert.NoError(t, os.WriteFile(path, []byte("not-a-hash\n"), 0600))
+
+		_, err := NewPolicy(config.PasswordConfig{BreachedListPath: path})
+		assert.Error(t, err)
+	})
+}
@@ -3,10 +3,21 @@ package postgres
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/the-code-genin/simple-jwt-api-go/common/config"
 )
 
-func NewConnection(config config.DatabaseConfig) (*pgx.Conn, error) {
-	return pgx.Connect(context.Background(), config.URL)
+// NewConnection connects a pool to the database, its connections are shared by every service running concurrently.
+func NewConnection(config config.DatabaseConfig) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(context.Background(), config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
 }
@@ -0,0 +1,30 @@
+package random
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+)
+
+// Token returns a hex encoded string of 32 cryptographically secure random bytes.
+func Token() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// String returns n cryptographically secure random characters from the alphabet.
+func String(alphabet string, n int) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	result := make([]byte, n)
+	for i := range result {
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		result[i] = alphabet[index.Int64()]
+	}
+	return string(result), nil
+}
@@ -3,16 +3,33 @@ package redis
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
 )
 
 const (
 	defaultExpirationTime = time.Hour
+
+	// resubscribeDelay is waited before subscribing again to a closed subscription
+	resubscribeDelay = time.Second
+
+	// Nil is returned by Get when the key does not exist.
+	Nil = redis.Nil
 )
 
+// Script is a Lua script run with Client.Run.
+type Script = redis.Script
+
+// NewScript loads the Lua script.
+func NewScript(src string) *Script {
+	return redis.NewScript(src)
+}
+
 type Client struct {
 	*redis.Client
 
@@ -63,6 +80,12 @@ func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
 	return c.Client.Get(ctx, key).Result()
 }
 
+// GetDel gets the value of the key and deletes it in a single step.
+func (c *Client) GetDel(ctx context.Context, key string) (interface{}, error) {
+	key = fmt.Sprintf("%s:%s", c.namespace, key)
+	return c.Client.GetDel(ctx, key).Result()
+}
+
 func (c *Client) Delete(ctx context.Context, key string) (int64, error) {
 	key = fmt.Sprintf("%s:%s", c.namespace, key)
 	return c.Client.Del(ctx, key).Result()
@@ -73,3 +96,100 @@ func (c *Client) Exists(ctx context.Context, key string) (bool, error) {
 	i, err := c.Client.Exists(ctx, key).Result()
 	return i >= 1, err
 }
+
+// Scan returns the keys matching the pattern without the namespace, it walks the keyspace in batches.
+func (c *Client) Scan(ctx context.Context, pattern string) ([]string, error) {
+	prefix := fmt.Sprintf("%s:", c.namespace)
+	keys := []string{}
+
+	iter := c.Client.Scan(ctx, 0, prefix+pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), prefix))
+	}
+	return keys, iter.Err()
+}
+
+// HSetWithTTL sets the fields of the hash and expires it after the duration.
+func (c *Client) HSetWithTTL(ctx context.Context, key string, values map[string]interface{}, duration time.Duration) error {
+	key = fmt.Sprintf("%s:%s", c.namespace, key)
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, values)
+		pipe.Expire(ctx, key, duration)
+		return nil
+	})
+	return err
+}
+
+// HGetAll returns the fields of the hash, it returns Nil when the key does not exist.
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	key = fmt.Sprintf("%s:%s", c.namespace, key)
+	res, err := c.Client.HGetAll(ctx, key).Result()
+	if err == nil && len(res) == 0 {
+		return nil, Nil
+	}
+	return res, err
+}
+
+// Run runs the script atomically against the keys.
+func (c *Client) Run(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	namespaced := make([]string, 0, len(keys))
+	for _, key := range keys {
+		namespaced = append(namespaced, fmt.Sprintf("%s:%s", c.namespace, key))
+	}
+	return script.Run(ctx, c.Client, namespaced, args...).Result()
+}
+
+func (c *Client) Publish(ctx context.Context, channel string, message interface{}) error {
+	channel = fmt.Sprintf("%s:%s", c.namespace, channel)
+	return c.Client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe listens on the channel, the subscription must be closed once done with.
+func (c *Client) Subscribe(ctx context.Context, channel string) *redis.PubSub {
+	channel = fmt.Sprintf("%s:%s", c.namespace, channel)
+	return c.Client.Subscribe(ctx, channel)
+}
+
+// Listen calls handle with every message published on the channel until the context is done.
+// Messages published while the subscription is down are lost, subscribed is called whenever it is (re)established
+// so callers can drop what they may have missed. The channel is subscribed to again whenever the subscription is closed.
+func (c *Client) Listen(ctx context.Context, channel string, handle func(payload string), subscribed func()) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "Redis/Listen"), zap.String("channel", channel))
+
+	for {
+		subscription := c.Subscribe(ctx, channel)
+		c.receive(ctx, subscription.ChannelWithSubscriptions(), handle, subscribed)
+		_ = subscription.Close()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeDelay):
+			logger.Error(ctx, "The subscription was closed, subscribing again")
+		}
+	}
+}
+
+// receive handles the subscription's messages until it is closed or the context is done.
+func (c *Client) receive(ctx context.Context, messages <-chan interface{}, handle func(payload string), subscribed func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			switch message := message.(type) {
+			case *redis.Subscription:
+				// Sent again whenever the connection is reestablished
+				if message.Kind == "subscribe" {
+					subscribed()
+				}
+			case *redis.Message:
+				handle(message.Payload)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/the-code-genin/simple-jwt-api-go/common/config"
 )
@@ -102,3 +103,25 @@ func TestRedis(t *testing.T) {
 		t.Logf("Redis value has expired")
 	})
 }
+
+func TestReceive(t *testing.T) {
+	messages := make(chan interface{}, 4)
+	messages <- &redis.Subscription{Kind: "subscribe"}
+	messages <- &redis.Message{Payload: "a"}
+	// The subscription is confirmed again after reconnecting
+	messages <- &redis.Subscription{Kind: "subscribe"}
+	messages <- &redis.Message{Payload: "b"}
+	close(messages)
+
+	var payloads []string
+	subscriptions := 0
+	(&Client{}).receive(
+		context.Background(),
+		messages,
+		func(payload string) { payloads = append(payloads, payload) },
+		func() { subscriptions++ },
+	)
+
+	assert.Equal(t, []string{"a", "b"}, payloads)
+	assert.Equal(t, 2, subscriptions)
+}
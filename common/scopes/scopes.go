@@ -0,0 +1,113 @@
+package scopes
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+)
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+var ErrInvalidScope = errors.New("invalid scope")
+
+// Registry holds the scopes tokens may be issued with.
+type Registry struct {
+	known    map[string]bool
+	all      []string
+	defaults []string
+}
+
+// Resolve validates a space-delimited scope request against the registry,
+// allowed decides which of the known scopes the principal may receive.
+// An empty request resolves to the allowed default scopes.
+func (r *Registry) Resolve(requested string, allowed func(scope string) bool) ([]string, error) {
+	requestedScopes := Parse(requested)
+	if len(requestedScopes) == 0 {
+		result := []string{}
+		for _, scope := range r.defaults {
+			if allowed(scope) {
+				result = append(result, scope)
+			}
+		}
+		return result, nil
+	}
+
+	for _, scope := range requestedScopes {
+		if !r.known[scope] || !allowed(scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+	return requestedScopes, nil
+}
+
+// Known reports whether the scope is in the registry.
+func (r *Registry) Known(scope string) bool {
+	return r.known[scope]
+}
+
+// All returns every scope in the registry.
+func (r *Registry) All() []string {
+	return append([]string{}, r.all...)
+}
+
+// Defaults returns the scopes granted when none are requested.
+func (r *Registry) Defaults() []string {
+	return append([]string{}, r.defaults...)
+}
+
+// Parse splits a space-delimited scope string, dropping duplicates.
+func Parse(scope string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, item := range strings.Fields(scope) {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Format joins scopes into the space-delimited form used by the scope claim.
+func Format(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// Contains reports whether every required scope has been granted.
+func Contains(granted []string, required ...string) bool {
+	set := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		set[scope] = true
+	}
+
+	for _, scope := range required {
+		if !set[scope] {
+			return false
+		}
+	}
+	return true
+}
+
+func NewRegistry(cfg config.OAuthConfig) *Registry {
+	registry := &Registry{known: map[string]bool{}}
+	for _, scope := range cfg.Scopes {
+		if !registry.known[scope] {
+			registry.known[scope] = true
+			registry.all = append(registry.all, scope)
+		}
+	}
+
+	// Defaults outside the registry would never be grantable
+	for _, scope := range cfg.DefaultScopes {
+		if registry.known[scope] {
+			registry.defaults = append(registry.defaults, scope)
+		}
+	}
+
+	return registry
+}
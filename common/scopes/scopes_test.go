@@ -0,0 +1,84 @@
+package scopes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+)
+
+func TestResolve(t *testing.T) {
+	registry := NewRegistry(config.OAuthConfig{
+		Scopes:        []string{ScopeOpenID, ScopeProfile, ScopeEmail, "admin", ScopeProfile},
+		DefaultScopes: []string{ScopeProfile, "admin", "unknown"},
+	})
+	allowed := func(scope string) bool { return scope != ScopeEmail && scope != "admin" }
+
+	cases := map[string]struct {
+		requested string
+		expected  []string
+		err       error
+	}{
+		"requested":                  {requested: "openid profile", expected: []string{ScopeOpenID, ScopeProfile}},
+		"duplicates":                 {requested: " profile  openid profile ", expected: []string{ScopeProfile, ScopeOpenID}},
+		"unknown scope":              {requested: "profile unknown", err: ErrInvalidScope},
+		"disallowed scope":           {requested: "profile email", err: ErrInvalidScope},
+		"empty request":              {requested: "", expected: []string{ScopeProfile}},
+		"blank request":              {requested: "   ", expected: []string{ScopeProfile}},
+		"case sensitive scope names": {requested: "Profile", err: ErrInvalidScope},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			scopes, err := registry.Resolve(c.requested, allowed)
+			assert.ErrorIs(t, err, c.err)
+			assert.Equal(t, c.expected, scopes)
+		})
+	}
+
+	// Defaults outside the registry are dropped, and the rest are only granted when allowed
+	assert.Equal(t, []string{ScopeProfile, "admin"}, registry.Defaults())
+	assert.Equal(t, []string{ScopeOpenID, ScopeProfile, ScopeEmail, "admin"}, registry.All())
+	scopes, err := registry.Resolve("", func(string) bool { return false })
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, scopes)
+}
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		scope    string
+		expected []string
+	}{
+		"empty":      {scope: "", expected: []string{}},
+		"blank":      {scope: " \t ", expected: []string{}},
+		"single":     {scope: "profile", expected: []string{"profile"}},
+		"order kept": {scope: "email openid profile", expected: []string{"email", "openid", "profile"}},
+		"duplicates": {scope: "profile email profile email", expected: []string{"profile", "email"}},
+		"whitespace": {scope: "\tprofile  email\n", expected: []string{"profile", "email"}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, Parse(c.scope))
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	cases := map[string]struct {
+		granted  []string
+		required []string
+		expected bool
+	}{
+		"nothing required":       {granted: []string{"profile"}, expected: true},
+		"nothing granted":        {required: []string{"profile"}, expected: false},
+		"granted":                {granted: []string{"profile", "email"}, required: []string{"email"}, expected: true},
+		"all granted":            {granted: []string{"profile", "email"}, required: []string{"email", "profile"}, expected: true},
+		"one missing":            {granted: []string{"profile"}, required: []string{"profile", "email"}, expected: false},
+		"duplicate requirements": {granted: []string{"profile"}, required: []string{"profile", "profile"}, expected: true},
+		"prefix isn't a match":   {granted: []string{"profile:read"}, required: []string{"profile"}, expected: false},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, Contains(c.granted, c.required...))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+)
+
+type jwtCodec struct {
+	key []byte
+
+	// keySet is only set when tokens are signed asymmetrically
+	keySet *keys.KeySet
+
+	// encryptionKey is only set when the signed tokens are nested in a JWE
+	encryptionKey *keys.EncryptionKey
+}
+
+func (c *jwtCodec) Encode(ctx context.Context, claims Claims) (string, error) {
+	var token string
+	var err error
+	if c.keySet != nil {
+		token, err = c.keySet.Sign(claims)
+	} else {
+		token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(c.key)
+	}
+	if err != nil || c.encryptionKey == nil {
+		return token, err
+	}
+
+	return c.encryptionKey.Encrypt(token)
+}
+
+func (c *jwtCodec) Decode(ctx context.Context, token string) (*Claims, error) {
+	// Signed tokens issued before encryption was enabled are still accepted
+	if isJWE(token) {
+		if c.encryptionKey == nil {
+			return nil, errors.New("encrypted tokens are not accepted")
+		}
+
+		signed, err := c.encryptionKey.Decrypt(token)
+		if err != nil {
+			return nil, err
+		}
+		token = signed
+	}
+
+	claims := &Claims{}
+	if c.keySet != nil {
+		if err := c.keySet.Verify(token, claims); err != nil {
+			return nil, err
+		}
+	} else {
+		jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return c.key, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if !jwtToken.Valid {
+			return nil, errors.New("invalid JWT token")
+		}
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("invalid/incomplete JWT claims")
+	}
+
+	return claims, nil
+}
+
+// NewJWTCodec returns a codec for JWTs signed with the configured algorithm,
+// any algorithm other than HS256 must match the key set's.
+// Tokens are encrypted as well when the key set has an encryption key.
+func NewJWTCodec(cfg config.JWTConfig, keySet *keys.KeySet) (Codec, error) {
+	switch cfg.Algorithm {
+	case "", jwt.SigningMethodHS256.Alg():
+		return &jwtCodec{key: []byte(cfg.Key), encryptionKey: keySet.EncryptionKey()}, nil
+	case keySet.SigningMethod().Alg():
+		return &jwtCodec{keySet: keySet, encryptionKey: keySet.EncryptionKey()}, nil
+	default:
+		return nil, fmt.Errorf("JWT algorithm %s does not match the %s private key", cfg.Algorithm, keySet.SigningMethod().Alg())
+	}
+}
+
+// isJWE reports whether the token is a compact serialized JWE rather than a JWS.
+func isJWE(token string) bool {
+	return strings.Count(token, ".") == 4
+}
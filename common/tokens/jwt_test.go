@@ -0,0 +1,183 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+)
+
+func TestJWTCodec(t *testing.T) {
+	ctx := context.Background()
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+
+	codec, err := NewJWTCodec(config.JWTConfig{Key: "key"}, keySet)
+	assert.Nil(t, err)
+
+	scope := "users:read"
+	token, err := codec.Encode(ctx, Claims{
+		RegisteredClaims: NewRegisteredClaims("client", time.Minute),
+		Scope:            &scope,
+		ClientID:         "client",
+	})
+	assert.Nil(t, err)
+
+	claims, err := codec.Decode(ctx, token)
+	assert.Nil(t, err)
+	assert.True(t, claims.IsClient())
+	assert.Equal(t, scope, *claims.Scope)
+
+	otherCodec, err := NewJWTCodec(config.JWTConfig{Key: "other"}, keySet)
+	assert.Nil(t, err)
+	_, err = otherCodec.Decode(ctx, token)
+	assert.NotNil(t, err)
+
+	// Tokens signed with the shared key are rejected once signing is asymmetric
+	asymmetricCodec, err := NewJWTCodec(config.JWTConfig{Algorithm: "RS256"}, keySet)
+	assert.Nil(t, err)
+	_, err = asymmetricCodec.Decode(ctx, token)
+	assert.NotNil(t, err)
+
+	token, err = asymmetricCodec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("client", time.Minute), ClientID: "client"})
+	assert.Nil(t, err)
+	_, err = asymmetricCodec.Decode(ctx, token)
+	assert.Nil(t, err)
+
+	expired, err := codec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("client", -time.Minute), ClientID: "client"})
+	assert.Nil(t, err)
+	_, err = codec.Decode(ctx, expired)
+	assert.NotNil(t, err)
+
+	// Asymmetric signing needs a key that survives restarts
+	_, err = keys.NewKeySet(config.JWTConfig{Algorithm: "RS256"})
+	assert.NotNil(t, err)
+}
+
+func TestEncryptedJWTCodec(t *testing.T) {
+	ctx := context.Background()
+
+	directKey := func() string {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		assert.Nil(t, err)
+		return hex.EncodeToString(key)
+	}
+
+	rsaKeyPath := func() string {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.Nil(t, err)
+
+		path := filepath.Join(t.TempDir(), "encryption.pem")
+		data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		assert.Nil(t, os.WriteFile(path, data, 0600))
+		return path
+	}
+
+	configs := map[string]func() config.JWTConfig{
+		"dir": func() config.JWTConfig {
+			return config.JWTConfig{Key: "key", Encryption: "dir", EncryptionKey: directKey()}
+		},
+		"RSA-OAEP-256": func() config.JWTConfig {
+			return config.JWTConfig{Key: "key", Encryption: "RSA-OAEP-256", EncryptionKeyPath: rsaKeyPath()}
+		},
+	}
+
+	for name, newConfig := range configs {
+		t.Run(name, func(t *testing.T) {
+			cfg := newConfig()
+			keySet, err := keys.NewKeySet(cfg)
+			assert.Nil(t, err)
+			codec, err := NewJWTCodec(cfg, keySet)
+			assert.Nil(t, err)
+
+			token, err := codec.Encode(ctx, Claims{
+				RegisteredClaims: NewRegisteredClaims("user", time.Minute),
+				UserID:           "user",
+				UserEmail:        "user@example.com",
+			})
+			assert.Nil(t, err)
+			assert.True(t, isJWE(token))
+
+			// The claims aren't readable from the token
+			for _, part := range strings.Split(token, ".") {
+				decoded, _ := base64.RawURLEncoding.DecodeString(part)
+				assert.NotContains(t, string(decoded), "user@example.com")
+			}
+
+			claims, err := codec.Decode(ctx, token)
+			assert.Nil(t, err)
+			assert.Equal(t, "user@example.com", claims.UserEmail)
+
+			// Tampering with the ciphertext fails authentication
+			parts := strings.Split(token, ".")
+			ciphertext, err := base64.RawURLEncoding.DecodeString(parts[3])
+			assert.Nil(t, err)
+			ciphertext[0] ^= 1
+			parts[3] = base64.RawURLEncoding.EncodeToString(ciphertext)
+			_, err = codec.Decode(ctx, strings.Join(parts, "."))
+			assert.NotNil(t, err)
+
+			// Tokens encrypted with another key are rejected
+			otherConfig := newConfig()
+			otherKeySet, err := keys.NewKeySet(otherConfig)
+			assert.Nil(t, err)
+			otherCodec, err := NewJWTCodec(otherConfig, otherKeySet)
+			assert.Nil(t, err)
+			_, err = otherCodec.Decode(ctx, token)
+			assert.NotNil(t, err)
+
+			// Encrypted tokens are rejected once encryption is disabled
+			plainKeySet, err := keys.NewKeySet(config.JWTConfig{})
+			assert.Nil(t, err)
+			plainCodec, err := NewJWTCodec(config.JWTConfig{Key: "key"}, plainKeySet)
+			assert.Nil(t, err)
+			_, err = plainCodec.Decode(ctx, token)
+			assert.NotNil(t, err)
+		})
+	}
+
+	_, err := keys.NewKeySet(config.JWTConfig{Encryption: "dir", EncryptionKey: "short"})
+	assert.NotNil(t, err)
+	_, err = keys.NewKeySet(config.JWTConfig{Encryption: "RSA-OAEP-256"})
+	assert.NotNil(t, err)
+
+	// Tokens encrypted with the right key but another content encryption algorithm are rejected
+	cfg := config.JWTConfig{Key: "key", Encryption: "dir", EncryptionKey: directKey()}
+	keySet, err := keys.NewKeySet(cfg)
+	assert.Nil(t, err)
+	codec, err := NewJWTCodec(cfg, keySet)
+	assert.Nil(t, err)
+
+	plainKeySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+	plainCodec, err := NewJWTCodec(config.JWTConfig{Key: "key"}, plainKeySet)
+	assert.Nil(t, err)
+	signed, err := plainCodec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", time.Minute), UserID: "user"})
+	assert.Nil(t, err)
+
+	key, err := hex.DecodeString(cfg.EncryptionKey)
+	assert.Nil(t, err)
+	encrypter, err := jose.NewEncrypter(jose.A128CBC_HS256, jose.Recipient{Algorithm: jose.DIRECT, Key: key}, nil)
+	assert.Nil(t, err)
+	object, err := encrypter.Encrypt([]byte(signed))
+	assert.Nil(t, err)
+	token, err := object.CompactSerialize()
+	assert.Nil(t, err)
+
+	_, err = codec.Decode(ctx, token)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,70 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/random"
+)
+
+// Store keeps the claims of opaque tokens until they expire.
+type Store interface {
+	Add(ctx context.Context, token string, claims Claims, ttl time.Duration) error
+	Get(ctx context.Context, token string) (*Claims, error)
+	Delete(ctx context.Context, token string) error
+}
+
+// opaqueCodec issues random reference tokens which reveal nothing about their claims,
+// the claims are looked up in the store instead.
+type opaqueCodec struct {
+	store Store
+}
+
+func (c *opaqueCodec) Encode(ctx context.Context, claims Claims) (string, error) {
+	if claims.ExpiresAt == nil {
+		return "", errors.New("opaque tokens must expire")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return "", errors.New("token would already be expired")
+	}
+
+	token, err := random.Token()
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.store.Add(ctx, token, claims, ttl); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (c *opaqueCodec) Decode(ctx context.Context, token string) (*Claims, error) {
+	claims, err := c.store.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ExpiresAt == nil || time.Now().After(claims.ExpiresAt.Time) {
+		return nil, errors.New("expired access token")
+	}
+
+	return claims, nil
+}
+
+func (c *opaqueCodec) Revoke(ctx context.Context, token string) error {
+	return c.store.Delete(ctx, token)
+}
+
+// isOpaque tells opaque tokens, which are hex encoded, apart from the dot separated JWTs.
+func isOpaque(token string) bool {
+	return token != "" && !strings.Contains(token, ".")
+}
+
+func NewOpaqueCodec(store Store) Codec {
+	return &opaqueCodec{store}
+}
@@ -0,0 +1,88 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+)
+
+type memoryStore map[string]Claims
+
+func (m memoryStore) Add(ctx context.Context, token string, claims Claims, ttl time.Duration) error {
+	m[token] = claims
+	return nil
+}
+
+func (m memoryStore) Get(ctx context.Context, token string) (*Claims, error) {
+	claims, ok := m[token]
+	if !ok {
+		return nil, errors.New("unknown token")
+	}
+	return &claims, nil
+}
+
+func (m memoryStore) Delete(ctx context.Context, token string) error {
+	delete(m, token)
+	return nil
+}
+
+func TestOpaqueCodec(t *testing.T) {
+	ctx := context.Background()
+	codec := NewOpaqueCodec(memoryStore{})
+
+	token, err := codec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", time.Minute), UserID: "user"})
+	assert.Nil(t, err)
+	assert.NotContains(t, token, "user")
+
+	claims, err := codec.Decode(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, "user", claims.UserID)
+
+	// Revoking deletes the token's claims
+	revoker, ok := codec.(Revoker)
+	assert.True(t, ok)
+	assert.Nil(t, revoker.Revoke(ctx, token))
+	_, err = codec.Decode(ctx, token)
+	assert.NotNil(t, err)
+
+	_, err = codec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", -time.Minute)})
+	assert.NotNil(t, err)
+}
+
+func TestOpaqueFormatAcceptsJWTs(t *testing.T) {
+	ctx := context.Background()
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+
+	jwtConfig := config.JWTConfig{Key: "key"}
+	jwtCodec, err := NewJWTCodec(jwtConfig, keySet)
+	assert.Nil(t, err)
+	jwtToken, err := jwtCodec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", time.Minute), UserID: "user"})
+	assert.Nil(t, err)
+
+	jwtConfig.Format = FormatOpaque
+	codec, err := NewCodec(&config.Config{JWT: jwtConfig}, keySet, memoryStore{})
+	assert.Nil(t, err)
+
+	token, err := codec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", time.Minute), UserID: "user"})
+	assert.Nil(t, err)
+
+	for _, token := range []string{token, jwtToken} {
+		claims, err := codec.Decode(ctx, token)
+		assert.Nil(t, err)
+		assert.Equal(t, "user", claims.UserID)
+	}
+
+	// JWTs can't be revoked directly, they are blacklisted instead
+	revoker, ok := codec.(Revoker)
+	assert.True(t, ok)
+	assert.ErrorIs(t, revoker.Revoke(ctx, jwtToken), ErrNotRevocable)
+	assert.Nil(t, revoker.Revoke(ctx, token))
+	_, err = codec.Decode(ctx, token)
+	assert.NotNil(t, err)
+}
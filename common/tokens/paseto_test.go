@@ -0,0 +1,83 @@
+package tokens
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+)
+
+func TestPASETOCodec(t *testing.T) {
+	ctx := context.Background()
+	pasetoConfig := config.PASETOConfig{
+		SecretKey: paseto.NewV4AsymmetricSecretKey().ExportHex(),
+		LocalKey:  paseto.NewV4SymmetricKey().ExportHex(),
+	}
+
+	for _, local := range []bool{false, true} {
+		codec, err := NewPASETOCodec(pasetoConfig, local)
+		assert.Nil(t, err)
+
+		token, err := codec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", time.Minute), UserID: "user"})
+		assert.Nil(t, err)
+		assert.True(t, isPASETO(token))
+		assert.Equal(t, local, strings.HasPrefix(token, pasetoLocalPrefix))
+
+		claims, err := codec.Decode(ctx, token)
+		assert.Nil(t, err)
+		assert.Equal(t, "user", claims.UserID)
+		assert.Equal(t, "user", claims.Subject)
+		assert.NotNil(t, claims.ExpiresAt)
+
+		// Tokens of another key are rejected
+		otherCodec, err := NewPASETOCodec(config.PASETOConfig{
+			SecretKey: paseto.NewV4AsymmetricSecretKey().ExportHex(),
+			LocalKey:  paseto.NewV4SymmetricKey().ExportHex(),
+		}, local)
+		assert.Nil(t, err)
+		_, err = otherCodec.Decode(ctx, token)
+		assert.NotNil(t, err)
+
+		expired, err := codec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", -time.Minute), UserID: "user"})
+		assert.Nil(t, err)
+		_, err = codec.Decode(ctx, expired)
+		assert.NotNil(t, err)
+	}
+
+	_, err := NewPASETOCodec(config.PASETOConfig{}, true)
+	assert.NotNil(t, err)
+}
+
+func TestPASETOFormatAcceptsJWTs(t *testing.T) {
+	ctx := context.Background()
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+
+	jwtConfig := config.JWTConfig{Key: "key"}
+	jwtCodec, err := NewJWTCodec(jwtConfig, keySet)
+	assert.Nil(t, err)
+	jwtToken, err := jwtCodec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", time.Minute), UserID: "user"})
+	assert.Nil(t, err)
+
+	jwtConfig.Format = FormatPASETOPublic
+	codec, err := NewCodec(&config.Config{
+		JWT:    jwtConfig,
+		PASETO: config.PASETOConfig{SecretKey: paseto.NewV4AsymmetricSecretKey().ExportHex()},
+	}, keySet, nil)
+	assert.Nil(t, err)
+
+	token, err := codec.Encode(ctx, Claims{RegisteredClaims: NewRegisteredClaims("user", time.Minute), UserID: "user"})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(token, pasetoPublicPrefix))
+
+	for _, token := range []string{token, jwtToken} {
+		claims, err := codec.Decode(ctx, token)
+		assert.Nil(t, err)
+		assert.Equal(t, "user", claims.UserID)
+	}
+}
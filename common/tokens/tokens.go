@@ -0,0 +1,174 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+)
+
+const (
+	FormatJWT          = "jwt"
+	FormatOpaque       = "opaque"
+	FormatPASETOPublic = "paseto-public"
+	FormatPASETOLocal  = "paseto-local"
+)
+
+// Codec encodes access token claims into tokens and verifies them back.
+type Codec interface {
+	Encode(ctx context.Context, claims Claims) (string, error)
+	// Decode verifies the token's integrity and expiry and returns its claims.
+	Decode(ctx context.Context, token string) (*Claims, error)
+}
+
+// ErrNotRevocable is returned by Revoke for tokens that have to be blacklisted instead.
+var ErrNotRevocable = errors.New("the token can't be revoked directly")
+
+// Revoker is implemented by codecs whose tokens can be revoked directly,
+// tokens of other codecs have to be blacklisted until they expire.
+type Revoker interface {
+	Revoke(ctx context.Context, token string) error
+}
+
+// Claims are the claims carried by every access token.
+type Claims struct {
+	jwt.RegisteredClaims
+
+	// The user claims are empty for tokens issued to a client acting on its own behalf
+	UserID      string   `json:"user_id,omitempty"`
+	UserEmail   string   `json:"user_email,omitempty"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+
+	// UserName and EmailVerified let the user be described without looking them up
+	UserName      string `json:"user_name,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+
+	// Scope is nil for tokens issued before scopes were introduced
+	Scope *string `json:"scope,omitempty"`
+
+	// ClientID is set when the token was issued to an OAuth client
+	ClientID string `json:"client_id,omitempty"`
+
+	// Actor is set when the token was exchanged for delegated access (RFC 8693)
+	Actor *Actor `json:"act,omitempty"`
+
+	// Confirmation binds the token to the key of a DPoP proof (RFC 9449)
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+
+	// SessionID is the session the token was recorded as, it is only set on user tokens
+	SessionID string `json:"sid,omitempty"`
+}
+
+// Actor is the party a token was delegated to, the nested actors
+// are the prior links of the delegation chain.
+type Actor struct {
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id,omitempty"`
+	Actor    *Actor `json:"act,omitempty"`
+}
+
+// Confirmation identifies the key a token is bound to.
+type Confirmation struct {
+	JKT string `json:"jkt"`
+}
+
+// NewConfirmation binds a token to the DPoP key with the thumbprint, it is nil for bearer tokens.
+func NewConfirmation(jkt string) *Confirmation {
+	if jkt == "" {
+		return nil
+	}
+	return &Confirmation{JKT: jkt}
+}
+
+// IsClient reports whether the token was issued to a client acting on its own behalf.
+func (c *Claims) IsClient() bool {
+	return c.UserID == "" && c.ClientID != ""
+}
+
+// NewRegisteredClaims returns the registered claims for a new token with a unique ID.
+func NewRegisteredClaims(subject string, ttl time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+}
+
+// Confidential reports whether the claims of the configured tokens can only be read by the issuer,
+// i.e. JWTs nested in a JWE, v4.local PASETOs and opaque tokens.
+func Confidential(cfg config.JWTConfig) bool {
+	switch cfg.Format {
+	case "", FormatJWT:
+		return cfg.Encryption != ""
+	case FormatOpaque, FormatPASETOLocal:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewCodec returns the codec for the configured token format,
+// the store is only used by opaque tokens.
+func NewCodec(cfg *config.Config, keySet *keys.KeySet, store Store) (Codec, error) {
+	switch cfg.JWT.Format {
+	case "", FormatJWT:
+		return NewJWTCodec(cfg.JWT, keySet)
+	case FormatOpaque:
+		jwtCodec, err := NewJWTCodec(cfg.JWT, keySet)
+		if err != nil {
+			return nil, err
+		}
+
+		return &prefixCodec{NewOpaqueCodec(store), isOpaque, jwtCodec}, nil
+	case FormatPASETOPublic, FormatPASETOLocal:
+		jwtCodec, err := NewJWTCodec(cfg.JWT, keySet)
+		if err != nil {
+			return nil, err
+		}
+
+		pasetoCodec, err := NewPASETOCodec(cfg.PASETO, cfg.JWT.Format == FormatPASETOLocal)
+		if err != nil {
+			return nil, err
+		}
+
+		return &prefixCodec{pasetoCodec, isPASETO, jwtCodec}, nil
+	default:
+		return nil, fmt.Errorf("unsupported token format %s", cfg.JWT.Format)
+	}
+}
+
+// prefixCodec issues PASETO or opaque tokens and still accepts the JWTs issued before switching to them,
+// the format of a token is recognised by its prefix.
+type prefixCodec struct {
+	codec    Codec
+	isIssued func(token string) bool
+	jwt      Codec
+}
+
+func (c *prefixCodec) Encode(ctx context.Context, claims Claims) (string, error) {
+	return c.codec.Encode(ctx, claims)
+}
+
+func (c *prefixCodec) Decode(ctx context.Context, token string) (*Claims, error) {
+	if c.isIssued(token) {
+		return c.codec.Decode(ctx, token)
+	}
+	return c.jwt.Decode(ctx, token)
+}
+
+// Revoke revokes the tokens issued by a codec that can, JWTs have to be blacklisted.
+func (c *prefixCodec) Revoke(ctx context.Context, token string) error {
+	revoker, ok := c.codec.(Revoker)
+	if !ok || !c.isIssued(token) {
+		return ErrNotRevocable
+	}
+	return revoker.Revoke(ctx, token)
+}
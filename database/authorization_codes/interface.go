@@ -0,0 +1,26 @@
+package authorization_codes
+
+import (
+	"context"
+	"time"
+)
+
+type AuthorizationCodesRepository interface {
+	Add(ctx context.Context, code string, authorization AuthorizationCode, ttl time.Duration) error
+
+	// Take returns the authorization code and deletes it,
+	// so a code can be redeemed at most once.
+	Take(ctx context.Context, code string) (*AuthorizationCode, error)
+}
+
+type AuthorizationCode struct {
+	ClientID            string   `json:"client_id"`
+	UserID              string   `json:"user_id"`
+	RedirectURI         string   `json:"redirect_uri"`
+	Scopes              []string `json:"scopes"`
+	CodeChallenge       string   `json:"code_challenge"`
+	CodeChallengeMethod string   `json:"code_challenge_method"`
+	Nonce               string   `json:"nonce"`
+	AuthTime            int64    `json:"auth_time"`
+	ExpiresAt           int64    `json:"expires_at"`
+}
@@ -0,0 +1,44 @@
+package authorization_codes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+)
+
+type authorizationCodesRepository struct {
+	client *redis.Client
+}
+
+func (codes *authorizationCodesRepository) Add(ctx context.Context, code string, authorization AuthorizationCode, ttl time.Duration) error {
+	data, err := json.Marshal(authorization)
+	if err != nil {
+		return err
+	}
+	return codes.client.Set(ctx, fmt.Sprintf("authorization_codes:%s", code), data, ttl)
+}
+
+func (codes *authorizationCodesRepository) Take(ctx context.Context, code string) (*AuthorizationCode, error) {
+	res, err := codes.client.GetDel(ctx, fmt.Sprintf("authorization_codes:%s", code))
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := res.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid authorization code data")
+	}
+
+	authorization := &AuthorizationCode{}
+	if err := json.Unmarshal([]byte(data), authorization); err != nil {
+		return nil, err
+	}
+	return authorization, nil
+}
+
+func NewAuthorizationCodesRepository(client *redis.Client) AuthorizationCodesRepository {
+	return &authorizationCodesRepository{client}
+}
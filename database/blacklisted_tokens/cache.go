@@ -0,0 +1,143 @@
+package blacklisted_tokens
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/cache"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"go.uber.org/zap"
+)
+
+const (
+	// InvalidationsChannel carries the tokens and users revoked on any instance
+	InvalidationsChannel = "blacklisted_tokens:invalidations"
+
+	tokenInvalidationPrefix = "token:"
+	userInvalidationPrefix  = "user:"
+)
+
+// CacheStats counts the hits of every instance's blacklist cache, see cache.Stats.Publish.
+var CacheStats = &cache.Stats{}
+
+// cachedBlacklistedTokensRepository keeps blacklist lookups in process,
+// other instances are told to drop their stale entries through redis pub/sub.
+type cachedBlacklistedTokensRepository struct {
+	repository  BlacklistedTokensRepository
+	client      *redis.Client
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	tokens        *cache.LRU[bool]
+	revokedBefore *cache.LRU[int64]
+}
+
+func (tokens *cachedBlacklistedTokensRepository) Exists(ctx context.Context, token, tokenID string) (bool, error) {
+	key := blacklistKey(token, tokenID)
+	if !cache.Skipped(ctx) {
+		if blacklisted, ok := tokens.tokens.Get(key); ok {
+			return blacklisted, nil
+		}
+	}
+
+	generation := tokens.tokens.Generation()
+	blacklisted, err := tokens.repository.Exists(ctx, token, tokenID)
+	if err != nil {
+		return false, err
+	}
+
+	// Blacklisted tokens stay blacklisted, other tokens may be blacklisted at any time
+	if blacklisted {
+		tokens.tokens.Set(key, true, tokens.ttl)
+	} else {
+		tokens.tokens.SetIfCurrent(key, false, tokens.negativeTTL, generation)
+	}
+	return blacklisted, nil
+}
+
+func (tokens *cachedBlacklistedTokensRepository) Add(ctx context.Context, token, tokenID string, expiresAt time.Time) error {
+	if err := tokens.repository.Add(ctx, token, tokenID, expiresAt); err != nil {
+		return err
+	}
+
+	key := blacklistKey(token, tokenID)
+	tokens.tokens.Set(key, true, tokens.ttl)
+	return tokens.client.Publish(ctx, InvalidationsChannel, tokenInvalidationPrefix+key)
+}
+
+func (tokens *cachedBlacklistedTokensRepository) RevokeUser(ctx context.Context, userID string, before int64, expiry int64) error {
+	if err := tokens.repository.RevokeUser(ctx, userID, before, expiry); err != nil {
+		return err
+	}
+
+	tokens.revokedBefore.Delete(userID)
+	return tokens.client.Publish(ctx, InvalidationsChannel, userInvalidationPrefix+userID)
+}
+
+func (tokens *cachedBlacklistedTokensRepository) UserRevokedBefore(ctx context.Context, userID string) (int64, error) {
+	if !cache.Skipped(ctx) {
+		if revokedBefore, ok := tokens.revokedBefore.Get(userID); ok {
+			return revokedBefore, nil
+		}
+	}
+
+	// The revocation time isn't cached when it is invalidated while being read
+	generation := tokens.revokedBefore.Generation()
+	revokedBefore, err := tokens.repository.UserRevokedBefore(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// The revocation time moves forward whenever the user's tokens are revoked again
+	tokens.revokedBefore.SetIfCurrent(userID, revokedBefore, tokens.negativeTTL, generation)
+	return revokedBefore, nil
+}
+
+// listen applies the invalidations published by every instance until the context is done.
+func (tokens *cachedBlacklistedTokensRepository) listen(ctx context.Context) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "CachedBlacklistedTokensRepository/listen"))
+	tokens.client.Listen(ctx, InvalidationsChannel, tokens.apply, tokens.clear)
+}
+
+func (tokens *cachedBlacklistedTokensRepository) apply(payload string) {
+	switch {
+	case strings.HasPrefix(payload, tokenInvalidationPrefix):
+		tokens.tokens.Set(strings.TrimPrefix(payload, tokenInvalidationPrefix), true, tokens.ttl)
+	case strings.HasPrefix(payload, userInvalidationPrefix):
+		tokens.revokedBefore.Delete(strings.TrimPrefix(payload, userInvalidationPrefix))
+	}
+}
+
+// clear drops every entry once subscribed, as invalidations may have been missed while the subscription was down.
+func (tokens *cachedBlacklistedTokensRepository) clear() {
+	tokens.tokens.Clear()
+	tokens.revokedBefore.Clear()
+}
+
+// NewCachedBlacklistedTokensRepository puts a bounded in-process cache in front of the repository,
+// it listens for invalidations from other instances until the context is done.
+func NewCachedBlacklistedTokensRepository(
+	ctx context.Context,
+	repository BlacklistedTokensRepository,
+	client *redis.Client,
+	cfg config.CacheConfig,
+) BlacklistedTokensRepository {
+	if cfg.BlacklistSize <= 0 {
+		return repository
+	}
+
+	cached := &cachedBlacklistedTokensRepository{
+		repository:    repository,
+		client:        client,
+		ttl:           time.Second * time.Duration(cfg.TTL),
+		negativeTTL:   time.Second * time.Duration(cfg.NegativeTTL),
+		tokens:        cache.NewLRU[bool](cfg.BlacklistSize, CacheStats),
+		revokedBefore: cache.NewLRU[int64](cfg.BlacklistSize, CacheStats),
+	}
+	go cached.listen(ctx)
+
+	return cached
+}
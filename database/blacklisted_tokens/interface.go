@@ -1,8 +1,17 @@
 package blacklisted_tokens
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type BlacklistedTokensRepository interface {
-	Exists(ctx context.Context, token string) (bool, error)
-	Add(ctx context.Context, token string, expiry int64) error
+	// Exists reports whether the token with the ID is blacklisted, the ID may be empty for tokens without a jti.
+	Exists(ctx context.Context, token, tokenID string) (bool, error)
+	// Add blacklists the token until it expires, it is keyed by its ID or by its digest when it has none.
+	Add(ctx context.Context, token, tokenID string, expiresAt time.Time) error
+
+	// RevokeUser blacklists every token issued to the user before the given unix time.
+	RevokeUser(ctx context.Context, userID string, before int64, expiry int64) error
+	UserRevokedBefore(ctx context.Context, userID string) (int64, error)
 }
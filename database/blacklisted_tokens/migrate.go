@@ -0,0 +1,67 @@
+package blacklisted_tokens
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+)
+
+const (
+	legacyKeyPrefix = "blacklisted_tokens:"
+
+	// legacyKeysMigratedKey is set once the legacy entries have been rewritten, so later startups skip the scan
+	legacyKeysMigratedKey = "migrations:blacklisted_tokens_keys"
+)
+
+// MigrateLegacyKeys rewrites the entries keyed by the raw token to their jti or digest key, expiring with the token.
+// Those entries were written without an expiry, so they would otherwise keep usable tokens in redis forever.
+// Tokens whose expiry can't be read are kept blacklisted for maxTTL. It returns how many entries were rewritten.
+func MigrateLegacyKeys(ctx context.Context, client *redis.Client, maxTTL time.Duration) (int, error) {
+	migrated, err := client.Exists(ctx, legacyKeysMigratedKey)
+	if err != nil || migrated {
+		return 0, err
+	}
+
+	keys, err := client.Scan(ctx, legacyKeyPrefix+"*")
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	now := time.Now()
+	for _, key := range keys {
+		token := strings.TrimPrefix(key, legacyKeyPrefix)
+		if strings.Contains(token, ":") {
+			// Already keyed by jti or digest
+			continue
+		}
+
+		newKey, expiresAt := legacyEntry(token, now, maxTTL)
+		if ttl := expiresAt.Sub(now); ttl > 0 {
+			if err := client.Set(ctx, newKey, expiresAt.Unix(), ttl); err != nil {
+				return count, err
+			}
+		}
+
+		if _, err := client.Delete(ctx, key); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, client.Set(ctx, legacyKeysMigratedKey, now.Unix(), 0)
+}
+
+// legacyEntry returns the key and the expiry of a token blacklisted under its raw value.
+// The signature isn't verified, the token was only blacklisted after it was.
+func legacyEntry(token string, now time.Time, maxTTL time.Duration) (string, time.Time) {
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil || claims.ExpiresAt == nil {
+		return blacklistKey(token, ""), now.Add(maxTTL)
+	}
+
+	return blacklistKey(token, claims.ID), claims.ExpiresAt.Time
+}
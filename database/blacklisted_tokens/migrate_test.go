@@ -0,0 +1,46 @@
+package blacklisted_tokens
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyEntry(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("key"))
+		assert.Nil(t, err)
+		return token
+	}
+	digestKey := func(token string) string {
+		digest := sha256.Sum256([]byte(token))
+		return "blacklisted_tokens:sha256:" + hex.EncodeToString(digest[:])
+	}
+
+	// Tokens with a jti are keyed by it
+	token := sign(jwt.MapClaims{"jti": "abc", "exp": now.Add(time.Hour).Unix()})
+	key, expiresAt := legacyEntry(token, now, time.Minute)
+	assert.Equal(t, "blacklisted_tokens:jti:abc", key)
+	assert.Equal(t, now.Add(time.Hour), expiresAt)
+
+	// Tokens issued before they carried a jti are keyed by their digest
+	token = sign(jwt.MapClaims{"user_id": "1", "exp": now.Add(time.Hour).Unix()})
+	key, expiresAt = legacyEntry(token, now, time.Minute)
+	assert.Equal(t, digestKey(token), key)
+	assert.Equal(t, now.Add(time.Hour), expiresAt)
+
+	// Expired tokens are dropped
+	token = sign(jwt.MapClaims{"user_id": "1", "exp": now.Add(-time.Hour).Unix()})
+	_, expiresAt = legacyEntry(token, now, time.Minute)
+	assert.False(t, expiresAt.After(now))
+
+	// Unreadable tokens are kept for the longest a token lives
+	key, expiresAt = legacyEntry("not-a-token", now, time.Minute)
+	assert.Equal(t, digestKey("not-a-token"), key)
+	assert.Equal(t, now.Add(time.Minute), expiresAt)
+}
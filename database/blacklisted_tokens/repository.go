@@ -2,7 +2,11 @@ package blacklisted_tokens
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
@@ -12,24 +16,57 @@ type blacklistedTokensRepository struct {
 	client *redis.Client
 }
 
-func (tokens *blacklistedTokensRepository) Exists(ctx context.Context, token string) (bool, error) {
-	res, err := tokens.client.Exists(ctx, fmt.Sprintf("blacklisted_tokens:%s", token))
-	if err != nil {
-		return false, err
+func (tokens *blacklistedTokensRepository) Exists(ctx context.Context, token, tokenID string) (bool, error) {
+	// Entries keyed by the raw token are rewritten at startup, see MigrateLegacyKeys
+	return tokens.client.Exists(ctx, blacklistKey(token, tokenID))
+}
+
+func (tokens *blacklistedTokensRepository) Add(ctx context.Context, token, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Expired tokens are rejected anyway
+		return nil
 	}
-	return res, nil
+
+	return tokens.client.Set(ctx, blacklistKey(token, tokenID), expiresAt.Unix(), ttl)
 }
 
-func (tokens *blacklistedTokensRepository) Add(ctx context.Context, token string, expiry int64) error {
+func (tokens *blacklistedTokensRepository) RevokeUser(ctx context.Context, userID string, before int64, expiry int64) error {
 	err := tokens.client.Set(
 		ctx,
-		fmt.Sprintf("blacklisted_tokens:%s", token),
-		expiry,
+		fmt.Sprintf("revoked_users:%s", userID),
+		before,
 		time.Until(time.Unix(expiry, 0)),
 	)
 	return err
 }
 
+func (tokens *blacklistedTokensRepository) UserRevokedBefore(ctx context.Context, userID string) (int64, error) {
+	res, err := tokens.client.Get(ctx, fmt.Sprintf("revoked_users:%s", userID))
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	value, ok := res.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid revocation time")
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// blacklistKey keys the token by its jti, or by its SHA-256 digest when it has none,
+// so the blacklist never holds usable tokens.
+func blacklistKey(token, tokenID string) string {
+	if tokenID != "" {
+		return fmt.Sprintf("blacklisted_tokens:jti:%s", tokenID)
+	}
+
+	digest := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("blacklisted_tokens:sha256:%s", hex.EncodeToString(digest[:]))
+}
+
 func NewBlacklistedTokensRepository(client *redis.Client) BlacklistedTokensRepository {
 	return &blacklistedTokensRepository{client}
 }
@@ -0,0 +1,39 @@
+package device_authorizations
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+)
+
+type DeviceAuthorizationsRepository interface {
+	// Add stores the authorization under its device code and indexes it by its user code.
+	Add(ctx context.Context, deviceCode string, authorization DeviceAuthorization, ttl time.Duration) error
+	Get(ctx context.Context, deviceCode string) (*DeviceAuthorization, error)
+	GetDeviceCode(ctx context.Context, userCode string) (string, error)
+
+	// Decide moves a pending authorization to the status, recording the user who approved it.
+	// It returns false when the authorization is no longer pending.
+	Decide(ctx context.Context, deviceCode, status, userID string) (bool, error)
+	// RecordPoll sets when the device last polled and the interval it must keep, leaving the status as is.
+	RecordPoll(ctx context.Context, deviceCode string, lastPolledAt int64, interval int) error
+	// Redeem returns the authorization and deletes it if it was decided and issued to the client,
+	// so an approval is redeemed at most once.
+	Redeem(ctx context.Context, deviceCode, clientID string) (*DeviceAuthorization, error)
+}
+
+type DeviceAuthorization struct {
+	ClientID     string   `json:"client_id"`
+	Scopes       []string `json:"scopes"`
+	UserCode     string   `json:"user_code"`
+	Status       string   `json:"status"`
+	UserID       string   `json:"user_id,omitempty"`
+	Interval     int      `json:"interval"`
+	LastPolledAt int64    `json:"last_polled_at"`
+	ExpiresAt    int64    `json:"expires_at"`
+}
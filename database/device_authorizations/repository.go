@@ -0,0 +1,174 @@
+package device_authorizations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+)
+
+// The authorizations are stored as hashes so polls and decisions only write their own fields,
+// the scripts check the hash still exists so they never recreate an expired one without a TTL.
+var (
+	decideScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "status") ~= ARGV[1] then
+	return 0
+end
+redis.call("HSET", KEYS[1], "status", ARGV[2], "user_id", ARGV[3])
+return 1`)
+
+	recordPollScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "last_polled_at", ARGV[1], "interval", ARGV[2])
+return 1`)
+
+	redeemScript = redis.NewScript(`
+local fields = redis.call("HGETALL", KEYS[1])
+local status = redis.call("HGET", KEYS[1], "status")
+if status and status ~= ARGV[1] and redis.call("HGET", KEYS[1], "client_id") == ARGV[2] then
+	redis.call("DEL", KEYS[1])
+end
+return fields`)
+)
+
+type deviceAuthorizationsRepository struct {
+	client *redis.Client
+}
+
+func (devices *deviceAuthorizationsRepository) Add(
+	ctx context.Context,
+	deviceCode string,
+	authorization DeviceAuthorization,
+	ttl time.Duration,
+) error {
+	err := devices.client.HSetWithTTL(ctx, deviceKey(deviceCode), map[string]interface{}{
+		"client_id":      authorization.ClientID,
+		"scopes":         strings.Join(authorization.Scopes, " "),
+		"user_code":      authorization.UserCode,
+		"status":         authorization.Status,
+		"user_id":        authorization.UserID,
+		"interval":       authorization.Interval,
+		"last_polled_at": authorization.LastPolledAt,
+		"expires_at":     authorization.ExpiresAt,
+	}, ttl)
+	if err != nil {
+		return err
+	}
+
+	return devices.client.Set(ctx, userCodeKey(authorization.UserCode), deviceCode, ttl)
+}
+
+func (devices *deviceAuthorizationsRepository) Get(ctx context.Context, deviceCode string) (*DeviceAuthorization, error) {
+	fields, err := devices.client.HGetAll(ctx, deviceKey(deviceCode))
+	if err != nil {
+		return nil, err
+	}
+	return parseDeviceAuthorization(fields)
+}
+
+func (devices *deviceAuthorizationsRepository) GetDeviceCode(ctx context.Context, userCode string) (string, error) {
+	res, err := devices.client.Get(ctx, userCodeKey(userCode))
+	if err != nil {
+		return "", err
+	}
+
+	deviceCode, ok := res.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid device code data")
+	}
+	return deviceCode, nil
+}
+
+func (devices *deviceAuthorizationsRepository) Decide(ctx context.Context, deviceCode, status, userID string) (bool, error) {
+	res, err := devices.client.Run(ctx, decideScript, []string{deviceKey(deviceCode)}, StatusPending, status, userID)
+	if err != nil {
+		return false, err
+	}
+	return res == int64(1), nil
+}
+
+func (devices *deviceAuthorizationsRepository) RecordPoll(ctx context.Context, deviceCode string, lastPolledAt int64, interval int) error {
+	_, err := devices.client.Run(ctx, recordPollScript, []string{deviceKey(deviceCode)}, lastPolledAt, interval)
+	return err
+}
+
+func (devices *deviceAuthorizationsRepository) Redeem(ctx context.Context, deviceCode, clientID string) (*DeviceAuthorization, error) {
+	res, err := devices.client.Run(ctx, redeemScript, []string{deviceKey(deviceCode)}, StatusPending, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values)%2 != 0 {
+		return nil, errors.New("invalid device authorization data")
+	} else if len(values) == 0 {
+		return nil, redis.Nil
+	}
+
+	fields := make(map[string]string, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, _ := values[i].(string)
+		value, _ := values[i+1].(string)
+		fields[key] = value
+	}
+
+	authorization, err := parseDeviceAuthorization(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user code no longer leads anywhere once the authorization is redeemed
+	if authorization.Status != StatusPending && authorization.ClientID == clientID {
+		if _, err := devices.client.Delete(ctx, userCodeKey(authorization.UserCode)); err != nil {
+			return nil, err
+		}
+	}
+
+	return authorization, nil
+}
+
+func parseDeviceAuthorization(fields map[string]string) (*DeviceAuthorization, error) {
+	interval, err := strconv.Atoi(fields["interval"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid device authorization interval: %w", err)
+	}
+
+	lastPolledAt, err := strconv.ParseInt(fields["last_polled_at"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device authorization poll time: %w", err)
+	}
+
+	expiresAt, err := strconv.ParseInt(fields["expires_at"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device authorization expiry: %w", err)
+	}
+
+	return &DeviceAuthorization{
+		ClientID:     fields["client_id"],
+		Scopes:       strings.Fields(fields["scopes"]),
+		UserCode:     fields["user_code"],
+		Status:       fields["status"],
+		UserID:       fields["user_id"],
+		Interval:     interval,
+		LastPolledAt: lastPolledAt,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func deviceKey(deviceCode string) string {
+	return fmt.Sprintf("device_authorizations:%s", deviceCode)
+}
+
+func userCodeKey(userCode string) string {
+	return fmt.Sprintf("device_authorizations:user_codes:%s", userCode)
+}
+
+func NewDeviceAuthorizationsRepository(client *redis.Client) DeviceAuthorizationsRepository {
+	return &deviceAuthorizationsRepository{client}
+}
@@ -0,0 +1,12 @@
+package dpop_proofs
+
+import (
+	"context"
+	"time"
+)
+
+// DPoPProofsRepository is the replay cache of DPoP proofs, see dpop.ReplayCache.
+type DPoPProofsRepository interface {
+	// Add records the proof id, it returns false if the proof id was already recorded.
+	Add(ctx context.Context, id string, ttl time.Duration) (bool, error)
+}
@@ -0,0 +1,21 @@
+package dpop_proofs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+)
+
+type dpopProofsRepository struct {
+	client *redis.Client
+}
+
+func (proofs *dpopProofsRepository) Add(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	return proofs.client.SetNX(ctx, fmt.Sprintf("dpop_proofs:%s", id), 1, ttl)
+}
+
+func NewDPoPProofsRepository(client *redis.Client) DPoPProofsRepository {
+	return &dpopProofsRepository{client}
+}
@@ -0,0 +1,22 @@
+package email_verifications
+
+import (
+	"context"
+	"time"
+)
+
+type EmailVerificationsRepository interface {
+	Add(ctx context.Context, token string, verification EmailVerification, ttl time.Duration) error
+	Get(ctx context.Context, token string) (*EmailVerification, error)
+	Delete(ctx context.Context, token string) error
+
+	// GetByUserID returns the most recent pending verification sent to the user.
+	GetByUserID(ctx context.Context, userID string) (*EmailVerification, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+type EmailVerification struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"expires_at"`
+}
@@ -0,0 +1,94 @@
+package email_verifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+)
+
+type emailVerificationsRepository struct {
+	client *redis.Client
+}
+
+func (verifications *emailVerificationsRepository) Add(ctx context.Context, token string, verification EmailVerification, ttl time.Duration) error {
+	data, err := json.Marshal(verification)
+	if err != nil {
+		return err
+	}
+
+	err = verifications.client.Set(ctx, fmt.Sprintf("email_verifications:%s", token), data, ttl)
+	if err != nil {
+		return err
+	}
+
+	// Index the token by user so it can be found for data exports and deletions
+	return verifications.client.Set(ctx, fmt.Sprintf("email_verifications:users:%s", verification.UserID), token, ttl)
+}
+
+func (verifications *emailVerificationsRepository) Get(ctx context.Context, token string) (*EmailVerification, error) {
+	res, err := verifications.client.Get(ctx, fmt.Sprintf("email_verifications:%s", token))
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := res.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid email verification data")
+	}
+
+	verification := &EmailVerification{}
+	if err := json.Unmarshal([]byte(data), verification); err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+func (verifications *emailVerificationsRepository) Delete(ctx context.Context, token string) error {
+	_, err := verifications.client.Delete(ctx, fmt.Sprintf("email_verifications:%s", token))
+	return err
+}
+
+func (verifications *emailVerificationsRepository) GetByUserID(ctx context.Context, userID string) (*EmailVerification, error) {
+	token, err := verifications.getTokenByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return verifications.Get(ctx, token)
+}
+
+func (verifications *emailVerificationsRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	token, err := verifications.getTokenByUserID(ctx, userID)
+	if errors.Is(err, redis.Nil) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := verifications.Delete(ctx, token); err != nil {
+		return err
+	}
+
+	_, err = verifications.client.Delete(ctx, fmt.Sprintf("email_verifications:users:%s", userID))
+	return err
+}
+
+func (verifications *emailVerificationsRepository) getTokenByUserID(ctx context.Context, userID string) (string, error) {
+	res, err := verifications.client.Get(ctx, fmt.Sprintf("email_verifications:users:%s", userID))
+	if err != nil {
+		return "", err
+	}
+
+	token, ok := res.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid email verification token")
+	}
+	return token, nil
+}
+
+func NewEmailVerificationsRepository(client *redis.Client) EmailVerificationsRepository {
+	return &emailVerificationsRepository{client}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS service.sessions;
\ No newline at end of file
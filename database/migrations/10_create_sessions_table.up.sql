@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS service.sessions (
+    id VARCHAR(64) NOT NULL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES service.users (id) ON DELETE CASCADE,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    device_label VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_index ON service.sessions (user_id);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE service.users DROP COLUMN IF EXISTS email_verified;
\ No newline at end of file
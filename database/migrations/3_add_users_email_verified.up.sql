@@ -0,0 +1 @@
+ALTER TABLE service.users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
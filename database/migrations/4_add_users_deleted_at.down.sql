@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS service.users_deleted_at_index;
+
+ALTER TABLE service.users DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
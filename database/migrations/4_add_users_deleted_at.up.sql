@@ -0,0 +1,3 @@
+ALTER TABLE service.users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_index ON service.users (deleted_at) WHERE deleted_at IS NOT NULL;
\ No newline at end of file
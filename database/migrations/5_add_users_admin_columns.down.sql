@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS service.users_email_id_index;
+DROP INDEX IF EXISTS service.users_name_id_index;
+DROP INDEX IF EXISTS service.users_created_at_id_index;
+
+ALTER TABLE service.users DROP COLUMN IF EXISTS created_at;
+ALTER TABLE service.users DROP COLUMN IF EXISTS password_reset_required;
+ALTER TABLE service.users DROP COLUMN IF EXISTS status;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE service.users ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'active';
+ALTER TABLE service.users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE service.users ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS users_created_at_id_index ON service.users (created_at, id);
+CREATE INDEX IF NOT EXISTS users_name_id_index ON service.users (name, id);
+CREATE INDEX IF NOT EXISTS users_email_id_index ON service.users (email, id);
\ No newline at end of file
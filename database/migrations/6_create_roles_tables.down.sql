@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS service.user_roles;
+DROP TABLE IF EXISTS service.role_permissions;
+DROP TABLE IF EXISTS service.permissions;
+DROP TABLE IF EXISTS service.roles;
\ No newline at end of file
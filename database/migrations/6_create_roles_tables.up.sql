@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS service.roles (
+    name VARCHAR(64) NOT NULL PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS service.permissions (
+    name VARCHAR(128) NOT NULL PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS service.role_permissions (
+    role_name VARCHAR(64) NOT NULL REFERENCES service.roles (name) ON DELETE CASCADE,
+    permission_name VARCHAR(128) NOT NULL REFERENCES service.permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role_name, permission_name)
+);
+
+CREATE TABLE IF NOT EXISTS service.user_roles (
+    user_id UUID NOT NULL REFERENCES service.users (id) ON DELETE CASCADE,
+    role_name VARCHAR(64) NOT NULL REFERENCES service.roles (name) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_name)
+);
+
+CREATE INDEX IF NOT EXISTS user_roles_role_name_index ON service.user_roles (role_name);
+
+INSERT INTO service.permissions (name, description) VALUES
+    ('users:read', 'View users'),
+    ('users:manage', 'Suspend, reset and delete users'),
+    ('roles:manage', 'Manage roles, permissions and their assignment')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO service.roles (name, description) VALUES ('admin', 'Full administrative access') ON CONFLICT DO NOTHING;
+
+INSERT INTO service.role_permissions (role_name, permission_name)
+    SELECT 'admin', name FROM service.permissions
+ON CONFLICT DO NOTHING;
\ No newline at end of file
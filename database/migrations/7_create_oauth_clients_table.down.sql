@@ -0,0 +1,3 @@
+DELETE FROM service.permissions WHERE name = 'clients:manage';
+
+DROP TABLE IF EXISTS service.oauth_clients;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS service.oauth_clients (
+    id VARCHAR(64) NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO service.permissions (name, description) VALUES ('clients:manage', 'Manage OAuth clients') ON CONFLICT DO NOTHING;
+
+INSERT INTO service.role_permissions (role_name, permission_name) VALUES ('admin', 'clients:manage') ON CONFLICT DO NOTHING;
\ No newline at end of file
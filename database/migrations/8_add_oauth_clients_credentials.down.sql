@@ -0,0 +1,3 @@
+ALTER TABLE service.oauth_clients DROP COLUMN IF EXISTS audiences;
+ALTER TABLE service.oauth_clients DROP COLUMN IF EXISTS grant_types;
+ALTER TABLE service.oauth_clients DROP COLUMN IF EXISTS secret_hash;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE service.oauth_clients ADD COLUMN IF NOT EXISTS secret_hash VARCHAR(255);
+ALTER TABLE service.oauth_clients ADD COLUMN IF NOT EXISTS grant_types TEXT[] NOT NULL DEFAULT '{authorization_code}';
+ALTER TABLE service.oauth_clients ADD COLUMN IF NOT EXISTS audiences TEXT[] NOT NULL DEFAULT '{}';
\ No newline at end of file
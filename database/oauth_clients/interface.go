@@ -0,0 +1,28 @@
+package oauth_clients
+
+import (
+	"context"
+	"time"
+)
+
+type OAuthClientsRepository interface {
+	Create(ctx context.Context, client Client) error
+	Delete(ctx context.Context, id string) error
+
+	GetOneById(ctx context.Context, id string) (*Client, error)
+	GetAll(ctx context.Context) ([]Client, error)
+}
+
+type Client struct {
+	ID   string
+	Name string
+
+	// SecretHash is nil for public clients which can't keep a secret
+	SecretHash *string
+
+	GrantTypes   []string
+	RedirectURIs []string
+	Scopes       []string
+	Audiences    []string
+	CreatedAt    time.Time
+}
@@ -0,0 +1,94 @@
+package oauth_clients
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const clientColumns = `id, name, secret_hash, grant_types, redirect_uris, scopes, audiences, created_at`
+
+type oauthClientsRepository struct {
+	conn *pgxpool.Pool
+}
+
+func (clients *oauthClientsRepository) Create(ctx context.Context, client Client) error {
+	res, err := clients.conn.Exec(
+		ctx,
+		`INSERT INTO service.oauth_clients (id, name, secret_hash, grant_types, redirect_uris, scopes, audiences)
+		VALUES($1, $2, $3, $4, $5, $6, $7);`,
+		client.ID, client.Name, client.SecretHash, client.GrantTypes, client.RedirectURIs, client.Scopes, client.Audiences,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to insert new oauth client")
+	}
+
+	return nil
+}
+
+func (clients *oauthClientsRepository) Delete(ctx context.Context, id string) error {
+	res, err := clients.conn.Exec(ctx, `DELETE FROM service.oauth_clients WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to delete oauth client")
+	}
+
+	return nil
+}
+
+func (clients *oauthClientsRepository) GetOneById(ctx context.Context, id string) (*Client, error) {
+	return scanClient(clients.conn.QueryRow(
+		ctx,
+		`SELECT `+clientColumns+` FROM service.oauth_clients WHERE id = $1 LIMIT 1`,
+		id,
+	))
+}
+
+func (clients *oauthClientsRepository) GetAll(ctx context.Context) ([]Client, error) {
+	rows, err := clients.conn.Query(ctx, `SELECT `+clientColumns+` FROM service.oauth_clients ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Client{}
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *client)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanClient(row pgx.Row) (*Client, error) {
+	client := &Client{}
+	err := row.Scan(
+		&client.ID,
+		&client.Name,
+		&client.SecretHash,
+		&client.GrantTypes,
+		&client.RedirectURIs,
+		&client.Scopes,
+		&client.Audiences,
+		&client.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func NewOAuthClientsRepository(conn *pgxpool.Pool) OAuthClientsRepository {
+	return &oauthClientsRepository{conn}
+}
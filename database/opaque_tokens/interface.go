@@ -0,0 +1,15 @@
+package opaque_tokens
+
+import (
+	"context"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+)
+
+// OpaqueTokensRepository keeps the claims of opaque access tokens, see tokens.Store.
+type OpaqueTokensRepository interface {
+	Add(ctx context.Context, token string, claims tokens.Claims, ttl time.Duration) error
+	Get(ctx context.Context, token string) (*tokens.Claims, error)
+	Delete(ctx context.Context, token string) error
+}
@@ -0,0 +1,58 @@
+package opaque_tokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+)
+
+type opaqueTokensRepository struct {
+	client *redis.Client
+}
+
+func (repository *opaqueTokensRepository) Add(ctx context.Context, token string, claims tokens.Claims, ttl time.Duration) error {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+	return repository.client.Set(ctx, tokenKey(token), data, ttl)
+}
+
+func (repository *opaqueTokensRepository) Get(ctx context.Context, token string) (*tokens.Claims, error) {
+	res, err := repository.client.Get(ctx, tokenKey(token))
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := res.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid opaque token data")
+	}
+
+	claims := &tokens.Claims{}
+	if err := json.Unmarshal([]byte(data), claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (repository *opaqueTokensRepository) Delete(ctx context.Context, token string) error {
+	_, err := repository.client.Delete(ctx, tokenKey(token))
+	return err
+}
+
+// tokenKey stores tokens by their hash, so reading redis doesn't reveal usable tokens.
+func tokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("opaque_tokens:%s", hex.EncodeToString(hash[:]))
+}
+
+func NewOpaqueTokensRepository(client *redis.Client) OpaqueTokensRepository {
+	return &opaqueTokensRepository{client}
+}
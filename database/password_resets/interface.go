@@ -0,0 +1,21 @@
+package password_resets
+
+import (
+	"context"
+	"time"
+)
+
+type PasswordResetsRepository interface {
+	Add(ctx context.Context, token string, reset PasswordReset, ttl time.Duration) error
+	Get(ctx context.Context, token string) (*PasswordReset, error)
+	Delete(ctx context.Context, token string) error
+
+	// GetByUserID returns the most recent pending reset issued to the user.
+	GetByUserID(ctx context.Context, userID string) (*PasswordReset, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+type PasswordReset struct {
+	UserID    string `json:"user_id"`
+	ExpiresAt int64  `json:"expires_at"`
+}
@@ -0,0 +1,94 @@
+package password_resets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+)
+
+type passwordResetsRepository struct {
+	client *redis.Client
+}
+
+func (resets *passwordResetsRepository) Add(ctx context.Context, token string, reset PasswordReset, ttl time.Duration) error {
+	data, err := json.Marshal(reset)
+	if err != nil {
+		return err
+	}
+
+	err = resets.client.Set(ctx, fmt.Sprintf("password_resets:%s", token), data, ttl)
+	if err != nil {
+		return err
+	}
+
+	// Index the token by user so it can be found for data exports and deletions
+	return resets.client.Set(ctx, fmt.Sprintf("password_resets:users:%s", reset.UserID), token, ttl)
+}
+
+func (resets *passwordResetsRepository) Get(ctx context.Context, token string) (*PasswordReset, error) {
+	res, err := resets.client.Get(ctx, fmt.Sprintf("password_resets:%s", token))
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := res.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid password reset data")
+	}
+
+	reset := &PasswordReset{}
+	if err := json.Unmarshal([]byte(data), reset); err != nil {
+		return nil, err
+	}
+	return reset, nil
+}
+
+func (resets *passwordResetsRepository) Delete(ctx context.Context, token string) error {
+	_, err := resets.client.Delete(ctx, fmt.Sprintf("password_resets:%s", token))
+	return err
+}
+
+func (resets *passwordResetsRepository) GetByUserID(ctx context.Context, userID string) (*PasswordReset, error) {
+	token, err := resets.getTokenByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return resets.Get(ctx, token)
+}
+
+func (resets *passwordResetsRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	token, err := resets.getTokenByUserID(ctx, userID)
+	if errors.Is(err, redis.Nil) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := resets.Delete(ctx, token); err != nil {
+		return err
+	}
+
+	_, err = resets.client.Delete(ctx, fmt.Sprintf("password_resets:users:%s", userID))
+	return err
+}
+
+func (resets *passwordResetsRepository) getTokenByUserID(ctx context.Context, userID string) (string, error) {
+	res, err := resets.client.Get(ctx, fmt.Sprintf("password_resets:users:%s", userID))
+	if err != nil {
+		return "", err
+	}
+
+	token, ok := res.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid password reset token")
+	}
+	return token, nil
+}
+
+func NewPasswordResetsRepository(client *redis.Client) PasswordResetsRepository {
+	return &passwordResetsRepository{client}
+}
@@ -0,0 +1,48 @@
+package roles
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleAdmin = "admin"
+
+	PermissionUsersRead     = "users:read"
+	PermissionUsersManage   = "users:manage"
+	PermissionRolesManage   = "roles:manage"
+	PermissionClientsManage = "clients:manage"
+)
+
+type RolesRepository interface {
+	Create(ctx context.Context, role Role) error
+	Delete(ctx context.Context, name string) error
+	SetPermissions(ctx context.Context, name string, permissions []string) error
+
+	GetOneByName(ctx context.Context, name string) (*Role, error)
+	GetAll(ctx context.Context) ([]Role, error)
+
+	CreatePermission(ctx context.Context, permission Permission) error
+	GetAllPermissions(ctx context.Context) ([]Permission, error)
+
+	AssignToUser(ctx context.Context, userID uuid.UUID, name string) error
+	RemoveFromUser(ctx context.Context, userID uuid.UUID, name string) error
+
+	// GetUserRoles returns the names of the roles assigned to each of the users.
+	GetUserRoles(ctx context.Context, userIDs ...uuid.UUID) (map[uuid.UUID][]string, error)
+	GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error)
+	// GetRoleUsers returns the IDs of the users the role is assigned to.
+	GetRoleUsers(ctx context.Context, name string) ([]uuid.UUID, error)
+}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
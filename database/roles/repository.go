@@ -0,0 +1,259 @@
+package roles
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type rolesRepository struct {
+	conn *pgxpool.Pool
+}
+
+// Create inserts the role along with its permissions in a single transaction.
+func (roles *rolesRepository) Create(ctx context.Context, role Role) error {
+	tx, err := roles.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(
+		ctx,
+		`INSERT INTO service.roles (name, description) VALUES($1, $2);`,
+		role.Name, role.Description,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to insert new role")
+	}
+
+	if err := setPermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (roles *rolesRepository) Delete(ctx context.Context, name string) error {
+	res, err := roles.conn.Exec(ctx, `DELETE FROM service.roles WHERE name = $1;`, name)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to delete role")
+	}
+
+	return nil
+}
+
+func (roles *rolesRepository) SetPermissions(ctx context.Context, name string, permissions []string) error {
+	tx, err := roles.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setPermissions(ctx, tx, name, permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (roles *rolesRepository) GetOneByName(ctx context.Context, name string) (*Role, error) {
+	role := &Role{}
+	err := roles.conn.QueryRow(
+		ctx,
+		`SELECT r.name, r.description, ARRAY_REMOVE(ARRAY_AGG(rp.permission_name ORDER BY rp.permission_name), NULL)
+		FROM service.roles r LEFT JOIN service.role_permissions rp ON rp.role_name = r.name
+		WHERE r.name = $1 GROUP BY r.name LIMIT 1`,
+		name,
+	).Scan(&role.Name, &role.Description, &role.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (roles *rolesRepository) GetAll(ctx context.Context) ([]Role, error) {
+	rows, err := roles.conn.Query(
+		ctx,
+		`SELECT r.name, r.description, ARRAY_REMOVE(ARRAY_AGG(rp.permission_name ORDER BY rp.permission_name), NULL)
+		FROM service.roles r LEFT JOIN service.role_permissions rp ON rp.role_name = r.name
+		GROUP BY r.name ORDER BY r.name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.Name, &role.Description, &role.Permissions); err != nil {
+			return nil, err
+		}
+		result = append(result, role)
+	}
+	return result, rows.Err()
+}
+
+func (roles *rolesRepository) CreatePermission(ctx context.Context, permission Permission) error {
+	res, err := roles.conn.Exec(
+		ctx,
+		`INSERT INTO service.permissions (name, description) VALUES($1, $2);`,
+		permission.Name, permission.Description,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to insert new permission")
+	}
+
+	return nil
+}
+
+func (roles *rolesRepository) GetAllPermissions(ctx context.Context) ([]Permission, error) {
+	rows, err := roles.conn.Query(ctx, `SELECT name, description FROM service.permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Permission{}
+	for rows.Next() {
+		var permission Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, err
+		}
+		result = append(result, permission)
+	}
+	return result, rows.Err()
+}
+
+func (roles *rolesRepository) AssignToUser(ctx context.Context, userID uuid.UUID, name string) error {
+	_, err := roles.conn.Exec(
+		ctx,
+		`INSERT INTO service.user_roles (user_id, role_name) VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+		userID.String(), name,
+	)
+	return err
+}
+
+func (roles *rolesRepository) RemoveFromUser(ctx context.Context, userID uuid.UUID, name string) error {
+	_, err := roles.conn.Exec(
+		ctx,
+		`DELETE FROM service.user_roles WHERE user_id = $1 AND role_name = $2;`,
+		userID.String(), name,
+	)
+	return err
+}
+
+func (roles *rolesRepository) GetUserRoles(ctx context.Context, userIDs ...uuid.UUID) (map[uuid.UUID][]string, error) {
+	ids := make([]string, 0, len(userIDs))
+	result := make(map[uuid.UUID][]string, len(userIDs))
+	for _, id := range userIDs {
+		ids = append(ids, id.String())
+		result[id] = []string{}
+	}
+
+	rows, err := roles.conn.Query(
+		ctx,
+		`SELECT user_id, role_name FROM service.user_roles WHERE user_id = ANY($1::uuid[]) ORDER BY role_name`,
+		ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, role string
+		if err := rows.Scan(&userID, &role); err != nil {
+			return nil, err
+		}
+
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, err
+		}
+		result[id] = append(result[id], role)
+	}
+	return result, rows.Err()
+}
+
+func (roles *rolesRepository) GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := roles.conn.Query(
+		ctx,
+		`SELECT DISTINCT rp.permission_name FROM service.user_roles ur
+		JOIN service.role_permissions rp ON rp.role_name = ur.role_name
+		WHERE ur.user_id = $1 ORDER BY rp.permission_name`,
+		userID.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		result = append(result, permission)
+	}
+	return result, rows.Err()
+}
+
+func (roles *rolesRepository) GetRoleUsers(ctx context.Context, name string) ([]uuid.UUID, error) {
+	rows, err := roles.conn.Query(ctx, `SELECT user_id FROM service.user_roles WHERE role_name = $1`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []uuid.UUID{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, id)
+	}
+	return result, rows.Err()
+}
+
+// setPermissions replaces the role's permissions within the transaction.
+func setPermissions(ctx context.Context, tx pgx.Tx, name string, permissions []string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM service.role_permissions WHERE role_name = $1;`, name)
+	if err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO service.role_permissions (role_name, permission_name) VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			name, permission,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func NewRolesRepository(conn *pgxpool.Pool) RolesRepository {
+	return &rolesRepository{conn}
+}
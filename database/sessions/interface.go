@@ -0,0 +1,34 @@
+package sessions
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SessionsRepository interface {
+	Create(ctx context.Context, session Session) error
+	// Touch records that the session was last used at the given time.
+	Touch(ctx context.Context, id string, lastSeenAt time.Time) error
+	// Delete returns pgx.ErrNoRows when the user has no such session.
+	Delete(ctx context.Context, userID uuid.UUID, id string) error
+	DeleteAllByUserId(ctx context.Context, userID uuid.UUID) error
+	PurgeExpired(ctx context.Context, expiredBefore time.Time) (int64, error)
+
+	GetOne(ctx context.Context, id string) (*Session, error)
+	// GetAllByUserId returns the user's sessions that haven't expired.
+	GetAllByUserId(ctx context.Context, userID uuid.UUID) ([]Session, error)
+}
+
+// Session is an access token issued to a user, its ID is the token's jti.
+type Session struct {
+	ID          string
+	UserID      uuid.UUID
+	IPAddress   string
+	UserAgent   string
+	DeviceLabel string
+	CreatedAt   time.Time
+	LastSeenAt  time.Time
+	ExpiresAt   time.Time
+}
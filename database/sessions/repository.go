@@ -0,0 +1,132 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const sessionColumns = `id, user_id, ip_address, user_agent, device_label, created_at, last_seen_at, expires_at`
+
+type sessionsRepository struct {
+	conn *pgxpool.Pool
+}
+
+func (sessions *sessionsRepository) Create(ctx context.Context, session Session) error {
+	res, err := sessions.conn.Exec(
+		ctx,
+		`INSERT INTO service.sessions (`+sessionColumns+`) VALUES($1, $2, $3, $4, $5, $6, $7, $8);`,
+		session.ID,
+		session.UserID.String(),
+		session.IPAddress,
+		session.UserAgent,
+		session.DeviceLabel,
+		session.CreatedAt,
+		session.LastSeenAt,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to insert new session")
+	}
+
+	return nil
+}
+
+func (sessions *sessionsRepository) Touch(ctx context.Context, id string, lastSeenAt time.Time) error {
+	_, err := sessions.conn.Exec(
+		ctx,
+		`UPDATE service.sessions SET last_seen_at = $2 WHERE id = $1;`,
+		id, lastSeenAt,
+	)
+	return err
+}
+
+func (sessions *sessionsRepository) Delete(ctx context.Context, userID uuid.UUID, id string) error {
+	res, err := sessions.conn.Exec(
+		ctx,
+		`DELETE FROM service.sessions WHERE user_id = $1 AND id = $2;`,
+		userID.String(), id,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+func (sessions *sessionsRepository) DeleteAllByUserId(ctx context.Context, userID uuid.UUID) error {
+	_, err := sessions.conn.Exec(ctx, `DELETE FROM service.sessions WHERE user_id = $1;`, userID.String())
+	return err
+}
+
+func (sessions *sessionsRepository) PurgeExpired(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	res, err := sessions.conn.Exec(ctx, `DELETE FROM service.sessions WHERE expires_at < $1;`, expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+func (sessions *sessionsRepository) GetOne(ctx context.Context, id string) (*Session, error) {
+	return scanSession(sessions.conn.QueryRow(
+		ctx,
+		`SELECT `+sessionColumns+` FROM service.sessions WHERE id = $1 LIMIT 1`,
+		id,
+	))
+}
+
+func (sessions *sessionsRepository) GetAllByUserId(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	rows, err := sessions.conn.Query(
+		ctx,
+		`SELECT `+sessionColumns+` FROM service.sessions WHERE user_id = $1 AND expires_at > NOW() ORDER BY last_seen_at DESC`,
+		userID.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanSession(row pgx.Row) (*Session, error) {
+	session := &Session{}
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.IPAddress,
+		&session.UserAgent,
+		&session.DeviceLabel,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func NewSessionsRepository(conn *pgxpool.Pool) SessionsRepository {
+	return &sessionsRepository{conn}
+}
@@ -0,0 +1,26 @@
+package social_logins
+
+import (
+	"context"
+	"time"
+)
+
+type SocialLoginsRepository interface {
+	Add(ctx context.Context, state string, login SocialLogin, ttl time.Duration) error
+
+	// Take returns the login started with the state and deletes it,
+	// so a callback can be handled at most once.
+	Take(ctx context.Context, state string) (*SocialLogin, error)
+}
+
+// SocialLogin is a sign in with an external identity provider waiting for its callback.
+type SocialLogin struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+
+	// LinkUserID is set when a signed in user is linking the provider to their account
+	LinkUserID string `json:"link_user_id,omitempty"`
+
+	ExpiresAt int64 `json:"expires_at"`
+}
@@ -0,0 +1,44 @@
+package social_logins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+)
+
+type socialLoginsRepository struct {
+	client *redis.Client
+}
+
+func (logins *socialLoginsRepository) Add(ctx context.Context, state string, login SocialLogin, ttl time.Duration) error {
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	return logins.client.Set(ctx, fmt.Sprintf("social_logins:%s", state), data, ttl)
+}
+
+func (logins *socialLoginsRepository) Take(ctx context.Context, state string) (*SocialLogin, error) {
+	res, err := logins.client.GetDel(ctx, fmt.Sprintf("social_logins:%s", state))
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := res.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid social login data")
+	}
+
+	login := &SocialLogin{}
+	if err := json.Unmarshal([]byte(data), login); err != nil {
+		return nil, err
+	}
+	return login, nil
+}
+
+func NewSocialLoginsRepository(client *redis.Client) SocialLoginsRepository {
+	return &socialLoginsRepository{client}
+}
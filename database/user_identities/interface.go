@@ -0,0 +1,25 @@
+package user_identities
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserIdentitiesRepository interface {
+	Create(ctx context.Context, identity Identity) error
+	Delete(ctx context.Context, userID uuid.UUID, provider string) error
+
+	GetOne(ctx context.Context, provider, subject string) (*Identity, error)
+	GetAllByUserId(ctx context.Context, userID uuid.UUID) ([]Identity, error)
+}
+
+// Identity links a user to their account with an external identity provider.
+type Identity struct {
+	Provider  string
+	Subject   string
+	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+}
@@ -0,0 +1,99 @@
+package user_identities
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const identityColumns = `provider, subject, user_id, email, created_at`
+
+type userIdentitiesRepository struct {
+	conn *pgxpool.Pool
+}
+
+func (identities *userIdentitiesRepository) Create(ctx context.Context, identity Identity) error {
+	res, err := identities.conn.Exec(
+		ctx,
+		`INSERT INTO service.user_identities (provider, subject, user_id, email) VALUES($1, $2, $3, $4);`,
+		identity.Provider, identity.Subject, identity.UserID.String(), identity.Email,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to insert new user identity")
+	}
+
+	return nil
+}
+
+func (identities *userIdentitiesRepository) Delete(ctx context.Context, userID uuid.UUID, provider string) error {
+	res, err := identities.conn.Exec(
+		ctx,
+		`DELETE FROM service.user_identities WHERE user_id = $1 AND provider = $2;`,
+		userID.String(), provider,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to delete user identity")
+	}
+
+	return nil
+}
+
+func (identities *userIdentitiesRepository) GetOne(ctx context.Context, provider, subject string) (*Identity, error) {
+	return scanIdentity(identities.conn.QueryRow(
+		ctx,
+		`SELECT `+identityColumns+` FROM service.user_identities WHERE provider = $1 AND subject = $2 LIMIT 1`,
+		provider, subject,
+	))
+}
+
+func (identities *userIdentitiesRepository) GetAllByUserId(ctx context.Context, userID uuid.UUID) ([]Identity, error) {
+	rows, err := identities.conn.Query(
+		ctx,
+		`SELECT `+identityColumns+` FROM service.user_identities WHERE user_id = $1 ORDER BY created_at, provider`,
+		userID.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Identity{}
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func scanIdentity(row pgx.Row) (*Identity, error) {
+	identity := &Identity{}
+	err := row.Scan(
+		&identity.Provider,
+		&identity.Subject,
+		&identity.UserID,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func NewUserIdentitiesRepository(conn *pgxpool.Pool) UserIdentitiesRepository {
+	return &userIdentitiesRepository{conn}
+}
@@ -0,0 +1,152 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/the-code-genin/simple-jwt-api-go/common/cache"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/redis"
+	"go.uber.org/zap"
+)
+
+const (
+	// InvalidationsChannel carries the IDs of the users changed on any instance
+	InvalidationsChannel = "users:invalidations"
+
+	// invalidateAll is published when users are purged
+	invalidateAll = "*"
+)
+
+// CacheStats counts the hits of the users cache, see cache.Stats.Publish.
+var CacheStats = &cache.Stats{}
+
+// cachedUsersRepository keeps the users looked up by ID in process, as done when verifying every token.
+// Writes drop the user from the cache of every instance through redis pub/sub.
+type cachedUsersRepository struct {
+	repository UsersRepository
+	client     *redis.Client
+	ttl        time.Duration
+	users      *cache.LRU[User]
+}
+
+func (users *cachedUsersRepository) Create(ctx context.Context, user User) error {
+	return users.repository.Create(ctx, user)
+}
+
+func (users *cachedUsersRepository) Update(ctx context.Context, user User) error {
+	if err := users.repository.Update(ctx, user); err != nil {
+		return err
+	}
+	return users.invalidate(ctx, user.ID.String())
+}
+
+func (users *cachedUsersRepository) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	if err := users.repository.UpdatePassword(ctx, id, password); err != nil {
+		return err
+	}
+	return users.invalidate(ctx, id.String())
+}
+
+func (users *cachedUsersRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	if err := users.repository.UpdateStatus(ctx, id, status); err != nil {
+		return err
+	}
+	return users.invalidate(ctx, id.String())
+}
+
+func (users *cachedUsersRepository) RequirePasswordReset(ctx context.Context, id uuid.UUID) error {
+	if err := users.repository.RequirePasswordReset(ctx, id); err != nil {
+		return err
+	}
+	return users.invalidate(ctx, id.String())
+}
+
+func (users *cachedUsersRepository) SoftDelete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error {
+	if err := users.repository.SoftDelete(ctx, id, deletedAt); err != nil {
+		return err
+	}
+	return users.invalidate(ctx, id.String())
+}
+
+func (users *cachedUsersRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	count, err := users.repository.PurgeDeleted(ctx, deletedBefore)
+	if err != nil || count == 0 {
+		return count, err
+	}
+	return count, users.invalidate(ctx, invalidateAll)
+}
+
+func (users *cachedUsersRepository) GetOneById(ctx context.Context, id uuid.UUID) (*User, error) {
+	if !cache.Skipped(ctx) {
+		if user, ok := users.users.Get(id.String()); ok {
+			return &user, nil
+		}
+	}
+
+	// The user isn't cached when it is invalidated while being read
+	generation := users.users.Generation()
+	user, err := users.repository.GetOneById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers get their own copy to modify
+	users.users.SetIfCurrent(id.String(), *user, users.ttl, generation)
+	return user, nil
+}
+
+// GetOneByEmail isn't cached, users are only looked up by email outside of token verification.
+func (users *cachedUsersRepository) GetOneByEmail(ctx context.Context, email string) (*User, error) {
+	return users.repository.GetOneByEmail(ctx, email)
+}
+
+func (users *cachedUsersRepository) List(ctx context.Context, query ListQuery) ([]User, error) {
+	return users.repository.List(ctx, query)
+}
+
+// invalidate drops the user from the cache of every instance, or every user for invalidateAll.
+func (users *cachedUsersRepository) invalidate(ctx context.Context, id string) error {
+	users.drop(id)
+	return users.client.Publish(ctx, InvalidationsChannel, id)
+}
+
+func (users *cachedUsersRepository) drop(id string) {
+	if id == invalidateAll {
+		users.users.Clear()
+	} else {
+		users.users.Delete(id)
+	}
+}
+
+// listen applies the invalidations published by every instance until the context is done,
+// the cache is cleared once subscribed as invalidations may have been missed while the subscription was down.
+func (users *cachedUsersRepository) listen(ctx context.Context) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "CachedUsersRepository/listen"))
+	users.client.Listen(ctx, InvalidationsChannel, users.drop, users.users.Clear)
+}
+
+// NewCachedUsersRepository puts a bounded in-process cache in front of the repository's lookups by ID,
+// it listens for invalidations from other instances until the context is done.
+func NewCachedUsersRepository(
+	ctx context.Context,
+	repository UsersRepository,
+	client *redis.Client,
+	cfg config.CacheConfig,
+) UsersRepository {
+	if cfg.UsersSize <= 0 {
+		return repository
+	}
+
+	cached := &cachedUsersRepository{
+		repository: repository,
+		client:     client,
+		ttl:        time.Second * time.Duration(cfg.TTL),
+		users:      cache.NewLRU[User](cfg.UsersSize, CacheStats),
+	}
+	go cached.listen(ctx)
+
+	return cached
+}
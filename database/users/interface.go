@@ -2,20 +2,66 @@ package users
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+
+	// StatusDeleted is not stored, it matches soft deleted users when listing.
+	StatusDeleted = "deleted"
+
+	SortByCreatedAt = "created_at"
+	SortByName      = "name"
+	SortByEmail     = "email"
+)
+
 type UsersRepository interface {
 	Create(ctx context.Context, user User) error
+	Update(ctx context.Context, user User) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, password string) error
+	UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
+	RequirePasswordReset(ctx context.Context, id uuid.UUID) error
+
+	// SoftDelete marks the user as deleted, the record is kept until it is purged.
+	SoftDelete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
 
 	GetOneById(ctx context.Context, id uuid.UUID) (*User, error)
 	GetOneByEmail(ctx context.Context, email string) (*User, error)
+	List(ctx context.Context, query ListQuery) ([]User, error)
 }
 
 type User struct {
-	ID       uuid.UUID `json:"id"`
-	Name     string    `json:"name"`
-	Email    string    `json:"email"`
-	Password string    `json:"-"`
+	ID                    uuid.UUID  `json:"id"`
+	Name                  string     `json:"name"`
+	Email                 string     `json:"email"`
+	EmailVerified         bool       `json:"email_verified"`
+	Password              string     `json:"-"`
+	Status                string     `json:"status"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+	DeletedAt             *time.Time `json:"deleted_at"`
+}
+
+// ListQuery filters and orders users for keyset pagination.
+type ListQuery struct {
+	Email  string
+	Name   string
+	Status string
+
+	SortBy     string
+	Descending bool
+
+	// After is the sort value and id of the last user on the previous page.
+	After *ListCursor
+	Limit int
+}
+
+type ListCursor struct {
+	Value string
+	ID    uuid.UUID
 }
@@ -5,13 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const userColumns = `id, name, email, email_verified, password, status, password_reset_required, created_at, deleted_at`
+
 type usersRepository struct {
-	conn *pgx.Conn
+	conn *pgxpool.Pool
 }
 
 func (users *usersRepository) Create(ctx context.Context, user User) error {
@@ -22,8 +26,8 @@ func (users *usersRepository) Create(ctx context.Context, user User) error {
 
 	res, err := users.conn.Exec(
 		ctx,
-		`INSERT INTO service.users (id, name, email, password) VALUES($1, $2, $3, $4);`,
-		id, user.Name, user.Email, user.Password,
+		`INSERT INTO service.users (id, name, email, email_verified, password, status) VALUES($1, $2, $3, $4, $5, $6);`,
+		id, user.Name, user.Email, user.EmailVerified, user.Password, user.Status,
 	)
 	if err != nil {
 		return err
@@ -34,28 +38,207 @@ func (users *usersRepository) Create(ctx context.Context, user User) error {
 	return nil
 }
 
+func (users *usersRepository) Update(ctx context.Context, user User) error {
+	res, err := users.conn.Exec(
+		ctx,
+		`UPDATE service.users SET name = $2, email = $3, email_verified = $4 WHERE id = $1;`,
+		user.ID.String(), user.Name, user.Email, user.EmailVerified,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to update user")
+	}
+
+	return nil
+}
+
+func (users *usersRepository) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	res, err := users.conn.Exec(
+		ctx,
+		`UPDATE service.users SET password = $2, password_reset_required = FALSE WHERE id = $1;`,
+		id.String(), password,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to update user password")
+	}
+
+	return nil
+}
+
+func (users *usersRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	res, err := users.conn.Exec(
+		ctx,
+		`UPDATE service.users SET status = $2 WHERE id = $1;`,
+		id.String(), status,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to update user status")
+	}
+
+	return nil
+}
+
+func (users *usersRepository) RequirePasswordReset(ctx context.Context, id uuid.UUID) error {
+	res, err := users.conn.Exec(
+		ctx,
+		`UPDATE service.users SET password_reset_required = TRUE WHERE id = $1;`,
+		id.String(),
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to require user password reset")
+	}
+
+	return nil
+}
+
+func (users *usersRepository) SoftDelete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error {
+	res, err := users.conn.Exec(
+		ctx,
+		`UPDATE service.users SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL;`,
+		id.String(), deletedAt,
+	)
+	if err != nil {
+		return err
+	} else if res.RowsAffected() != 1 {
+		return errors.New("unable to delete user")
+	}
+
+	return nil
+}
+
+func (users *usersRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := users.conn.Exec(
+		ctx,
+		`DELETE FROM service.users WHERE deleted_at IS NOT NULL AND deleted_at < $1;`,
+		deletedBefore,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
+
 func (users *usersRepository) GetOneById(ctx context.Context, id uuid.UUID) (*User, error) {
-	user := &User{ID: id}
-	err := users.conn.QueryRow(
+	return scanUser(users.conn.QueryRow(
 		ctx,
-		`SELECT name, email, password FROM service.users WHERE id = $1 LIMIT 1`,
+		`SELECT `+userColumns+` FROM service.users WHERE id = $1 LIMIT 1`,
 		id.String(),
-	).Scan(&user.Name, &user.Email, &user.Password)
+	))
+}
+
+func (users *usersRepository) GetOneByEmail(ctx context.Context, email string) (*User, error) {
+	return scanUser(users.conn.QueryRow(
+		ctx,
+		`SELECT `+userColumns+` FROM service.users WHERE LOWER(email) = LOWER($1) LIMIT 1`,
+		email,
+	))
+}
+
+func (users *usersRepository) List(ctx context.Context, query ListQuery) ([]User, error) {
+	if query.SortBy == "" {
+		query.SortBy = SortByCreatedAt
+	}
+
+	switch query.SortBy {
+	case SortByCreatedAt, SortByName, SortByEmail:
+	default:
+		return nil, fmt.Errorf("invalid sort column %q", query.SortBy)
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if query.Email != "" {
+		conditions = append(conditions, fmt.Sprintf("email ILIKE %s", addArg("%"+escapeLike(query.Email)+"%")))
+	}
+
+	if query.Name != "" {
+		conditions = append(conditions, fmt.Sprintf("name ILIKE %s", addArg("%"+escapeLike(query.Name)+"%")))
+	}
+
+	switch query.Status {
+	case "":
+	case StatusDeleted:
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	default:
+		conditions = append(conditions, fmt.Sprintf("status = %s AND deleted_at IS NULL", addArg(query.Status)))
+	}
+
+	order, operator := "ASC", ">"
+	if query.Descending {
+		order, operator = "DESC", "<"
+	}
+
+	if query.After != nil {
+		var value interface{} = query.After.Value
+		if query.SortBy == SortByCreatedAt {
+			createdAt, err := time.Parse(time.RFC3339Nano, query.After.Value)
+			if err != nil {
+				return nil, err
+			}
+			value = createdAt
+		}
+
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s, id) %s (%s, %s)",
+			query.SortBy, operator, addArg(value), addArg(query.After.ID.String()),
+		))
+	}
+
+	sql := `SELECT ` + userColumns + ` FROM service.users`
+	if len(conditions) != 0 {
+		sql += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	sql += fmt.Sprintf(` ORDER BY %s %s, id %s LIMIT %s`, query.SortBy, order, order, addArg(query.Limit))
+
+	rows, err := users.conn.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
+	defer rows.Close()
+
+	result := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-func (users *usersRepository) GetOneByEmail(ctx context.Context, email string) (*User, error) {
-	user := &User{Email: email}
+func scanUser(row pgx.Row) (*User, error) {
+	user := &User{}
 	var id string
 
-	err := users.conn.QueryRow(
-		ctx,
-		`SELECT id, name, password FROM service.users WHERE LOWER(email) = LOWER($1) LIMIT 1`,
-		email,
-	).Scan(&id, &user.Name, &user.Password)
+	err := row.Scan(
+		&id,
+		&user.Name,
+		&user.Email,
+		&user.EmailVerified,
+		&user.Password,
+		&user.Status,
+		&user.PasswordResetRequired,
+		&user.CreatedAt,
+		&user.DeletedAt,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +251,10 @@ func (users *usersRepository) GetOneByEmail(ctx context.Context, email string) (
 	return user, nil
 }
 
-func NewUsersRepository(conn *pgx.Conn) UsersRepository {
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func NewUsersRepository(conn *pgxpool.Pool) UsersRepository {
 	return &usersRepository{conn}
 }
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/admin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/oauth"
+	"github.com/the-code-genin/simple-jwt-api-go/application/roles"
+)
+
+func (c *Client) ListUsers(ctx context.Context, req admin.ListUsersDTO) (*admin.UsersPageDTO, error) {
+	query := url.Values{}
+	setIfNotEmpty(query, "email", req.Email)
+	setIfNotEmpty(query, "name", req.Name)
+	setIfNotEmpty(query, "status", req.Status)
+	setIfNotEmpty(query, "sort", req.Sort)
+	setIfNotEmpty(query, "order", req.Order)
+	setIfNotEmpty(query, "cursor", req.Cursor)
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+
+	page := &admin.UsersPageDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/admin/users", query: query, auth: true}, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (c *Client) GetUser(ctx context.Context, id string) (*admin.UserDTO, error) {
+	return c.adminUser(ctx, http.MethodGet, "/admin/users/"+url.PathEscape(id))
+}
+
+func (c *Client) SuspendUser(ctx context.Context, id string) (*admin.UserDTO, error) {
+	return c.adminUser(ctx, http.MethodPost, "/admin/users/"+url.PathEscape(id)+"/suspend")
+}
+
+func (c *Client) UnsuspendUser(ctx context.Context, id string) (*admin.UserDTO, error) {
+	return c.adminUser(ctx, http.MethodPost, "/admin/users/"+url.PathEscape(id)+"/unsuspend")
+}
+
+func (c *Client) adminUser(ctx context.Context, method, path string) (*admin.UserDTO, error) {
+	user := &admin.UserDTO{}
+	if err := c.do(ctx, request{method: method, path: path, auth: true}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *Client) ForcePasswordReset(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/admin/users/" + url.PathEscape(id) + "/force-password-reset", auth: true}, nil)
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/users/" + url.PathEscape(id), auth: true}, nil)
+}
+
+func (c *Client) ListRoles(ctx context.Context) ([]roles.RoleDTO, error) {
+	result := []roles.RoleDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/admin/roles", auth: true}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) CreateRole(ctx context.Context, req roles.CreateRoleDTO) (*roles.RoleDTO, error) {
+	role := &roles.RoleDTO{}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/admin/roles", body: req, auth: true}, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (c *Client) SetRolePermissions(ctx context.Context, name string, req roles.SetRolePermissionsDTO) (*roles.RoleDTO, error) {
+	role := &roles.RoleDTO{}
+	path := "/admin/roles/" + url.PathEscape(name) + "/permissions"
+	if err := c.do(ctx, request{method: http.MethodPut, path: path, body: req, auth: true}, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (c *Client) DeleteRole(ctx context.Context, name string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/roles/" + url.PathEscape(name), auth: true}, nil)
+}
+
+func (c *Client) ListPermissions(ctx context.Context) ([]roles.PermissionDTO, error) {
+	result := []roles.PermissionDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/admin/permissions", auth: true}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) CreatePermission(ctx context.Context, req roles.CreatePermissionDTO) (*roles.PermissionDTO, error) {
+	permission := &roles.PermissionDTO{}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/admin/permissions", body: req, auth: true}, permission); err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (c *Client) AssignUserRole(ctx context.Context, userID string, req roles.AssignUserRoleDTO) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/admin/users/" + url.PathEscape(userID) + "/roles", body: req, auth: true}, nil)
+}
+
+func (c *Client) RemoveUserRole(ctx context.Context, userID, role string) error {
+	path := "/admin/users/" + url.PathEscape(userID) + "/roles/" + url.PathEscape(role)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, auth: true}, nil)
+}
+
+func (c *Client) ListClients(ctx context.Context) ([]oauth.ClientDTO, error) {
+	clients := []oauth.ClientDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/admin/oauth/clients", auth: true}, &clients); err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// CreateClient registers an OAuth client, the secret of confidential clients is only returned here.
+func (c *Client) CreateClient(ctx context.Context, req oauth.CreateClientDTO) (*oauth.ClientDTO, error) {
+	client := &oauth.ClientDTO{}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/admin/oauth/clients", body: req, auth: true}, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *Client) DeleteClient(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/oauth/clients/" + url.PathEscape(id), auth: true}, nil)
+}
+
+// Metrics returns the variables published at /debug/vars, which is only served outside production
+// or with METRICS_ENABLED.
+func (c *Client) Metrics(ctx context.Context) (map[string]json.RawMessage, error) {
+	vars := map[string]json.RawMessage{}
+	if err := c.doRaw(ctx, request{method: http.MethodGet, path: "/debug/vars"}, &vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
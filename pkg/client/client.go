@@ -0,0 +1,226 @@
+// Package client is a typed client for the API's HTTP routes.
+//
+// Responses are unwrapped from the API's {code, data, message} envelope into the application's DTOs,
+// failures are returned as *APIError, or *oauth.Error for the OAuth endpoints. Access tokens are kept in a
+// TokenStore and, when the client is given the user's credentials, generated again once they expire or are rejected.
+//
+// Routes rendering pages for a browser, like /oauth/authorize, are covered by methods building their URLs.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type Config struct {
+	// BaseURL is where the API is served, e.g. https://auth.example.com
+	BaseURL string
+
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+
+	// TokenStore keeps the access token between requests, an in memory store when nil
+	TokenStore TokenStore
+
+	// Credentials are used to generate access tokens when there is no usable one
+	Credentials *Credentials
+}
+
+// Client calls the API, it is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	tokens     TokenStore
+
+	// refreshMu serializes generating tokens and guards credentials
+	refreshMu   sync.Mutex
+	credentials *Credentials
+}
+
+// request is an API call, the body is sent as JSON and the form as form-urlencoded.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	form   url.Values
+
+	// auth sends the stored access token
+	auth bool
+	// bearer is sent instead of the stored access token, it is never refreshed
+	bearer string
+
+	cookies []*http.Cookie
+}
+
+// envelope is the API's APIResponse.
+type envelope struct {
+	Code    int             `json:"code"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+}
+
+// do sends an API call and unwraps the response's data into out, which may be nil.
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	status, body, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	res := envelope{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		if status >= http.StatusBadRequest {
+			return &APIError{StatusCode: status, Message: http.StatusText(status)}
+		}
+		return fmt.Errorf("invalid response: %w", err)
+	}
+
+	if status >= http.StatusBadRequest {
+		return newAPIError(status, res)
+	}
+
+	if out == nil || len(res.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(res.Data, out)
+}
+
+// doRaw sends a call to an endpoint which doesn't use the API's envelope, like the OAuth endpoints.
+// Their errors are returned as *oauth.Error when the body is one.
+func (c *Client) doRaw(ctx context.Context, req request, out interface{}) error {
+	status, body, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if status >= http.StatusBadRequest {
+		return newRawError(status, body)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// send sends the request with the stored access token when it needs one,
+// a rejected token is generated again and the request retried once.
+func (c *Client) send(ctx context.Context, req request) (int, []byte, error) {
+	if !req.auth {
+		return c.roundTrip(ctx, req, req.bearer)
+	}
+
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	status, body, err := c.roundTrip(ctx, req, token)
+	if err != nil || !c.canRefresh() || !isAuthRejection(status, body) {
+		return status, body, err
+	}
+
+	fresh, err := c.refresh(ctx, token)
+	if err != nil {
+		return 0, nil, err
+	}
+	return c.roundTrip(ctx, req, fresh.AccessToken)
+}
+
+func (c *Client) roundTrip(ctx context.Context, req request, token string) (int, []byte, error) {
+	var body io.Reader
+	contentType := ""
+	if req.body != nil {
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return 0, nil, err
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	} else if req.form != nil {
+		body, contentType = strings.NewReader(req.form.Encode()), "application/x-www-form-urlencoded"
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.url(req.path, req.query), body)
+	if err != nil {
+		return 0, nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, cookie := range req.cookies {
+		httpReq.AddCookie(cookie)
+	}
+
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, data, nil
+}
+
+// url resolves the path against the base URL.
+func (c *Client) url(path string, query url.Values) string {
+	u := *c.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = ""
+	if len(query) != 0 {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// setIfNotEmpty only sets non empty values, so optional fields aren't sent.
+func setIfNotEmpty(values url.Values, key, value string) {
+	if value != "" {
+		values.Set(key, value)
+	}
+}
+
+func New(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("a base URL is required")
+	}
+
+	baseURL, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	} else if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %s", cfg.BaseURL)
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	tokens := cfg.TokenStore
+	if tokens == nil {
+		tokens = NewMemoryTokenStore()
+	}
+
+	var credentials *Credentials
+	if cfg.Credentials != nil {
+		copied := *cfg.Credentials
+		credentials = &copied
+	}
+
+	return &Client{
+		baseURL:     baseURL,
+		httpClient:  httpClient,
+		tokens:      tokens,
+		credentials: credentials,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/application/gateway"
+	"github.com/the-code-genin/simple-jwt-api-go/application/oauth"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+)
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	// Only the latest token generated is accepted
+	var issued int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate-access-token", func(w http.ResponseWriter, r *http.Request) {
+		req := users.GenerateUserAccessTokenDTO{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Password != "password" {
+			writeJSON(w, http.StatusPreconditionFailed, map[string]interface{}{"code": 412, "message": "invalid credentials"})
+			return
+		}
+
+		token := "token-" + strconv.Itoa(int(atomic.AddInt32(&issued, 1)))
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 200, "data": users.AccessTokenDTO{
+			AccessToken: token,
+			Type:        "bearer",
+			ExpiresIn:   3600,
+		}})
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-"+strconv.Itoa(int(atomic.LoadInt32(&issued))) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"code": 400, "message": "Unable to decode user access token"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 200, "data": users.UserDTO{ID: "1", Email: "user@example.com"}})
+	})
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    400,
+			"message": "password: too short",
+			"data":    users.ValidationErrors{"password": {"too short"}},
+		})
+	})
+	mux.HandleFunc("/auth/verify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer forwarded" {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"code": 401, "message": "invalid access token"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 200, "data": gateway.DecisionDTO{UserID: "2"}})
+	})
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, oauth.Error{Code: oauth.ErrorInvalidGrant, Description: "invalid code"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Tokens are generated with the credentials when needed
+	client, err := New(Config{BaseURL: server.URL, Credentials: &Credentials{Email: "user@example.com", Password: "password"}})
+	assert.Nil(t, err)
+
+	user, err := client.GetMe(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "user@example.com", user.Email)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issued))
+
+	// A rejected token is generated again
+	atomic.AddInt32(&issued, 1)
+	_, err = client.GetMe(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&issued))
+
+	// Without credentials there is nothing to authenticate with
+	anonymous, err := New(Config{BaseURL: server.URL})
+	assert.Nil(t, err)
+	_, err = anonymous.GetMe(ctx)
+	assert.ErrorIs(t, err, ErrNoToken)
+
+	_, err = anonymous.GenerateAccessToken(ctx, users.GenerateUserAccessTokenDTO{Email: "user@example.com", Password: "wrong"})
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	_, err = anonymous.Register(ctx, users.RegisterUserDTO{})
+	apiErr := &APIError{}
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, []string{"too short"}, apiErr.ValidationErrors["password"])
+
+	_, err = anonymous.Token(ctx, oauth.TokenRequestDTO{GrantType: oauth.GrantTypeAuthorizationCode})
+	oauthErr := &oauth.Error{}
+	assert.True(t, errors.As(err, &oauthErr))
+	assert.Equal(t, oauth.ErrorInvalidGrant, oauthErr.Code)
+
+	// Tokens forwarded to a service are verified instead of the stored one
+	decision, err := anonymous.VerifyToken(ctx, "forwarded")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", decision.UserID)
+	_, err = anonymous.VerifyToken(ctx, "other")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	assert.ErrorIs(t, &APIError{StatusCode: http.StatusBadRequest, Message: "invalid Authorization header"}, ErrUnauthorized)
+	assert.Equal(t, server.URL+"/auth/google/login", client.LoginURL("google"))
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/oauth"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+)
+
+// The sentinel errors are matched by *APIError with errors.Is.
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrServerError        = errors.New("server error")
+
+	// ErrNoToken is returned for authenticated routes when there is no access token and no credentials to generate one
+	ErrNoToken = errors.New("no access token")
+)
+
+// authRejections are the messages the API rejects access tokens with.
+var authRejections = map[string]bool{
+	"invalid Authorization header":       true,
+	"Unable to decode user access token": true,
+	"Invalid DPoP proof":                 true,
+}
+
+// APIError is a failed API call.
+type APIError struct {
+	StatusCode int
+	Message    string
+
+	// ValidationErrors is set when the request failed validation
+	ValidationErrors users.ValidationErrors
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		// The API rejects access tokens as bad requests
+		return e.StatusCode == http.StatusUnauthorized ||
+			(e.StatusCode == http.StatusBadRequest && authRejections[e.Message])
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+func newAPIError(status int, res envelope) *APIError {
+	err := &APIError{StatusCode: status, Message: res.Message}
+	if status == http.StatusBadRequest && len(res.Data) != 0 {
+		validationErrors := users.ValidationErrors{}
+		if json.Unmarshal(res.Data, &validationErrors) == nil && len(validationErrors) != 0 {
+			err.ValidationErrors = validationErrors
+		}
+	}
+	return err
+}
+
+// newRawError parses the error of an endpoint which doesn't use the API's envelope.
+func newRawError(status int, body []byte) error {
+	oauthErr := &oauth.Error{}
+	if json.Unmarshal(body, oauthErr) == nil && oauthErr.Code != "" {
+		return oauthErr
+	}
+
+	res := envelope{}
+	if json.Unmarshal(body, &res) == nil && res.Message != "" {
+		return newAPIError(status, res)
+	}
+	return &APIError{StatusCode: status, Message: http.StatusText(status)}
+}
+
+// isAuthRejection reports whether the API rejected the request's access token.
+func isAuthRejection(status int, body []byte) bool {
+	if status == http.StatusUnauthorized {
+		return true
+	} else if status != http.StatusBadRequest {
+		return false
+	}
+
+	res := envelope{}
+	return json.Unmarshal(body, &res) == nil && authRejections[res.Message]
+}
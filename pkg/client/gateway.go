@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/gateway"
+)
+
+// VerifyToken checks a token the way a proxy forwarding a request does, e.g. for a service receiving tokens
+// issued to its callers. The token is verified instead of the stored one, an invalid token is ErrUnauthorized.
+func (c *Client) VerifyToken(ctx context.Context, token string) (*gateway.DecisionDTO, error) {
+	decision := &gateway.DecisionDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/auth/verify", bearer: token}, decision); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
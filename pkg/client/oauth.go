@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/the-code-genin/simple-jwt-api-go/application/oauth"
+)
+
+// AuthorizeURL is where the user's browser is sent to approve an authorization code request,
+// the approval itself is submitted from the page.
+func (c *Client) AuthorizeURL(req oauth.AuthorizationRequestDTO) string {
+	query := url.Values{}
+	setIfNotEmpty(query, "response_type", req.ResponseType)
+	setIfNotEmpty(query, "client_id", req.ClientID)
+	setIfNotEmpty(query, "redirect_uri", req.RedirectURI)
+	setIfNotEmpty(query, "scope", req.Scope)
+	setIfNotEmpty(query, "state", req.State)
+	setIfNotEmpty(query, "code_challenge", req.CodeChallenge)
+	setIfNotEmpty(query, "code_challenge_method", req.CodeChallengeMethod)
+	setIfNotEmpty(query, "nonce", req.Nonce)
+	return c.url("/oauth/authorize", query)
+}
+
+// DeviceURL is where the user approves a device, the code is filled in when given.
+func (c *Client) DeviceURL(userCode string) string {
+	query := url.Values{}
+	setIfNotEmpty(query, "user_code", userCode)
+	return c.url("/oauth/device", query)
+}
+
+func (c *Client) DeviceAuthorization(ctx context.Context, req oauth.DeviceAuthorizationRequestDTO) (*oauth.DeviceAuthorizationDTO, error) {
+	form := url.Values{}
+	setIfNotEmpty(form, "client_id", req.ClientID)
+	setIfNotEmpty(form, "client_secret", req.ClientSecret)
+	setIfNotEmpty(form, "scope", req.Scope)
+
+	authorization := &oauth.DeviceAuthorizationDTO{}
+	if err := c.doRaw(ctx, request{method: http.MethodPost, path: "/oauth/device_authorization", form: form}, authorization); err != nil {
+		return nil, err
+	}
+	return authorization, nil
+}
+
+// Token requests a token from the token endpoint, it isn't stored since it may not be the user's.
+func (c *Client) Token(ctx context.Context, req oauth.TokenRequestDTO) (*oauth.TokenDTO, error) {
+	form := url.Values{}
+	setIfNotEmpty(form, "grant_type", req.GrantType)
+	setIfNotEmpty(form, "code", req.Code)
+	setIfNotEmpty(form, "redirect_uri", req.RedirectURI)
+	setIfNotEmpty(form, "client_id", req.ClientID)
+	setIfNotEmpty(form, "client_secret", req.ClientSecret)
+	setIfNotEmpty(form, "code_verifier", req.CodeVerifier)
+	setIfNotEmpty(form, "device_code", req.DeviceCode)
+	setIfNotEmpty(form, "scope", req.Scope)
+	for _, audience := range req.Audience {
+		form.Add("audience", audience)
+	}
+	setIfNotEmpty(form, "subject_token", req.SubjectToken)
+	setIfNotEmpty(form, "subject_token_type", req.SubjectTokenType)
+	setIfNotEmpty(form, "actor_token", req.ActorToken)
+	setIfNotEmpty(form, "actor_token_type", req.ActorTokenType)
+	setIfNotEmpty(form, "requested_token_type", req.RequestedTokenType)
+
+	token := &oauth.TokenDTO{}
+	if err := c.doRaw(ctx, request{method: http.MethodPost, path: "/oauth/token", form: form}, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (c *Client) Introspect(ctx context.Context, req oauth.IntrospectDTO) (*oauth.IntrospectionDTO, error) {
+	form := url.Values{}
+	setIfNotEmpty(form, "token", req.Token)
+	setIfNotEmpty(form, "client_id", req.ClientID)
+	setIfNotEmpty(form, "client_secret", req.ClientSecret)
+
+	introspection := &oauth.IntrospectionDTO{}
+	if err := c.doRaw(ctx, request{method: http.MethodPost, path: "/oauth/introspect", form: form}, introspection); err != nil {
+		return nil, err
+	}
+	return introspection, nil
+}
+
+func (c *Client) Discovery(ctx context.Context) (*oauth.DiscoveryDTO, error) {
+	discovery := &oauth.DiscoveryDTO{}
+	if err := c.doRaw(ctx, request{method: http.MethodGet, path: "/.well-known/openid-configuration"}, discovery); err != nil {
+		return nil, err
+	}
+	return discovery, nil
+}
+
+func (c *Client) JWKS(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	keySet := &jose.JSONWebKeySet{}
+	if err := c.doRaw(ctx, request{method: http.MethodGet, path: "/.well-known/jwks.json"}, keySet); err != nil {
+		return nil, err
+	}
+	return keySet, nil
+}
+
+func (c *Client) UserInfo(ctx context.Context) (*oauth.UserInfoDTO, error) {
+	info := &oauth.UserInfoDTO{}
+	if err := c.doRaw(ctx, request{method: http.MethodGet, path: "/userinfo", auth: true}, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/social"
+)
+
+func (c *Client) ListProviders(ctx context.Context) ([]string, error) {
+	providers := []string{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/auth/providers"}, &providers); err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+// LoginURL is where the user's browser is sent to log in with the provider.
+func (c *Client) LoginURL(provider string) string {
+	return c.url("/auth/"+url.PathEscape(provider)+"/login", nil)
+}
+
+// Callback completes a login with the provider, the token of a login is stored for the authenticated calls.
+// The state is checked against the social_login_state cookie set when the login started, it is sent from
+// req.StateCookie when set, otherwise it has to be kept by the HTTPClient's cookie jar.
+func (c *Client) Callback(ctx context.Context, provider string, req social.CallbackDTO) (*social.SocialLoginDTO, error) {
+	query := url.Values{}
+	setIfNotEmpty(query, "code", req.Code)
+	setIfNotEmpty(query, "state", req.State)
+	setIfNotEmpty(query, "error", req.Error)
+	setIfNotEmpty(query, "error_description", req.ErrorDescription)
+
+	var cookies []*http.Cookie
+	if req.StateCookie != "" {
+		cookies = append(cookies, &http.Cookie{Name: "social_login_state", Value: req.StateCookie})
+	}
+
+	result := &social.SocialLoginDTO{}
+	path := "/auth/" + url.PathEscape(provider) + "/callback"
+	if err := c.do(ctx, request{method: http.MethodGet, path: path, query: query, cookies: cookies}, result); err != nil {
+		return nil, err
+	}
+
+	if result.Token != nil {
+		if err := c.storeAccessToken(ctx, result.Token); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) ListIdentities(ctx context.Context) ([]social.IdentityDTO, error) {
+	identities := []social.IdentityDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/me/identities", auth: true}, &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// LinkIdentity returns where the user's browser is sent to link their account with the provider,
+// the response sets the social_login_state cookie the callback is checked against.
+func (c *Client) LinkIdentity(ctx context.Context, provider string) (*social.AuthorizationURLDTO, error) {
+	authURL := &social.AuthorizationURLDTO{}
+	path := "/me/identities/" + url.PathEscape(provider)
+	if err := c.do(ctx, request{method: http.MethodPost, path: path, auth: true}, authURL); err != nil {
+		return nil, err
+	}
+	return authURL, nil
+}
+
+func (c *Client) UnlinkIdentity(ctx context.Context, provider string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/me/identities/" + url.PathEscape(provider), auth: true}, nil)
+}
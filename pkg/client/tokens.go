@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+)
+
+// expiryDelta is how long before expiring tokens are generated again,
+// so they don't expire on the way to the API.
+const expiryDelta = 30 * time.Second
+
+// Token is a stored access token.
+type Token struct {
+	AccessToken string
+	Type        string
+	Scope       string
+
+	// ExpiresAt is zero when the expiry isn't known
+	ExpiresAt time.Time
+}
+
+func (t *Token) expired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().Add(expiryDelta).After(t.ExpiresAt)
+}
+
+// TokenStore keeps the client's access token, e.g. to share it between processes.
+type TokenStore interface {
+	// Token returns nil when no token is stored
+	Token(ctx context.Context) (*Token, error)
+	// SetToken clears the stored token when given nil
+	SetToken(ctx context.Context, token *Token) error
+}
+
+type memoryTokenStore struct {
+	mu    sync.RWMutex
+	token *Token
+}
+
+func (s *memoryTokenStore) Token(ctx context.Context) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token, nil
+}
+
+func (s *memoryTokenStore) SetToken(ctx context.Context, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+	return nil
+}
+
+func NewMemoryTokenStore() TokenStore {
+	return &memoryTokenStore{}
+}
+
+// Credentials generate the client's access tokens.
+type Credentials struct {
+	Email    string
+	Password string
+	Scope    string
+
+	// DeviceLabel names the tokens' session
+	DeviceLabel string
+}
+
+// accessToken returns the stored access token, generating one when it is missing or expired.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if token != nil && !token.expired() {
+		return token.AccessToken, nil
+	} else if !c.canRefresh() {
+		if token == nil {
+			return "", ErrNoToken
+		}
+		// Let the API decide, the clock may be off
+		return token.AccessToken, nil
+	}
+
+	stale := ""
+	if token != nil {
+		stale = token.AccessToken
+	}
+
+	token, err = c.refresh(ctx, stale)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+func (c *Client) canRefresh() bool {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.credentials != nil
+}
+
+// refresh generates a new access token to replace the stale one,
+// unless another request already replaced it.
+func (c *Client) refresh(ctx context.Context, stale string) (*Token, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	} else if token != nil && token.AccessToken != stale && !token.expired() {
+		return token, nil
+	}
+
+	if _, err := c.generateAccessToken(ctx, users.GenerateUserAccessTokenDTO{
+		Email:       c.credentials.Email,
+		Password:    c.credentials.Password,
+		Scope:       c.credentials.Scope,
+		DeviceLabel: c.credentials.DeviceLabel,
+	}); err != nil {
+		return nil, err
+	}
+	return c.tokens.Token(ctx)
+}
+
+// storeAccessToken stores a token the API issued.
+func (c *Client) storeAccessToken(ctx context.Context, accessToken *users.AccessTokenDTO) error {
+	token := &Token{
+		AccessToken: accessToken.AccessToken,
+		Type:        accessToken.Type,
+		Scope:       accessToken.Scope,
+	}
+	if accessToken.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(accessToken.ExpiresIn) * time.Second)
+	}
+	return c.tokens.SetToken(ctx, token)
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+)
+
+// ReissuedTokenDTO is the token issued when a password change revoked the other sessions.
+type ReissuedTokenDTO struct {
+	AccessToken string `json:"access_token"`
+	Type        string `json:"type"`
+}
+
+func (c *Client) Register(ctx context.Context, req users.RegisterUserDTO) (*users.UserDTO, error) {
+	user := &users.UserDTO{}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/register", body: req}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GenerateAccessToken generates an access token and stores it for the authenticated calls.
+func (c *Client) GenerateAccessToken(ctx context.Context, req users.GenerateUserAccessTokenDTO) (*users.AccessTokenDTO, error) {
+	return c.generateAccessToken(ctx, req)
+}
+
+func (c *Client) generateAccessToken(ctx context.Context, req users.GenerateUserAccessTokenDTO) (*users.AccessTokenDTO, error) {
+	token := &users.AccessTokenDTO{}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/generate-access-token", body: req}, token); err != nil {
+		return nil, err
+	}
+
+	if err := c.storeAccessToken(ctx, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// BlacklistAccessToken revokes the stored access token and clears it.
+func (c *Client) BlacklistAccessToken(ctx context.Context) error {
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/blacklist-access-token", auth: true}, nil); err != nil {
+		return err
+	}
+	return c.tokens.SetToken(ctx, nil)
+}
+
+func (c *Client) GetMe(ctx context.Context) (*users.UserDTO, error) {
+	user := &users.UserDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/me", auth: true}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *Client) UpdateMe(ctx context.Context, req users.UpdateProfileDTO) (*users.UserDTO, error) {
+	user := &users.UserDTO{}
+	if err := c.do(ctx, request{method: http.MethodPatch, path: "/me", body: req, auth: true}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteMe deletes the authenticated user's account and clears the stored access token.
+func (c *Client) DeleteMe(ctx context.Context, req users.DeleteAccountDTO) error {
+	if err := c.do(ctx, request{method: http.MethodDelete, path: "/me", body: req, auth: true}, nil); err != nil {
+		return err
+	}
+	return c.tokens.SetToken(ctx, nil)
+}
+
+// ChangePassword changes the authenticated user's password, the token issued when the other sessions
+// are revoked replaces the stored one and is returned, it is nil otherwise.
+// The client's credentials are updated to the new password.
+func (c *Client) ChangePassword(ctx context.Context, req users.ChangePasswordDTO) (*ReissuedTokenDTO, error) {
+	token := &ReissuedTokenDTO{}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/me/password", body: req, auth: true}, token); err != nil {
+		return nil, err
+	}
+
+	c.refreshMu.Lock()
+	if c.credentials != nil {
+		c.credentials.Password = req.NewPassword
+	}
+	c.refreshMu.Unlock()
+
+	if token.AccessToken == "" {
+		return nil, nil
+	}
+
+	// The new token's expiry isn't returned
+	if err := c.tokens.SetToken(ctx, &Token{AccessToken: token.AccessToken, Type: token.Type}); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (c *Client) ExportMe(ctx context.Context) (*users.UserDataExportDTO, error) {
+	export := &users.UserDataExportDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/me/export", auth: true}, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (c *Client) ListSessions(ctx context.Context) ([]users.SessionDTO, error) {
+	sessions := []users.SessionDTO{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/me/sessions", auth: true}, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (c *Client) RevokeSession(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/me/sessions/" + url.PathEscape(id), auth: true}, nil)
+}
+
+func (c *Client) VerifyEmail(ctx context.Context, req users.VerifyEmailDTO) (*users.UserDTO, error) {
+	user := &users.UserDTO{}
+	query := url.Values{"token": {req.Token}}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/verify-email", query: query}, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *Client) ResetPassword(ctx context.Context, req users.ResetPasswordDTO) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/reset-password", body: req}, nil)
+}
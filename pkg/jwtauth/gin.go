@@ -0,0 +1,23 @@
+package jwtauth
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Gin returns a gin middleware, the principal is set on the gin context as "principal"
+// as well as on the request's context.
+func (v *Verifier) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := v.Verify(c.Request.Context(), bearerToken(c.GetHeader("Authorization")))
+		if err != nil {
+			status, response := errorStatus(err)
+			setAuthenticateHeader(c.Writer.Header(), err)
+			c.AbortWithStatusJSON(status, response)
+			return
+		}
+
+		c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), principal))
+		c.Set("principal", principal)
+		c.Next()
+	}
+}
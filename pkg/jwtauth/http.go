@@ -0,0 +1,87 @@
+package jwtauth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// errorResponse matches the API's own error responses.
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handler only lets through requests with a valid Bearer token,
+// the authenticated principal is available to next through PrincipalFromContext.
+func (v *Verifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := v.Verify(r.Context(), bearerToken(r.Header.Get("Authorization")))
+		if err != nil {
+			status, response := errorStatus(err)
+			setAuthenticateHeader(w.Header(), err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// EchoContext is the part of echo.Context the echo-style middleware uses,
+// so echo doesn't have to be a dependency.
+type EchoContext interface {
+	Request() *http.Request
+	SetRequest(r *http.Request)
+	Response() http.ResponseWriter
+	Set(key string, val interface{})
+	JSON(code int, i interface{}) error
+}
+
+// Echo returns an echo-style middleware, the principal is set on the context as "principal"
+// as well as on the request's context. With echo it is used as
+//
+//	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc { return jwtauth.Echo[echo.Context](verifier)(next) })
+func Echo[C EchoContext](v *Verifier) func(next func(C) error) func(C) error {
+	return func(next func(C) error) func(C) error {
+		return func(c C) error {
+			r := c.Request()
+			principal, err := v.Verify(r.Context(), bearerToken(r.Header.Get("Authorization")))
+			if err != nil {
+				status, response := errorStatus(err)
+				setAuthenticateHeader(c.Response().Header(), err)
+				return c.JSON(status, response)
+			}
+
+			c.SetRequest(r.WithContext(WithPrincipal(r.Context(), principal)))
+			c.Set("principal", principal)
+			return next(c)
+		}
+	}
+}
+
+// errorStatus maps a verification error to its response,
+// errors other than the token being rejected are server errors.
+func errorStatus(err error) (int, errorResponse) {
+	status := http.StatusInternalServerError
+	if isUnauthenticated(err) {
+		status = http.StatusUnauthorized
+	}
+	return status, errorResponse{Code: status, Message: err.Error()}
+}
+
+func isUnauthenticated(err error) bool {
+	return errors.Is(err, ErrMissingToken) || errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrRevokedToken)
+}
+
+// setAuthenticateHeader sets the RFC 6750 challenge for rejected tokens.
+func setAuthenticateHeader(header http.Header, err error) {
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		header.Set("WWW-Authenticate", "Bearer")
+	case isUnauthenticated(err):
+		header.Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+}
@@ -0,0 +1,146 @@
+package jwtauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+const (
+	defaultJWKSRefreshInterval = time.Hour
+
+	// minJWKSRefreshInterval limits how often tokens signed by unknown keys trigger a refresh
+	minJWKSRefreshInterval = 10 * time.Second
+
+	// jwksFetchTimeout bounds a refresh, whatever the HTTP client
+	jwksFetchTimeout = 10 * time.Second
+)
+
+// jwks caches the keys published by the API, they are refreshed once stale
+// or when a token is signed by a key that isn't known yet, e.g. after the key was rotated.
+// A single refresh runs at a time, outside of the lock, so a slow endpoint only delays tokens signed by unknown keys.
+type jwks struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]jose.JSONWebKey
+	fetchedAt time.Time
+
+	// refreshing is closed once the refresh in flight is done, it is nil when none is
+	refreshing chan struct{}
+	// refreshErr is the error of the last refresh
+	refreshErr error
+}
+
+func (j *jwks) key(ctx context.Context, kid, alg string) (interface{}, error) {
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	sinceFetch := time.Since(j.fetchedAt)
+	var done chan struct{}
+	if sinceFetch >= j.refreshInterval || (!ok && sinceFetch >= minJWKSRefreshInterval) {
+		done = j.startRefresh()
+	} else if !ok {
+		done = j.refreshing
+	}
+	j.mu.Unlock()
+
+	// Known keys are used while stale ones are refreshed in the background
+	if !ok && done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		j.mu.Lock()
+		key, ok = j.keys[kid]
+		refreshErr := j.refreshErr
+		j.mu.Unlock()
+
+		if !ok && refreshErr != nil {
+			return nil, refreshErr
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key %s", kid)
+	} else if key.Algorithm != "" && key.Algorithm != alg {
+		return nil, fmt.Errorf("key %s is not used with %s", kid, alg)
+	}
+	return key.Key, nil
+}
+
+// startRefresh must be called with the lock held, it joins the refresh in flight if there is one.
+func (j *jwks) startRefresh() chan struct{} {
+	if j.refreshing != nil {
+		return j.refreshing
+	}
+
+	done := make(chan struct{})
+	j.refreshing = done
+	j.fetchedAt = time.Now()
+
+	go func() {
+		// The refresh is shared, so it isn't bound to the context of the request which started it
+		ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		defer cancel()
+		keys, err := j.fetch(ctx)
+
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+		}
+		j.refreshErr = err
+		j.refreshing = nil
+		j.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+func (j *jwks) fetch(ctx context.Context) (map[string]jose.JSONWebKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch the JWKS: %s", res.Status)
+	}
+
+	var keySet jose.JSONWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&keySet); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]jose.JSONWebKey, len(keySet.Keys))
+	for _, key := range keySet.Keys {
+		if key.Use == "" || key.Use == "sig" {
+			keys[key.KeyID] = key
+		}
+	}
+	return keys, nil
+}
+
+func newJWKS(url string, client *http.Client, refreshInterval time.Duration) *jwks {
+	if client == nil {
+		client = &http.Client{Timeout: jwksFetchTimeout}
+	}
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+	return &jwks{url: url, client: client, refreshInterval: refreshInterval}
+}
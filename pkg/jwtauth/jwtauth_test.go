@@ -0,0 +1,190 @@
+package jwtauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/common/config"
+	"github.com/the-code-genin/simple-jwt-api-go/common/keys"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type revokedTokens map[string]bool
+
+func (r revokedTokens) Revoked(ctx context.Context, token string, principal *Principal) (bool, error) {
+	return r[principal.TokenID], nil
+}
+
+func issueToken(t *testing.T, cfg config.JWTConfig, keySet *keys.KeySet) (string, tokens.Claims) {
+	codec, err := tokens.NewJWTCodec(cfg, keySet)
+	assert.Nil(t, err)
+
+	claims := tokens.Claims{
+		RegisteredClaims: tokens.NewRegisteredClaims("1", time.Minute),
+		UserID:           "1",
+		UserEmail:        "user@example.com",
+		Permissions:      []string{"users:read"},
+	}
+	token, err := codec.Encode(context.Background(), claims)
+	assert.Nil(t, err)
+	return token, claims
+}
+
+func TestHandler(t *testing.T) {
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+	token, claims := issueToken(t, config.JWTConfig{Key: "key"}, keySet)
+
+	revocations := revokedTokens{}
+	verifier, err := NewVerifier(Config{Key: []byte("key"), Revocations: revocations})
+	assert.Nil(t, err)
+
+	handler := verifier.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := PrincipalFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "user@example.com", principal.UserEmail)
+		assert.True(t, principal.HasPermission("users:read"))
+	}))
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	assert.Equal(t, http.StatusOK, serve("Bearer "+token).Code)
+
+	res := serve("")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, "Bearer", res.Header().Get("WWW-Authenticate"))
+
+	res = serve("Bearer " + token + "x")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	response := errorResponse{}
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&response))
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+
+	revocations[claims.ID] = true
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer "+token).Code)
+}
+
+func TestJWKS(t *testing.T) {
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+	token, _ := issueToken(t, config.JWTConfig{Algorithm: "RS256"}, keySet)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(keySet.JWKS())
+	}))
+	defer server.Close()
+
+	verifier, err := NewVerifier(Config{JWKSURL: server.URL})
+	assert.Nil(t, err)
+
+	principal, err := verifier.Verify(context.Background(), token)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", principal.UserID)
+
+	// Tokens signed with the shared key aren't accepted without it
+	hsToken, _ := issueToken(t, config.JWTConfig{Key: "key"}, keySet)
+	_, err = verifier.Verify(context.Background(), hsToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+	token, _ := issueToken(t, config.JWTConfig{Key: "key"}, keySet)
+
+	verifier, err := NewVerifier(Config{Key: []byte("key")})
+	assert.Nil(t, err)
+	interceptor := verifier.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, _ := PrincipalFromContext(ctx)
+		return principal.UserID, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", res)
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestVerifyClaims(t *testing.T) {
+	ctx := context.Background()
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+	codec, err := tokens.NewJWTCodec(config.JWTConfig{Key: "key"}, keySet)
+	assert.Nil(t, err)
+
+	encode := func(claims tokens.Claims) string {
+		token, err := codec.Encode(ctx, claims)
+		assert.Nil(t, err)
+		return token
+	}
+	registeredClaims := tokens.NewRegisteredClaims("1", time.Minute)
+	registeredClaims.Issuer = "https://auth.example.com"
+
+	verifier, err := NewVerifier(Config{Key: []byte("key"), Issuer: "https://auth.example.com"})
+	assert.Nil(t, err)
+
+	_, err = verifier.Verify(ctx, encode(tokens.Claims{RegisteredClaims: registeredClaims, UserID: "1"}))
+	assert.Nil(t, err)
+
+	// ID tokens carry neither the user nor the client
+	_, err = verifier.Verify(ctx, encode(tokens.Claims{RegisteredClaims: registeredClaims}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = verifier.Verify(ctx, encode(tokens.Claims{
+		RegisteredClaims: registeredClaims,
+		UserID:           "1",
+		Confirmation:     tokens.NewConfirmation("jkt"),
+	}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	otherIssuer := registeredClaims
+	otherIssuer.Issuer = "https://other.example.com"
+	_, err = verifier.Verify(ctx, encode(tokens.Claims{RegisteredClaims: otherIssuer, UserID: "1"}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWKSTimeout(t *testing.T) {
+	keySet, err := keys.NewKeySet(config.JWTConfig{})
+	assert.Nil(t, err)
+	token, _ := issueToken(t, config.JWTConfig{Algorithm: "RS256"}, keySet)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	verifier, err := NewVerifier(Config{JWKSURL: server.URL})
+	assert.Nil(t, err)
+
+	// Verifications give up with their own context while the endpoint hangs
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err = verifier.Verify(ctx, token)
+		cancel()
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+}
@@ -0,0 +1,35 @@
+package jwtauth
+
+import (
+	"context"
+
+	"github.com/the-code-genin/simple-jwt-api-go/database/blacklisted_tokens"
+)
+
+type revocationList struct {
+	repository blacklisted_tokens.BlacklistedTokensRepository
+}
+
+func (l *revocationList) Revoked(ctx context.Context, token string, principal *Principal) (bool, error) {
+	blacklisted, err := l.repository.Exists(ctx, token, principal.TokenID)
+	if err != nil || blacklisted {
+		return blacklisted, err
+	}
+
+	// Client tokens can only be blacklisted one by one
+	if principal.UserID == "" {
+		return false, nil
+	}
+
+	revokedBefore, err := l.repository.UserRevokedBefore(ctx, principal.UserID)
+	if err != nil {
+		return false, err
+	}
+	return principal.IssuedAt.Unix() < revokedBefore, nil
+}
+
+// NewRevocationList consults the API's blacklist, e.g. through blacklisted_tokens.NewBlacklistedTokensRepository
+// with a client for the API's redis, wrapped by NewCachedBlacklistedTokensRepository to cache the lookups.
+func NewRevocationList(repository blacklisted_tokens.BlacklistedTokensRepository) RevocationList {
+	return &revocationList{repository}
+}
@@ -0,0 +1,218 @@
+// Package jwtauth verifies the access tokens issued by this API in other Go services.
+//
+// Tokens are verified with the shared JWT_KEY for HS256 tokens or with the keys published at
+// /.well-known/jwks.json for asymmetrically signed ones. Middlewares for net/http, gin and
+// echo-style handlers and gRPC interceptors put the authenticated Principal in the request's context.
+//
+// Encrypted, PASETO and opaque tokens can't be verified offline and are rejected,
+// so are DPoP bound tokens since there is no proof of their key.
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
+)
+
+var (
+	ErrMissingToken = errors.New("missing access token")
+	ErrInvalidToken = errors.New("invalid access token")
+	ErrRevokedToken = errors.New("revoked access token")
+)
+
+type Config struct {
+	// Key is the shared JWT_KEY, it is required to accept HS256 tokens
+	Key []byte
+
+	// JWKSURL is the API's /.well-known/jwks.json, it is required to accept asymmetrically signed tokens
+	JWKSURL string
+	// JWKSRefreshInterval is how long the keys are cached for, an hour when zero
+	JWKSRefreshInterval time.Duration
+	// HTTPClient fetches the keys, a client with a 10 second timeout when nil
+	HTTPClient *http.Client
+
+	// Issuer is the API's APP_URL, tokens must have been issued by it when set
+	Issuer string
+	// Audience must be one of the token's audiences when set
+	Audience string
+
+	// Revocations is consulted when set, otherwise tokens are valid until they expire
+	Revocations RevocationList
+}
+
+// RevocationList tells whether a verified token was revoked before it expired, see NewRevocationList.
+type RevocationList interface {
+	Revoked(ctx context.Context, token string, principal *Principal) (bool, error)
+}
+
+// Principal is who a verified access token was issued to.
+type Principal struct {
+	TokenID string
+	Subject string
+
+	// The user fields are empty for tokens issued to a client acting on its own behalf
+	UserID      string
+	UserEmail   string
+	Roles       []string
+	Permissions []string
+
+	ClientID  string
+	Scopes    []string
+	Audience  []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	// Claims are all of the token's claims
+	Claims tokens.Claims
+}
+
+// IsClient reports whether the token was issued to a client acting on its own behalf.
+func (p *Principal) IsClient() bool {
+	return p.Claims.IsClient()
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return scopes.Contains(p.Scopes, scope)
+}
+
+func (p *Principal) HasPermission(permission string) bool {
+	return contains(p.Permissions, permission)
+}
+
+// Verifier verifies access tokens, it is safe for concurrent use.
+type Verifier struct {
+	config Config
+	jwks   *jwks
+}
+
+// Verify checks the token's signature, expiry and audience and, when configured, that it wasn't revoked.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	claims := tokens.Claims{}
+	_, err := jwt.ParseWithClaims(
+		token,
+		&claims,
+		func(parsed *jwt.Token) (interface{}, error) { return v.key(ctx, parsed) },
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	} else if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	}
+
+	// ID tokens are signed with the same keys but carry neither the user nor the client
+	if claims.UserID == "" && claims.ClientID == "" {
+		return nil, fmt.Errorf("%w: not an access token", ErrInvalidToken)
+	}
+
+	// There is no proof of the key DPoP bound tokens are bound to
+	if claims.Confirmation != nil {
+		return nil, fmt.Errorf("%w: DPoP bound", ErrInvalidToken)
+	}
+
+	if v.config.Issuer != "" && claims.Issuer != v.config.Issuer {
+		return nil, fmt.Errorf("%w: not issued by %s", ErrInvalidToken, v.config.Issuer)
+	}
+
+	if v.config.Audience != "" && !contains(claims.Audience, v.config.Audience) {
+		return nil, fmt.Errorf("%w: not issued for %s", ErrInvalidToken, v.config.Audience)
+	}
+
+	principal := newPrincipal(claims)
+	if v.config.Revocations != nil {
+		revoked, err := v.config.Revocations.Revoked(ctx, token, principal)
+		if err != nil {
+			return nil, err
+		} else if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	return principal, nil
+}
+
+// key returns the key the token must be signed with.
+func (v *Verifier) key(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if token.Method != jwt.SigningMethodHS256 || len(v.config.Key) == 0 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return v.config.Key, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		if v.jwks == nil {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return v.jwks.key(ctx, kid, token.Method.Alg())
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+}
+
+func newPrincipal(claims tokens.Claims) *Principal {
+	// Tokens issued before scopes were introduced carry no scope claim
+	var grantedScopes []string
+	if claims.Scope != nil {
+		grantedScopes = scopes.Parse(*claims.Scope)
+	}
+
+	principal := &Principal{
+		TokenID:     claims.ID,
+		Subject:     claims.Subject,
+		UserID:      claims.UserID,
+		UserEmail:   claims.UserEmail,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		ClientID:    claims.ClientID,
+		Scopes:      grantedScopes,
+		Audience:    claims.Audience,
+		ExpiresAt:   claims.ExpiresAt.Time,
+		Claims:      claims,
+	}
+	if claims.IssuedAt != nil {
+		principal.IssuedAt = claims.IssuedAt.Time
+	}
+	return principal
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token of a Bearer authorization header.
+func bearerToken(header string) string {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if len(cfg.Key) == 0 && cfg.JWKSURL == "" {
+		return nil, errors.New("either a shared key or a JWKS URL is required")
+	}
+
+	verifier := &Verifier{config: cfg}
+	if cfg.JWKSURL != "" {
+		verifier.jwks = newJWKS(cfg.JWKSURL, cfg.HTTPClient, cfg.JWKSRefreshInterval)
+	}
+	return verifier, nil
+}
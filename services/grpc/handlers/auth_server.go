@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/services/grpc/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type AuthServer struct {
+	pb.UnimplementedAuthServiceServer
+
+	usersService       users.UsersService
+	verificationPolicy users.VerificationPolicy
+}
+
+func (s *AuthServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.User, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AuthServer/Register"))
+
+	if req.Name == "" || req.Password == "" || !isEmail(req.Email) {
+		message := "a name, a valid email and a password are required"
+		logger.Error(ctx, message)
+		return nil, status.Error(codes.InvalidArgument, message)
+	}
+
+	user, err := s.usersService.Register(ctx, users.RegisterUserDTO{
+		Name:     req.Name,
+		Email:    req.Email,
+		Password: req.Password,
+	})
+	if err != nil {
+		message := "An error occured while registering the user"
+		logger.Error(ctx, message, zap.Error(err))
+
+		var validationErrors users.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return newUser(*user), nil
+}
+
+func (s *AuthServer) GenerateAccessToken(ctx context.Context, req *pb.GenerateAccessTokenRequest) (*pb.AccessToken, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AuthServer/GenerateAccessToken"))
+
+	if req.Password == "" || !isEmail(req.Email) {
+		message := "a valid email and a password are required"
+		logger.Error(ctx, message)
+		return nil, status.Error(codes.InvalidArgument, message)
+	}
+
+	token, err := s.usersService.GenerateAccessToken(ctx, users.GenerateUserAccessTokenDTO{
+		Email:       req.Email,
+		Password:    req.Password,
+		Scope:       req.Scope,
+		DeviceLabel: req.DeviceLabel,
+		Device:      getDevice(ctx),
+	})
+	if err != nil {
+		message := "An error occured while generate user access token"
+		logger.Error(ctx, message, zap.Error(err))
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return &pb.AccessToken{
+		User:        newUser(token.User),
+		AccessToken: token.AccessToken,
+		Type:        token.Type,
+		Scope:       token.Scope,
+		ExpiresIn:   int32(token.ExpiresIn),
+	}, nil
+}
+
+func (s *AuthServer) DecodeAccessToken(ctx context.Context, req *pb.DecodeAccessTokenRequest) (*pb.DecodeAccessTokenResponse, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AuthServer/DecodeAccessToken"))
+
+	if req.Token == "" {
+		message := "a token is required"
+		logger.Error(ctx, message)
+		return nil, status.Error(codes.InvalidArgument, message)
+	}
+
+	accessToken, err := s.usersService.DecodeAccessToken(ctx, req.Token, s.verificationPolicy)
+	if err != nil {
+		logger.Error(ctx, "Unable to decode user access token", zap.Error(err))
+		return &pb.DecodeAccessTokenResponse{Active: false}, nil
+	}
+
+	decoded := &pb.DecodedAccessToken{
+		Id:        accessToken.ID,
+		ClientId:  accessToken.ClientID,
+		UserId:    accessToken.User.ID,
+		Audience:  accessToken.Audience,
+		Scopes:    accessToken.Scopes,
+		IssuedAt:  accessToken.IssuedAt,
+		ExpiresAt: accessToken.ExpiresAt,
+		SessionId: accessToken.SessionID,
+	}
+	if accessToken.Confirmation != nil {
+		decoded.Jkt = accessToken.Confirmation.JKT
+	}
+
+	return &pb.DecodeAccessTokenResponse{Active: true, Token: decoded}, nil
+}
+
+func (s *AuthServer) BlacklistAccessToken(ctx context.Context, req *emptypb.Empty) (*emptypb.Empty, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AuthServer/BlacklistAccessToken"))
+
+	auth, ok := getAuthContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "an error occured")
+	}
+
+	if err := s.usersService.BlacklistAccessToken(ctx, auth.token); err != nil {
+		message := "An error occured while blacklisting user access token"
+		logger.Error(ctx, message, zap.Error(err))
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *AuthServer) GetMe(ctx context.Context, req *emptypb.Empty) (*pb.User, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "AuthServer/GetMe"))
+
+	auth, ok := getAuthContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "an error occured")
+	}
+
+	return newUser(auth.accessToken.User), nil
+}
+
+func newUser(user users.UserDTO) *pb.User {
+	return &pb.User{
+		Id:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Roles:         user.Roles,
+		Permissions:   user.Permissions,
+	}
+}
+
+func isEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// getDevice describes the device the call was made from.
+func getDevice(ctx context.Context) users.DeviceDTO {
+	device := users.DeviceDTO{}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		device.IPAddress = p.Addr.String()
+		if host, _, err := net.SplitHostPort(device.IPAddress); err == nil {
+			device.IPAddress = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) != 0 {
+			device.UserAgent = values[0]
+		}
+	}
+	return device
+}
+
+func NewAuthServer(usersService users.UsersService, verificationPolicy users.VerificationPolicy) *AuthServer {
+	return &AuthServer{usersService: usersService, verificationPolicy: verificationPolicy}
+}
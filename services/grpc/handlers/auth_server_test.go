@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/services/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type fakeUsersService struct {
+	users.UsersService
+	tokens map[string]users.DecodedAccessTokenDTO
+}
+
+func (f *fakeUsersService) DecodeAccessToken(ctx context.Context, token string, policy users.VerificationPolicy) (*users.DecodedAccessTokenDTO, error) {
+	accessToken, ok := f.tokens[token]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &accessToken, nil
+}
+
+func TestAuthServer(t *testing.T) {
+	user := users.UserDTO{ID: "1", Name: "User", Email: "user@example.com", Roles: []string{"admin"}}
+	usersService := &fakeUsersService{tokens: map[string]users.DecodedAccessTokenDTO{
+		"profile": {ID: "profile", User: user, Scopes: []string{"profile"}},
+		"narrow":  {ID: "narrow", User: user, Scopes: []string{"email"}},
+	}}
+	interceptors := NewInterceptors(usersService, users.VerificationCached)
+	server := NewAuthServer(usersService, users.VerificationCached)
+
+	getMe := func(token string) (*pb.User, error) {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+
+		info := &grpc.UnaryServerInfo{FullMethod: pb.AuthService_GetMe_FullMethodName}
+		res, err := interceptors.UnaryAuth(ctx, &emptypb.Empty{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return server.GetMe(ctx, req.(*emptypb.Empty))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res.(*pb.User), nil
+	}
+
+	me, err := getMe("profile")
+	assert.Nil(t, err)
+	assert.Equal(t, "user@example.com", me.Email)
+
+	_, err = getMe("")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = getMe("invalid")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Tokens narrowed down to other scopes can't read the profile
+	_, err = getMe("narrow")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Decoding doesn't require authentication, so the profile isn't disclosed
+	res, err := server.DecodeAccessToken(context.Background(), &pb.DecodeAccessTokenRequest{Token: "profile"})
+	assert.Nil(t, err)
+	assert.True(t, res.Active)
+	assert.Equal(t, "1", res.Token.UserId)
+
+	res, err = server.DecodeAccessToken(context.Background(), &pb.DecodeAccessTokenRequest{Token: "invalid"})
+	assert.Nil(t, err)
+	assert.False(t, res.Active)
+	assert.Nil(t, res.Token)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authz "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/the-code-genin/simple-jwt-api-go/application/gateway"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
+	rpc_status "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// ExtAuthzServer is an Envoy external authorization service (ext_authz),
+// allowed requests reach the upstream with the X-User-Id and X-User-Email headers.
+type ExtAuthzServer struct {
+	gatewayService gateway.GatewayService
+}
+
+func (s *ExtAuthzServer) Check(ctx context.Context, req *authz.CheckRequest) (*authz.CheckResponse, error) {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "ExtAuthzServer/Check"))
+
+	// Envoy lower cases the header names
+	token := ""
+	authorization := req.GetAttributes().GetRequest().GetHttp().GetHeaders()["authorization"]
+	if scheme, value, found := strings.Cut(authorization, " "); found && strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimSpace(value)
+	}
+
+	decision, err := s.gatewayService.Verify(ctx, token)
+	if err != nil {
+		logger.Error(ctx, "The request is not authenticated", zap.Error(err))
+
+		switch {
+		case errors.Is(err, gateway.ErrMissingToken):
+			return denied(codes.Unauthenticated, err.Error(), "Bearer"), nil
+		case errors.Is(err, gateway.ErrInvalidToken), errors.Is(err, gateway.ErrDPoPBound):
+			return denied(codes.Unauthenticated, err.Error(), `Bearer error="invalid_token"`), nil
+		}
+		return nil, err
+	}
+
+	// The headers replace any sent by the client
+	return &authz.CheckResponse{
+		Status: &rpc_status.Status{Code: int32(codes.OK)},
+		HttpResponse: &authz.CheckResponse_OkResponse{
+			OkResponse: &authz.OkHttpResponse{
+				Headers: []*core.HeaderValueOption{
+					header("x-user-id", decision.UserID),
+					header("x-user-email", decision.UserEmail),
+				},
+			},
+		},
+	}, nil
+}
+
+func denied(code codes.Code, message, challenge string) *authz.CheckResponse {
+	return &authz.CheckResponse{
+		Status: &rpc_status.Status{Code: int32(code), Message: message},
+		HttpResponse: &authz.CheckResponse_DeniedResponse{
+			DeniedResponse: &authz.DeniedHttpResponse{
+				Status:  &envoy_type.HttpStatus{Code: envoy_type.StatusCode_Unauthorized},
+				Headers: []*core.HeaderValueOption{header("www-authenticate", challenge)},
+				Body:    message,
+			},
+		},
+	}
+}
+
+func header(key, value string) *core.HeaderValueOption {
+	return &core.HeaderValueOption{
+		Header:       &core.HeaderValue{Key: key, Value: value},
+		AppendAction: core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	}
+}
+
+func NewExtAuthzServer(gatewayService gateway.GatewayService) *ExtAuthzServer {
+	return &ExtAuthzServer{gatewayService}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/services/grpc/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type authContextKey struct{}
+
+// authContext is what Interceptors.UnaryAuth puts in the context of authenticated calls.
+type authContext struct {
+	token       string
+	accessToken users.DecodedAccessTokenDTO
+}
+
+// methodAuth is how calls to an authenticated method are verified.
+type methodAuth struct {
+	policy users.VerificationPolicy
+	// scopes must all be granted to the token, like with the HTTP routes
+	scopes []string
+}
+
+type Interceptors struct {
+	usersService       users.UsersService
+	verificationPolicy users.VerificationPolicy
+
+	// authenticated maps the methods requiring an access token to how they are verified
+	authenticated map[string]methodAuth
+}
+
+// UnaryAuth verifies the access token of calls to authenticated methods, other calls are let through.
+func (i *Interceptors) UnaryAuth(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	auth, ok := i.authenticated[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	logCtx := logger.With(ctx, zap.String(logger.FunctionNameField, "Interceptors/UnaryAuth"))
+
+	token, ok := bearerToken(ctx)
+	if !ok {
+		message := "invalid authorization metadata"
+		logger.Error(logCtx, message)
+		return nil, status.Error(codes.Unauthenticated, message)
+	}
+
+	accessToken, err := i.usersService.DecodeAccessToken(ctx, token, auth.policy)
+	if err != nil {
+		message := "Unable to decode user access token"
+		logger.Error(logCtx, message, zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, message)
+	}
+
+	// There is no way to send the DPoP proof bound tokens need
+	if accessToken.Confirmation != nil {
+		message := "DPoP bound access tokens can't be used over gRPC"
+		logger.Error(logCtx, message)
+		return nil, status.Error(codes.Unauthenticated, message)
+	}
+
+	for _, scope := range auth.scopes {
+		if !scopes.Contains(accessToken.Scopes, scope) {
+			message := fmt.Sprintf("Scope %s required", scope)
+			logger.Error(logCtx, message, zap.String("userId", accessToken.User.ID))
+			return nil, status.Error(codes.PermissionDenied, message)
+		}
+	}
+
+	return handler(context.WithValue(ctx, authContextKey{}, authContext{token, *accessToken}), req)
+}
+
+// bearerToken reads the access token from the call's authorization metadata.
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+
+	scheme, token, found := strings.Cut(values[0], " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// getAuthContext returns what UnaryAuth put in the context.
+func getAuthContext(ctx context.Context) (authContext, bool) {
+	auth, ok := ctx.Value(authContextKey{}).(authContext)
+	if !ok {
+		logger.Error(ctx, "Auth context not in call context")
+	}
+	return auth, ok
+}
+
+func NewInterceptors(usersService users.UsersService, verificationPolicy users.VerificationPolicy) *Interceptors {
+	return &Interceptors{
+		usersService:       usersService,
+		verificationPolicy: verificationPolicy,
+		authenticated: map[string]methodAuth{
+			// Revoking access always verifies the token against the backing stores
+			pb.AuthService_BlacklistAccessToken_FullMethodName: {policy: users.VerificationStrict},
+			pb.AuthService_GetMe_FullMethodName:                {policy: verificationPolicy, scopes: []string{scopes.ScopeProfile}},
+		},
+	}
+}
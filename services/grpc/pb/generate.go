@@ -0,0 +1,4 @@
+// Package pb holds the gRPC API's protobuf definitions and the code generated from them.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative auth.proto
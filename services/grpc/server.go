@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"fmt"
+	"net"
+
+	authz "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/the-code-genin/simple-jwt-api-go/application/gateway"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/services/grpc/handlers"
+	"github.com/the-code-genin/simple-jwt-api-go/services/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type Server struct {
+	server *grpc.Server
+}
+
+func (s *Server) Run(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return s.server.Serve(listener)
+}
+
+func NewServer(
+	usersService users.UsersService,
+	gatewayService gateway.GatewayService,
+	verificationPolicy users.VerificationPolicy,
+) (*Server, error) {
+	interceptors := handlers.NewInterceptors(usersService, verificationPolicy)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors.UnaryAuth))
+
+	pb.RegisterAuthServiceServer(server, handlers.NewAuthServer(usersService, verificationPolicy))
+	authz.RegisterAuthorizationServer(server, handlers.NewExtAuthzServer(gatewayService))
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.AuthService_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("envoy.service.auth.v3.Authorization", grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return &Server{server}, nil
+}
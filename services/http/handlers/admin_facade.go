@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/admin"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
+)
+
+type AdminFacade struct {
+	adminService admin.AdminService
+}
+
+// ListUsers godoc
+//
+// @Summary  List users
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    email  query     string false "filter by email substring"
+// @Param    name   query     string false "filter by name substring"
+// @Param    status query     string false "filter by status" Enums(active, suspended, deleted)
+// @Param    sort   query     string false "sort column" Enums(created_at, name, email)
+// @Param    order  query     string false "sort order" Enums(asc, desc)
+// @Param    cursor query     string false "next_cursor from the previous page"
+// @Param    limit  query     int    false "page size"
+// @Success  200    {object} APIResponse{data=admin.UsersPageDTO}
+// @Failure  400    {object} APIResponse
+// @Failure  403    {object} APIResponse
+// @Failure  412    {object} APIResponse
+// @Failure  500    {object} APIResponse
+// @Router   /admin/users [get]
+func (a *AdminFacade) ListUsers(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "AdminFacade/ListUsers"))
+
+	var req admin.ListUsersDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request query to admin.ListUsersDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	page, err := a.adminService.ListUsers(c, req)
+	if err != nil {
+		message := "An error occured while listing users"
+		logger.Error(ctx, message, zap.Error(err))
+		SendPreconditionFailed(c, err.Error())
+		return
+	}
+
+	SendOk(c, page)
+}
+
+// GetUser godoc
+//
+// @Summary  Get a user
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    id  path      string true "user id"
+// @Success  200 {object} APIResponse{data=admin.UserDTO}
+// @Failure  403 {object} APIResponse
+// @Failure  404 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/users/{id} [get]
+func (a *AdminFacade) GetUser(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "AdminFacade/GetUser"))
+
+	user, err := a.adminService.GetUser(c, c.Param("id"))
+	if err != nil {
+		message := "An error occured while getting the user"
+		logger.Error(ctx, message, zap.Error(err))
+		sendAdminError(c, err)
+		return
+	}
+
+	SendOk(c, user)
+}
+
+// SuspendUser godoc
+//
+// @Summary  Suspend a user
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    id  path      string true "user id"
+// @Success  200 {object} APIResponse{data=admin.UserDTO}
+// @Failure  403 {object} APIResponse
+// @Failure  404 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/users/{id}/suspend [post]
+func (a *AdminFacade) SuspendUser(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "AdminFacade/SuspendUser"))
+
+	user, err := a.adminService.SuspendUser(c, c.Param("id"))
+	if err != nil {
+		message := "An error occured while suspending the user"
+		logger.Error(ctx, message, zap.Error(err))
+		sendAdminError(c, err)
+		return
+	}
+
+	SendOk(c, user)
+}
+
+// UnsuspendUser godoc
+//
+// @Summary  Unsuspend a user
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    id  path      string true "user id"
+// @Success  200 {object} APIResponse{data=admin.UserDTO}
+// @Failure  403 {object} APIResponse
+// @Failure  404 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/users/{id}/unsuspend [post]
+func (a *AdminFacade) UnsuspendUser(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "AdminFacade/UnsuspendUser"))
+
+	user, err := a.adminService.UnsuspendUser(c, c.Param("id"))
+	if err != nil {
+		message := "An error occured while unsuspending the user"
+		logger.Error(ctx, message, zap.Error(err))
+		sendAdminError(c, err)
+		return
+	}
+
+	SendOk(c, user)
+}
+
+// ForcePasswordReset godoc
+//
+// @Summary     Force a user to reset their password
+// @Description Revokes the user's access tokens and emails them a password reset token
+// @Produce     json
+// @security    securitydefinitions.apikey
+// @Param       id  path      string true "user id"
+// @Success     200 {object} APIResponse{data=BlankStruct}
+// @Failure     403 {object} APIResponse
+// @Failure     404 {object} APIResponse
+// @Failure     412 {object} APIResponse
+// @Failure     500 {object} APIResponse
+// @Router      /admin/users/{id}/force-password-reset [post]
+func (a *AdminFacade) ForcePasswordReset(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "AdminFacade/ForcePasswordReset"))
+
+	if err := a.adminService.ForcePasswordReset(c, c.Param("id")); err != nil {
+		message := "An error occured while forcing the user password reset"
+		logger.Error(ctx, message, zap.Error(err))
+		sendAdminError(c, err)
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// DeleteUser godoc
+//
+// @Summary  Delete a user
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    id  path      string true "user id"
+// @Success  200 {object} APIResponse{data=BlankStruct}
+// @Failure  403 {object} APIResponse
+// @Failure  404 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/users/{id} [delete]
+func (a *AdminFacade) DeleteUser(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "AdminFacade/DeleteUser"))
+
+	if err := a.adminService.DeleteUser(c, c.Param("id")); err != nil {
+		message := "An error occured while deleting the user"
+		logger.Error(ctx, message, zap.Error(err))
+		sendAdminError(c, err)
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+func sendAdminError(c *gin.Context, err error) {
+	if errors.Is(err, admin.ErrUserNotFound) {
+		SendNotFound(c, err.Error())
+		return
+	}
+	SendPreconditionFailed(c, err.Error())
+}
+
+func NewAdminFacade(adminService admin.AdminService) *AdminFacade {
+	return &AdminFacade{adminService}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/gateway"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
+)
+
+type GatewayFacade struct {
+	gatewayService gateway.GatewayService
+}
+
+// Verify godoc
+//
+// @Summary     Verify a request forwarded by a proxy
+// @Description For nginx auth_request and Traefik ForwardAuth, the user is passed on in the X-User-Id and X-User-Email headers
+// @Produce     json
+// @security    securitydefinitions.apikey
+// @Success     200 {object} APIResponse{data=gateway.DecisionDTO}
+// @Header      200 {string} X-User-Id    "authenticated user's id"
+// @Header      200 {string} X-User-Email "authenticated user's email"
+// @Failure     401 {object} APIResponse
+// @Failure     500 {object} APIResponse
+// @Router      /auth/verify [get]
+func (a *GatewayFacade) Verify(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "GatewayFacade/Verify"))
+
+	token := ""
+	if scheme, value, found := strings.Cut(c.GetHeader("Authorization"), " "); found && strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimSpace(value)
+	}
+
+	decision, err := a.gatewayService.Verify(c, token)
+	if err != nil {
+		message := "The request is not authenticated"
+		logger.Error(ctx, message, zap.Error(err))
+
+		if errors.Is(err, gateway.ErrMissingToken) {
+			c.Header("WWW-Authenticate", "Bearer")
+			SendUnauthorized(c, err.Error())
+			return
+		} else if errors.Is(err, gateway.ErrInvalidToken) || errors.Is(err, gateway.ErrDPoPBound) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			SendUnauthorized(c, err.Error())
+			return
+		}
+
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	c.Header("X-User-Id", decision.UserID)
+	c.Header("X-User-Email", decision.UserEmail)
+	SendOk(c, decision)
+}
+
+func NewGatewayFacade(gatewayService gateway.GatewayService) *GatewayFacade {
+	return &GatewayFacade{gatewayService}
+}
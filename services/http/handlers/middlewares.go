@@ -1,23 +1,42 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
 	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	"github.com/the-code-genin/simple-jwt-api-go/common/tokens"
 	"go.uber.org/zap"
 )
 
 type Middlewares struct {
-	usersService users.UsersService
+	usersService       users.UsersService
+	dpopVerifier       *dpop.Verifier
+	verificationPolicy users.VerificationPolicy
 }
 
+// HandleUserAuth verifies the request's access token with the default verification policy.
 func (m *Middlewares) HandleUserAuth(c *gin.Context) {
+	m.handleUserAuth(c, m.verificationPolicy)
+}
+
+// HandleUserAuthWith verifies the request's access token with the policy instead of the default one.
+func (m *Middlewares) HandleUserAuthWith(policy users.VerificationPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.handleUserAuth(c, policy)
+	}
+}
+
+func (m *Middlewares) handleUserAuth(c *gin.Context, policy users.VerificationPolicy) {
 	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "Middlewares/HandleUserAuth"))
 
-	authHeader := strings.Split(c.GetHeader("Authorization"), "Bearer ")
-	if len(authHeader) != 2 {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !(strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, dpop.TokenType)) {
 		message := "invalid Authorization header"
 		logger.Error(ctx, message)
 		SendBadRequest(c, message)
@@ -25,8 +44,8 @@ func (m *Middlewares) HandleUserAuth(c *gin.Context) {
 		return
 	}
 
-	token := strings.TrimSpace(authHeader[1])
-	user, err := m.usersService.DecodeAccessToken(c, token)
+	token = strings.TrimSpace(token)
+	accessToken, err := m.usersService.DecodeAccessToken(c, token, policy)
 	if err != nil {
 		message := "Unable to decode user access token"
 		logger.Error(ctx, message, zap.Error(err))
@@ -35,11 +54,121 @@ func (m *Middlewares) HandleUserAuth(c *gin.Context) {
 		return
 	}
 
-	c.Set("auth_user", *user)
+	// DPoP bound tokens are only usable with a fresh proof signed by their key
+	if accessToken.Confirmation != nil || strings.EqualFold(scheme, dpop.TokenType) {
+		if err := m.verifyDPoP(c, scheme, token, accessToken.Confirmation); err != nil {
+			message := "Invalid DPoP proof"
+			logger.Error(ctx, message, zap.Error(err))
+			c.Header("WWW-Authenticate", fmt.Sprintf(`DPoP error="invalid_dpop_proof", algs="%s"`, strings.Join(dpop.SigningAlgorithms, " ")))
+			SendBadRequest(c, message)
+			c.Abort()
+			return
+		}
+	}
+
+	c.Set("auth_user", accessToken.User)
+	c.Set("auth_access_token", *accessToken)
 	c.Set("auth_token", token)
 	c.Next()
 }
 
-func NewMiddlewares(usersService users.UsersService) *Middlewares {
-	return &Middlewares{usersService}
+// RequirePermissions must run after HandleUserAuth,
+// it only lets through users whose token grants every one of the permissions.
+func (m *Middlewares) RequirePermissions(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "Middlewares/RequirePermissions"))
+
+		authUser, ok := getAuthUser(ctx, c)
+		if !ok {
+			SendServerError(c, "an error occured")
+			c.Abort()
+			return
+		}
+
+		granted := make(map[string]bool, len(authUser.Permissions))
+		for _, permission := range authUser.Permissions {
+			granted[permission] = true
+		}
+
+		for _, permission := range permissions {
+			if !granted[permission] {
+				message := fmt.Sprintf("Permission %s required", permission)
+				logger.Error(ctx, message, zap.String("userId", authUser.ID))
+				SendForbidden(c, message)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScopes must run after HandleUserAuth,
+// it only lets through tokens that were granted every one of the scopes.
+func (m *Middlewares) RequireScopes(requiredScopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "Middlewares/RequireScopes"))
+
+		val, ok := c.Get("auth_access_token")
+		if !ok {
+			logger.Error(ctx, "Auth access token not in gin context")
+			SendServerError(c, "an error occured")
+			c.Abort()
+			return
+		}
+		accessToken := val.(users.DecodedAccessTokenDTO)
+
+		for _, scope := range requiredScopes {
+			if !scopes.Contains(accessToken.Scopes, scope) {
+				message := fmt.Sprintf("Scope %s required", scope)
+				logger.Error(ctx, message, zap.String("userId", accessToken.User.ID))
+				SendForbidden(c, message)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// verifyDPoP checks the request's DPoP proof was signed by the key the token is bound to.
+func (m *Middlewares) verifyDPoP(c *gin.Context, scheme, token string, confirmation *tokens.Confirmation) error {
+	if !strings.EqualFold(scheme, dpop.TokenType) || confirmation == nil {
+		return errors.New("DPoP bound tokens must use the DPoP authorization scheme")
+	}
+
+	proof, err := m.dpopVerifier.Verify(c, c.GetHeader(dpop.HeaderName), c.Request.Method, c.Request.URL.Path, token)
+	if err != nil {
+		return err
+	}
+
+	if proof.JKT != confirmation.JKT {
+		return errors.New("DPoP proof was not signed by the key the token is bound to")
+	}
+	return nil
+}
+
+// bindDPoPProof verifies the DPoP proof sent to a token endpoint,
+// it returns the thumbprint of the key to bind the token to or an empty string if no proof was sent.
+func bindDPoPProof(c *gin.Context, verifier *dpop.Verifier) (string, error) {
+	proof := c.GetHeader(dpop.HeaderName)
+	if proof == "" {
+		return "", nil
+	}
+
+	result, err := verifier.Verify(c, proof, c.Request.Method, c.Request.URL.Path, "")
+	if err != nil {
+		return "", err
+	}
+	return result.JKT, nil
+}
+
+func NewMiddlewares(
+	usersService users.UsersService,
+	dpopVerifier *dpop.Verifier,
+	verificationPolicy users.VerificationPolicy,
+) *Middlewares {
+	return &Middlewares{usersService, dpopVerifier, verificationPolicy}
 }
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+)
+
+func TestRequirePermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	middlewares := NewMiddlewares(nil, nil, users.VerificationStrict)
+
+	serve := func(permissions ...string) int {
+		router := gin.New()
+		router.GET(
+			"/",
+			func(c *gin.Context) {
+				c.Set("auth_user", users.UserDTO{ID: "1", Permissions: permissions})
+			},
+			middlewares.RequirePermissions("users:read", "users:manage"),
+			func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			},
+		)
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+		return res.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve("users:read", "users:manage", "roles:manage"))
+	assert.Equal(t, http.StatusForbidden, serve("users:read"))
+	assert.Equal(t, http.StatusForbidden, serve())
+}
@@ -0,0 +1,574 @@
+package handlers
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/oauth"
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var templates = template.Must(template.ParseFS(templateFiles, "templates/*.html"))
+
+type OAuthFacade struct {
+	oauthService oauth.OAuthService
+	dpopVerifier *dpop.Verifier
+}
+
+// ListClients godoc
+//
+// @Summary  List OAuth clients
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Success  200 {object} APIResponse{data=[]oauth.ClientDTO}
+// @Failure  403 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/oauth/clients [get]
+func (a *OAuthFacade) ListClients(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/ListClients"))
+
+	result, err := a.oauthService.ListClients(c)
+	if err != nil {
+		message := "An error occured while listing oauth clients"
+		logger.Error(ctx, message, zap.Error(err))
+		SendServerError(c, err.Error())
+		return
+	}
+
+	SendOk(c, result)
+}
+
+// CreateClient godoc
+//
+// @Summary  Register an OAuth client
+// @Accept   json
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    req body      oauth.CreateClientDTO true "body"
+// @Success  201 {object} APIResponse{data=oauth.ClientDTO}
+// @Failure  400 {object} APIResponse
+// @Failure  403 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/oauth/clients [post]
+func (a *OAuthFacade) CreateClient(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/CreateClient"))
+
+	var req oauth.CreateClientDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to oauth.CreateClientDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	client, err := a.oauthService.CreateClient(c, req)
+	if err != nil {
+		message := "An error occured while creating the oauth client"
+		logger.Error(ctx, message, zap.Error(err))
+		sendOAuthClientError(c, err)
+		return
+	}
+
+	SendCreated(c, client)
+}
+
+// DeleteClient godoc
+//
+// @Summary  Delete an OAuth client
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    id  path      string true "client id"
+// @Success  200 {object} APIResponse{data=BlankStruct}
+// @Failure  403 {object} APIResponse
+// @Failure  404 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/oauth/clients/{id} [delete]
+func (a *OAuthFacade) DeleteClient(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/DeleteClient"))
+
+	if err := a.oauthService.DeleteClient(c, c.Param("id")); err != nil {
+		message := "An error occured while deleting the oauth client"
+		logger.Error(ctx, message, zap.Error(err))
+		sendOAuthClientError(c, err)
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// Authorize godoc
+//
+// @Summary Show the login and consent page for an OAuth authorization request
+// @Produce html
+// @Param   response_type         query string true  "must be code"
+// @Param   client_id             query string true  "client id"
+// @Param   redirect_uri          query string true  "registered redirect uri"
+// @Param   scope                 query string false "space-delimited scopes"
+// @Param   state                 query string false "opaque client state"
+// @Param   code_challenge        query string true  "PKCE code challenge"
+// @Param   code_challenge_method query string true  "must be S256"
+// @Param   nonce                 query string false "OpenID Connect nonce"
+// @Success 200
+// @Failure 302
+// @Failure 400
+// @Router  /oauth/authorize [get]
+func (a *OAuthFacade) Authorize(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/Authorize"))
+
+	var req oauth.AuthorizationRequestDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request query to oauth.AuthorizationRequestDTO", zap.Error(err))
+		renderOAuthError(c, err)
+		return
+	}
+
+	consent, err := a.oauthService.ValidateAuthorization(c, req)
+	if err != nil {
+		logger.Error(ctx, "Invalid authorization request", zap.Error(err))
+		renderOAuthError(c, err)
+		return
+	}
+
+	renderConsent(c, http.StatusOK, req, *consent, "", "")
+}
+
+// SubmitAuthorization godoc
+//
+// @Summary Log in and approve or deny an OAuth authorization request
+// @Accept  x-www-form-urlencoded
+// @Produce html
+// @Param   email    formData string false "email"
+// @Param   password formData string false "password"
+// @Param   action   formData string true  "approve or deny"
+// @Success 302
+// @Failure 400
+// @Failure 401
+// @Router  /oauth/authorize [post]
+func (a *OAuthFacade) SubmitAuthorization(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/SubmitAuthorization"))
+
+	var req oauth.AuthorizeDTO
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to oauth.AuthorizeDTO", zap.Error(err))
+		renderOAuthError(c, err)
+		return
+	}
+
+	if c.PostForm("action") == "deny" {
+		redirectURI, err := a.oauthService.Deny(c, req.AuthorizationRequestDTO)
+		if err != nil {
+			logger.Error(ctx, "Invalid authorization request", zap.Error(err))
+			renderOAuthError(c, err)
+			return
+		}
+
+		c.Redirect(http.StatusFound, redirectURI)
+		return
+	}
+
+	redirectURI, err := a.oauthService.Authorize(c, req)
+	var redirectErr *oauth.RedirectError
+	var oauthErr *oauth.Error
+	if errors.As(err, &redirectErr) || errors.As(err, &oauthErr) {
+		logger.Error(ctx, "Invalid authorization request", zap.Error(err))
+		renderOAuthError(c, err)
+		return
+	} else if err != nil {
+		// Let the user try again with the same request
+		logger.Error(ctx, "An error occured while authorizing the request", zap.Error(err))
+		consent, validateErr := a.oauthService.ValidateAuthorization(c, req.AuthorizationRequestDTO)
+		if validateErr != nil {
+			renderOAuthError(c, validateErr)
+			return
+		}
+
+		renderConsent(c, http.StatusUnauthorized, req.AuthorizationRequestDTO, *consent, req.Email, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURI)
+}
+
+// DeviceAuthorization godoc
+//
+// @Summary Start a device authorization grant
+// @Accept  x-www-form-urlencoded
+// @Produce json
+// @Param   client_id     formData string false "client id, unless sent with HTTP basic auth"
+// @Param   client_secret formData string false "client secret for confidential clients"
+// @Param   scope         formData string false "space-delimited scopes"
+// @Success 200 {object} oauth.DeviceAuthorizationDTO
+// @Failure 400 {object} oauth.Error
+// @Failure 401 {object} oauth.Error
+// @Failure 500 {object} oauth.Error
+// @Router  /oauth/device_authorization [post]
+func (a *OAuthFacade) DeviceAuthorization(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/DeviceAuthorization"))
+
+	c.Header("Cache-Control", "no-store")
+
+	var req oauth.DeviceAuthorizationRequestDTO
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to oauth.DeviceAuthorizationRequestDTO", zap.Error(err))
+		c.JSON(http.StatusBadRequest, oauth.Error{Code: oauth.ErrorInvalidRequest, Description: err.Error()})
+		return
+	}
+
+	if err := bindClientCredentials(c, &req.ClientID, &req.ClientSecret); err != nil {
+		logger.Error(ctx, "Invalid client credentials", zap.Error(err))
+		sendOAuthTokenError(c, err)
+		return
+	}
+
+	authorization, err := a.oauthService.DeviceAuthorization(c, req)
+	if err != nil {
+		logger.Error(ctx, "An error occured while starting the device authorization", zap.Error(err))
+		sendOAuthTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, authorization)
+}
+
+// Device godoc
+//
+// @Summary Show the page where users enter and approve a device's user code
+// @Produce html
+// @Param   user_code query string false "user code shown on the device"
+// @Success 200
+// @Failure 400
+// @Router  /oauth/device [get]
+func (a *OAuthFacade) Device(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/Device"))
+
+	userCode := c.Query("user_code")
+	if userCode == "" {
+		renderDevice(c, http.StatusOK, gin.H{})
+		return
+	}
+
+	consent, err := a.oauthService.GetDeviceConsent(c, userCode)
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the device consent", zap.Error(err))
+		renderDevice(c, http.StatusBadRequest, gin.H{"UserCode": userCode, "Message": err.Error()})
+		return
+	}
+
+	renderDevice(c, http.StatusOK, gin.H{"UserCode": userCode, "Consent": consent})
+}
+
+// SubmitDevice godoc
+//
+// @Summary Log in and approve or deny a device's user code
+// @Accept  x-www-form-urlencoded
+// @Produce html
+// @Param   user_code formData string true  "user code shown on the device"
+// @Param   email     formData string false "email"
+// @Param   password  formData string false "password"
+// @Param   action    formData string true  "approve or deny"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Router  /oauth/device [post]
+func (a *OAuthFacade) SubmitDevice(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/SubmitDevice"))
+
+	var req oauth.ApproveDeviceDTO
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to oauth.ApproveDeviceDTO", zap.Error(err))
+		renderDevice(c, http.StatusBadRequest, gin.H{"Message": err.Error()})
+		return
+	}
+
+	if c.PostForm("action") == "deny" {
+		if err := a.oauthService.DenyDevice(c, req.UserCode); err != nil {
+			logger.Error(ctx, "An error occured while denying the device", zap.Error(err))
+			renderDevice(c, http.StatusBadRequest, gin.H{"Message": err.Error()})
+			return
+		}
+
+		renderDevice(c, http.StatusOK, gin.H{"Done": "The device was denied access."})
+		return
+	}
+
+	if err := a.oauthService.ApproveDevice(c, req); err != nil {
+		logger.Error(ctx, "An error occured while approving the device", zap.Error(err))
+
+		// Let the user try again with the same code while it is still valid
+		consent, consentErr := a.oauthService.GetDeviceConsent(c, req.UserCode)
+		if consentErr != nil {
+			renderDevice(c, http.StatusBadRequest, gin.H{"Message": consentErr.Error()})
+			return
+		}
+
+		renderDevice(c, http.StatusUnauthorized, gin.H{
+			"UserCode": req.UserCode,
+			"Consent":  consent,
+			"Email":    req.Email,
+			"Message":  err.Error(),
+		})
+		return
+	}
+
+	renderDevice(c, http.StatusOK, gin.H{"Done": "The device is connected, you can return to it now."})
+}
+
+// Token godoc
+//
+// @Summary Exchange an authorization grant for an access token
+// @Accept  x-www-form-urlencoded
+// @Produce json
+// @Param   DPoP                 header   string   false "DPoP proof to bind the token to"
+// @Param   grant_type           formData string   true  "authorization_code, client_credentials, urn:ietf:params:oauth:grant-type:device_code or urn:ietf:params:oauth:grant-type:token-exchange"
+// @Param   code                 formData string   false "authorization code"
+// @Param   redirect_uri         formData string   false "redirect uri used to get the code"
+// @Param   client_id            formData string   false "client id, unless sent with HTTP basic auth"
+// @Param   client_secret        formData string   false "client secret, unless sent with HTTP basic auth"
+// @Param   code_verifier        formData string   false "PKCE code verifier"
+// @Param   device_code          formData string   false "device code for the device_code grant"
+// @Param   scope                formData string   false "space-delimited scopes for client_credentials and token-exchange"
+// @Param   audience             formData []string false "audiences for client_credentials and token-exchange"
+// @Param   subject_token        formData string   false "token being exchanged"
+// @Param   subject_token_type   formData string   false "urn:ietf:params:oauth:token-type:access_token"
+// @Param   actor_token          formData string   false "token of the client acting for the subject"
+// @Param   actor_token_type     formData string   false "urn:ietf:params:oauth:token-type:access_token"
+// @Param   requested_token_type formData string   false "urn:ietf:params:oauth:token-type:access_token"
+// @Success 200 {object} oauth.TokenDTO
+// @Failure 400 {object} oauth.Error
+// @Failure 401 {object} oauth.Error
+// @Failure 500 {object} oauth.Error
+// @Router  /oauth/token [post]
+func (a *OAuthFacade) Token(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/Token"))
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req oauth.TokenRequestDTO
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to oauth.TokenRequestDTO", zap.Error(err))
+		c.JSON(http.StatusBadRequest, oauth.Error{Code: oauth.ErrorInvalidRequest, Description: err.Error()})
+		return
+	}
+
+	if err := bindClientCredentials(c, &req.ClientID, &req.ClientSecret); err != nil {
+		logger.Error(ctx, "Invalid client credentials", zap.Error(err))
+		sendOAuthTokenError(c, err)
+		return
+	}
+
+	jkt, err := bindDPoPProof(c, a.dpopVerifier)
+	if err != nil {
+		logger.Error(ctx, "Invalid DPoP proof", zap.Error(err))
+		sendOAuthTokenError(c, &oauth.Error{Code: oauth.ErrorInvalidDPoPProof, Description: err.Error()})
+		return
+	}
+	req.JKT = jkt
+	req.Device = getDevice(c)
+
+	token, err := a.oauthService.Token(c, req)
+	if err != nil {
+		logger.Error(ctx, "An error occured while issuing the token", zap.Error(err))
+		sendOAuthTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// Introspect godoc
+//
+// @Summary Describe an access token to a confidential client
+// @Accept  x-www-form-urlencoded
+// @Produce json
+// @Param   token         formData string true  "access token"
+// @Param   client_id     formData string false "client id, unless sent with HTTP basic auth"
+// @Param   client_secret formData string false "client secret, unless sent with HTTP basic auth"
+// @Success 200 {object} oauth.IntrospectionDTO
+// @Failure 400 {object} oauth.Error
+// @Failure 401 {object} oauth.Error
+// @Failure 500 {object} oauth.Error
+// @Router  /oauth/introspect [post]
+func (a *OAuthFacade) Introspect(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/Introspect"))
+
+	var req oauth.IntrospectDTO
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to oauth.IntrospectDTO", zap.Error(err))
+		c.JSON(http.StatusBadRequest, oauth.Error{Code: oauth.ErrorInvalidRequest, Description: err.Error()})
+		return
+	}
+
+	if err := bindClientCredentials(c, &req.ClientID, &req.ClientSecret); err != nil {
+		logger.Error(ctx, "Invalid client credentials", zap.Error(err))
+		sendOAuthTokenError(c, err)
+		return
+	}
+
+	introspection, err := a.oauthService.Introspect(c, req)
+	if err != nil {
+		logger.Error(ctx, "An error occured while introspecting the token", zap.Error(err))
+		sendOAuthTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, introspection)
+}
+
+// Discovery godoc
+//
+// @Summary OpenID Connect provider metadata
+// @Produce json
+// @Success 200 {object} oauth.DiscoveryDTO
+// @Router  /.well-known/openid-configuration [get]
+func (a *OAuthFacade) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, a.oauthService.Discovery(c))
+}
+
+// JWKS godoc
+//
+// @Summary Public keys ID tokens are signed with
+// @Produce json
+// @Success 200 {object} BlankStruct{keys=[]object}
+// @Router  /.well-known/jwks.json [get]
+func (a *OAuthFacade) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, a.oauthService.JWKS(c))
+}
+
+// UserInfo godoc
+//
+// @Summary  Standard claims about the authenticated user
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Success  200 {object} oauth.UserInfoDTO
+// @Failure  400 {object} APIResponse
+// @Failure  403 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /userinfo [get]
+// @Router   /userinfo [post]
+func (a *OAuthFacade) UserInfo(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "OAuthFacade/UserInfo"))
+
+	val, ok := c.Get("auth_access_token")
+	if !ok {
+		logger.Error(ctx, "Auth access token not in gin context")
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	info, err := a.oauthService.UserInfo(c, val.(users.DecodedAccessTokenDTO))
+	if err != nil {
+		logger.Error(ctx, "An error occured while getting the user info", zap.Error(err))
+		SendForbidden(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// bindClientCredentials reads client_secret_basic credentials,
+// which take the place of the client_id and client_secret form fields.
+func bindClientCredentials(c *gin.Context, clientID, clientSecret *string) error {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return nil
+	}
+
+	// The credentials are form-urlencoded before being base64 encoded (RFC 6749 section 2.3.1)
+	id, err := url.QueryUnescape(username)
+	if err != nil {
+		return &oauth.Error{Code: oauth.ErrorInvalidClient, Description: "malformed client credentials"}
+	}
+
+	secret, err := url.QueryUnescape(password)
+	if err != nil {
+		return &oauth.Error{Code: oauth.ErrorInvalidClient, Description: "malformed client credentials"}
+	}
+
+	if *clientID != "" && *clientID != id {
+		return &oauth.Error{Code: oauth.ErrorInvalidRequest, Description: "client_id does not match the authorization header"}
+	}
+
+	*clientID, *clientSecret = id, secret
+	return nil
+}
+
+func renderConsent(
+	c *gin.Context,
+	status int,
+	req oauth.AuthorizationRequestDTO,
+	consent oauth.ConsentDTO,
+	email string,
+	message string,
+) {
+	renderTemplate(c, status, "authorize.html", gin.H{
+		"Request": req,
+		"Consent": consent,
+		"Email":   email,
+		"Message": message,
+	})
+}
+
+func renderDevice(c *gin.Context, status int, data gin.H) {
+	renderTemplate(c, status, "device.html", data)
+}
+
+// renderOAuthError redirects errors the client should be told about,
+// the rest are shown to the user as the request can't be trusted.
+func renderOAuthError(c *gin.Context, err error) {
+	var redirectErr *oauth.RedirectError
+	if errors.As(err, &redirectErr) {
+		c.Redirect(http.StatusFound, redirectErr.RedirectURI)
+		return
+	}
+
+	renderTemplate(c, http.StatusBadRequest, "error.html", gin.H{"Message": err.Error()})
+}
+
+func renderTemplate(c *gin.Context, status int, name string, data interface{}) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	if err := templates.ExecuteTemplate(c.Writer, name, data); err != nil {
+		logger.Error(c.Request.Context(), "Unable to render template", zap.String("template", name), zap.Error(err))
+	}
+}
+
+func sendOAuthTokenError(c *gin.Context, err error) {
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, oauth.Error{Code: oauth.ErrorServerError})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == oauth.ErrorInvalidClient {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.JSON(status, oauthErr)
+}
+
+func sendOAuthClientError(c *gin.Context, err error) {
+	if errors.Is(err, oauth.ErrClientNotFound) {
+		SendNotFound(c, err.Error())
+		return
+	}
+	SendPreconditionFailed(c, err.Error())
+}
+
+func NewOAuthFacade(oauthService oauth.OAuthService, dpopVerifier *dpop.Verifier) *OAuthFacade {
+	return &OAuthFacade{oauthService, dpopVerifier}
+}
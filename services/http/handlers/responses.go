@@ -21,6 +21,14 @@ func SendBadRequest(ctx *gin.Context, message string) {
 	})
 }
 
+func SendValidationErrors(ctx *gin.Context, message string, errors interface{}) {
+	ctx.JSON(http.StatusBadRequest, APIResponse{
+		Code:    http.StatusBadRequest,
+		Data:    errors,
+		Message: message,
+	})
+}
+
 func SendPreconditionFailed(ctx *gin.Context, message string) {
 	ctx.JSON(http.StatusPreconditionFailed, APIResponse{
 		Code:    http.StatusPreconditionFailed,
@@ -28,6 +36,20 @@ func SendPreconditionFailed(ctx *gin.Context, message string) {
 	})
 }
 
+func SendUnauthorized(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusUnauthorized, APIResponse{
+		Code:    http.StatusUnauthorized,
+		Message: message,
+	})
+}
+
+func SendForbidden(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusForbidden, APIResponse{
+		Code:    http.StatusForbidden,
+		Message: message,
+	})
+}
+
 func SendServerError(ctx *gin.Context, message string) {
 	ctx.JSON(http.StatusInternalServerError, APIResponse{
 		Code:    http.StatusInternalServerError,
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
+)
+
+type RolesFacade struct {
+	rolesService roles.RolesService
+}
+
+// ListRoles godoc
+//
+// @Summary  List roles and their permissions
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Success  200 {object} APIResponse{data=[]roles.RoleDTO}
+// @Failure  403 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/roles [get]
+func (a *RolesFacade) ListRoles(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "RolesFacade/ListRoles"))
+
+	result, err := a.rolesService.ListRoles(c)
+	if err != nil {
+		message := "An error occured while listing roles"
+		logger.Error(ctx, message, zap.Error(err))
+		SendServerError(c, err.Error())
+		return
+	}
+
+	SendOk(c, result)
+}
+
+// CreateRole godoc
+//
+// @Summary  Create a role
+// @Accept   json
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    req body      roles.CreateRoleDTO true "body"
+// @Success  201 {object} APIResponse{data=roles.RoleDTO}
+// @Failure  400 {object} APIResponse
+// @Failure  403 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/roles [post]
+func (a *RolesFacade) CreateRole(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "RolesFacade/CreateRole"))
+
+	var req roles.CreateRoleDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to roles.CreateRoleDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	role, err := a.rolesService.CreateRole(c, req)
+	if err != nil {
+		message := "An error occured while creating the role"
+		logger.Error(ctx, message, zap.Error(err))
+		sendRolesError(c, err)
+		return
+	}
+
+	SendCreated(c, role)
+}
+
+// SetRolePermissions godoc
+//
+// @Summary  Replace the permissions granted by a role
+// @Accept   json
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    name path      string                      true "role name"
+// @Param    req  body      roles.SetRolePermissionsDTO true "body"
+// @Success  200  {object} APIResponse{data=roles.RoleDTO}
+// @Failure  400  {object} APIResponse
+// @Failure  403  {object} APIResponse
+// @Failure  404  {object} APIResponse
+// @Failure  412  {object} APIResponse
+// @Failure  500  {object} APIResponse
+// @Router   /admin/roles/{name}/permissions [put]
+func (a *RolesFacade) SetRolePermissions(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "RolesFacade/SetRolePermissions"))
+
+	var req roles.SetRolePermissionsDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to roles.SetRolePermissionsDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	role, err := a.rolesService.SetRolePermissions(c, c.Param("name"), req)
+	if err != nil {
+		message := "An error occured while setting the role permissions"
+		logger.Error(ctx, message, zap.Error(err))
+		sendRolesError(c, err)
+		return
+	}
+
+	SendOk(c, role)
+}
+
+// DeleteRole godoc
+//
+// @Summary  Delete a role
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    name path      string true "role name"
+// @Success  200  {object} APIResponse{data=BlankStruct}
+// @Failure  403  {object} APIResponse
+// @Failure  404  {object} APIResponse
+// @Failure  412  {object} APIResponse
+// @Failure  500  {object} APIResponse
+// @Router   /admin/roles/{name} [delete]
+func (a *RolesFacade) DeleteRole(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "RolesFacade/DeleteRole"))
+
+	if err := a.rolesService.DeleteRole(c, c.Param("name")); err != nil {
+		message := "An error occured while deleting the role"
+		logger.Error(ctx, message, zap.Error(err))
+		sendRolesError(c, err)
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// ListPermissions godoc
+//
+// @Summary  List permissions
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Success  200 {object} APIResponse{data=[]roles.PermissionDTO}
+// @Failure  403 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/permissions [get]
+func (a *RolesFacade) ListPermissions(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "RolesFacade/ListPermissions"))
+
+	result, err := a.rolesService.ListPermissions(c)
+	if err != nil {
+		message := "An error occured while listing permissions"
+		logger.Error(ctx, message, zap.Error(err))
+		SendServerError(c, err.Error())
+		return
+	}
+
+	SendOk(c, result)
+}
+
+// CreatePermission godoc
+//
+// @Summary  Create a permission
+// @Accept   json
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    req body      roles.CreatePermissionDTO true "body"
+// @Success  201 {object} APIResponse{data=roles.PermissionDTO}
+// @Failure  400 {object} APIResponse
+// @Failure  403 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/permissions [post]
+func (a *RolesFacade) CreatePermission(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "RolesFacade/CreatePermission"))
+
+	var req roles.CreatePermissionDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to roles.CreatePermissionDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	permission, err := a.rolesService.CreatePermission(c, req)
+	if err != nil {
+		message := "An error occured while creating the permission"
+		logger.Error(ctx, message, zap.Error(err))
+		sendRolesError(c, err)
+		return
+	}
+
+	SendCreated(c, permission)
+}
+
+// AssignUserRole godoc
+//
+// @Summary  Assign a role to a user
+// @Accept   json
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    id  path      string                  true "user id"
+// @Param    req body      roles.AssignUserRoleDTO true "body"
+// @Success  200 {object} APIResponse{data=BlankStruct}
+// @Failure  400 {object} APIResponse
+// @Failure  403 {object} APIResponse
+// @Failure  404 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /admin/users/{id}/roles [post]
+func (a *RolesFacade) AssignUserRole(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "RolesFacade/AssignUserRole"))
+
+	var req roles.AssignUserRoleDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to roles.AssignUserRoleDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	if err := a.rolesService.AssignUserRole(c, c.Param("id"), req); err != nil {
+		message := "An error occured while assigning the role"
+		logger.Error(ctx, message, zap.Error(err))
+		sendRolesError(c, err)
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// RemoveUserRole godoc
+//
+// @Summary     Remove a role from a user
+// @Description The user's existing access tokens are revoked
+// @Produce     json
+// @security    securitydefinitions.apikey
+// @Param       id   path      string true "user id"
+// @Param       role path      string true "role name"
+// @Success     200  {object} APIResponse{data=BlankStruct}
+// @Failure     403  {object} APIResponse
+// @Failure     404  {object} APIResponse
+// @Failure     500  {object} APIResponse
+// @Router      /admin/users/{id}/roles/{role} [delete]
+func (a *RolesFacade) RemoveUserRole(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "RolesFacade/RemoveUserRole"))
+
+	if err := a.rolesService.RemoveUserRole(c, c.Param("id"), c.Param("role")); err != nil {
+		message := "An error occured while removing the role"
+		logger.Error(ctx, message, zap.Error(err))
+		sendRolesError(c, err)
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+func sendRolesError(c *gin.Context, err error) {
+	if errors.Is(err, roles.ErrRoleNotFound) || errors.Is(err, roles.ErrUserNotFound) {
+		SendNotFound(c, err.Error())
+		return
+	}
+	SendPreconditionFailed(c, err.Error())
+}
+
+func NewRolesFacade(rolesService roles.RolesService) *RolesFacade {
+	return &RolesFacade{rolesService}
+}
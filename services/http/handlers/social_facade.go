@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/social"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
+)
+
+// socialStateCookie binds a login's state to the browser it was started in
+const socialStateCookie = "social_login_state"
+
+type SocialFacade struct {
+	socialService social.SocialService
+
+	// secureCookies only sends the state cookie over HTTPS
+	secureCookies bool
+}
+
+// ListProviders godoc
+//
+// @Summary List the identity providers users can sign in with
+// @Produce json
+// @Success 200 {object} APIResponse{data=[]string}
+// @Router  /auth/providers [get]
+func (a *SocialFacade) ListProviders(c *gin.Context) {
+	SendOk(c, a.socialService.ListProviders(c))
+}
+
+// Login godoc
+//
+// @Summary Sign in with an identity provider
+// @Param   provider path string true "provider name"
+// @Success 302
+// @Failure 404 {object} APIResponse
+// @Failure 500 {object} APIResponse
+// @Router  /auth/{provider}/login [get]
+func (a *SocialFacade) Login(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "SocialFacade/Login"))
+
+	authURL, err := a.socialService.Login(c, c.Param("provider"))
+	if err != nil {
+		message := "An error occured while starting the login"
+		logger.Error(ctx, message, zap.Error(err))
+		sendSocialError(c, err)
+		return
+	}
+
+	a.setStateCookie(c, authURL.State)
+	c.Redirect(http.StatusFound, authURL.URL)
+}
+
+// Callback godoc
+//
+// @Summary     Complete a sign in or link with an identity provider
+// @Description New users are signed up when the provider has verified their email,
+// @Description existing users are linked when both emails are verified.
+// @Description The state has to match the social_login_state cookie set when the login or link was started.
+// @Produce     json
+// @Param       provider          path  string true  "provider name"
+// @Param       code              query string false "authorization code"
+// @Param       state             query string true  "login state"
+// @Param       error             query string false "error returned by the provider"
+// @Param       error_description query string false "error description returned by the provider"
+// @Success     200 {object} APIResponse{data=social.SocialLoginDTO}
+// @Failure     400 {object} APIResponse
+// @Failure     404 {object} APIResponse
+// @Failure     412 {object} APIResponse
+// @Router      /auth/{provider}/callback [get]
+func (a *SocialFacade) Callback(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "SocialFacade/Callback"))
+
+	var req social.CallbackDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request query to social.CallbackDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+	req.Device = getDevice(c)
+
+	// The state cookie is only good for one callback
+	req.StateCookie, _ = c.Cookie(socialStateCookie)
+	a.setStateCookie(c, "")
+
+	result, err := a.socialService.Callback(c, c.Param("provider"), req)
+	if err != nil {
+		message := "An error occured while completing the login"
+		logger.Error(ctx, message, zap.Error(err))
+		sendSocialError(c, err)
+		return
+	}
+
+	SendOk(c, result)
+}
+
+// ListIdentities godoc
+//
+// @Summary  List the identity providers linked to the authenticated user
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Success  200 {object} APIResponse{data=[]social.IdentityDTO}
+// @Failure  500 {object} APIResponse
+// @Router   /me/identities [get]
+func (a *SocialFacade) ListIdentities(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "SocialFacade/ListIdentities"))
+
+	authUser, ok := getAuthUser(ctx, c)
+	if !ok {
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	identities, err := a.socialService.ListIdentities(c, authUser.ID)
+	if err != nil {
+		message := "An error occured while listing the identities"
+		logger.Error(ctx, message, zap.Error(err))
+		SendServerError(c, err.Error())
+		return
+	}
+
+	SendOk(c, identities)
+}
+
+// LinkIdentity godoc
+//
+// @Summary     Start linking an identity provider to the authenticated user
+// @Description The user is sent to the returned URL, the link is made once the provider redirects them back.
+// @Description The social_login_state cookie set with the response has to be sent with the callback.
+// @Produce     json
+// @security    securitydefinitions.apikey
+// @Param       provider path      string true "provider name"
+// @Success     200      {object} APIResponse{data=social.AuthorizationURLDTO}
+// @Failure     404      {object} APIResponse
+// @Failure     500      {object} APIResponse
+// @Router      /me/identities/{provider} [post]
+func (a *SocialFacade) LinkIdentity(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "SocialFacade/LinkIdentity"))
+
+	authUser, ok := getAuthUser(ctx, c)
+	if !ok {
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	authURL, err := a.socialService.Link(c, authUser.ID, c.Param("provider"))
+	if err != nil {
+		message := "An error occured while starting the link"
+		logger.Error(ctx, message, zap.Error(err))
+		sendSocialError(c, err)
+		return
+	}
+
+	a.setStateCookie(c, authURL.State)
+	SendOk(c, authURL)
+}
+
+// UnlinkIdentity godoc
+//
+// @Summary  Unlink an identity provider from the authenticated user
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    provider path      string true "provider name"
+// @Success  200      {object} APIResponse{data=BlankStruct}
+// @Failure  404      {object} APIResponse
+// @Failure  500      {object} APIResponse
+// @Router   /me/identities/{provider} [delete]
+func (a *SocialFacade) UnlinkIdentity(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "SocialFacade/UnlinkIdentity"))
+
+	authUser, ok := getAuthUser(ctx, c)
+	if !ok {
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	if err := a.socialService.Unlink(c, authUser.ID, c.Param("provider")); err != nil {
+		message := "An error occured while unlinking the identity"
+		logger.Error(ctx, message, zap.Error(err))
+		sendSocialError(c, err)
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// setStateCookie stores the state for the callback, it is cleared when the state is empty.
+func (a *SocialFacade) setStateCookie(c *gin.Context, state string) {
+	maxAge := 0
+	if state == "" {
+		maxAge = -1
+	}
+
+	// Lax cookies are still sent when the provider redirects the browser back
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(socialStateCookie, state, maxAge, "/auth", "", a.secureCookies, true)
+}
+
+func sendSocialError(c *gin.Context, err error) {
+	if errors.Is(err, social.ErrProviderNotFound) || errors.Is(err, social.ErrIdentityNotFound) {
+		SendNotFound(c, err.Error())
+		return
+	}
+	SendPreconditionFailed(c, err.Error())
+}
+
+func NewSocialFacade(socialService social.SocialService, secureCookies bool) *SocialFacade {
+	return &SocialFacade{socialService, secureCookies}
+}
@@ -1,14 +1,20 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/gin-gonic/gin"
 	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
 	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
 	"go.uber.org/zap"
 )
 
 type UsersFacade struct {
 	usersService users.UsersService
+	dpopVerifier *dpop.Verifier
 }
 
 // Register godoc
@@ -36,6 +42,13 @@ func (a *UsersFacade) Register(c *gin.Context) {
 	if err != nil {
 		message := "An error occured while registering the user"
 		logger.Error(ctx, message, zap.Error(err))
+
+		var validationErrors users.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			SendValidationErrors(c, err.Error(), validationErrors)
+			return
+		}
+
 		SendPreconditionFailed(c, err.Error())
 		return
 	}
@@ -45,14 +58,16 @@ func (a *UsersFacade) Register(c *gin.Context) {
 
 // GenerateAccessToken godoc
 //
-// @Summary Generate access token for a new user
-// @Accept  json
-// @Produce json
-// @Param   req body      users.GenerateUserAccessTokenDTO true "body"
-// @Success 200 {object} APIResponse{data=BlankStruct{user=users.UserDTO,access_token=string,type=string}}
-// @Failure 400 {object} APIResponse
-// @Failure 500 {object} APIResponse
-// @Router  /generate-access-token  [post]
+// @Summary     Generate access token for a new user
+// @Description The token is bound to the key of the DPoP proof when one is sent
+// @Accept      json
+// @Produce     json
+// @Param       DPoP header    string                         false "DPoP proof"
+// @Param       req  body      users.GenerateUserAccessTokenDTO true  "body"
+// @Success     200  {object} APIResponse{data=users.AccessTokenDTO}
+// @Failure     400  {object} APIResponse
+// @Failure     500  {object} APIResponse
+// @Router      /generate-access-token  [post]
 func (a *UsersFacade) GenerateAccessToken(c *gin.Context) {
 	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/GenerateAccessToken"))
 
@@ -63,7 +78,17 @@ func (a *UsersFacade) GenerateAccessToken(c *gin.Context) {
 		return
 	}
 
-	user, token, err := a.usersService.GenerateAccessToken(c, req)
+	jkt, err := bindDPoPProof(c, a.dpopVerifier)
+	if err != nil {
+		message := "Invalid DPoP proof"
+		logger.Error(ctx, message, zap.Error(err))
+		SendBadRequest(c, message)
+		return
+	}
+	req.JKT = jkt
+	req.Device = getDevice(c)
+
+	token, err := a.usersService.GenerateAccessToken(c, req)
 	if err != nil {
 		message := "An error occured while generate user access token"
 		logger.Error(ctx, message, zap.Error(err))
@@ -71,11 +96,7 @@ func (a *UsersFacade) GenerateAccessToken(c *gin.Context) {
 		return
 	}
 
-	SendOk(c, gin.H{
-		"user":         user,
-		"access_token": token,
-		"type":         "bearer",
-	})
+	SendOk(c, token)
 }
 
 // BlacklistAccessToken godoc
@@ -127,25 +148,381 @@ func (a *UsersFacade) BlacklistAccessToken(c *gin.Context) {
 func (a *UsersFacade) GetMe(c *gin.Context) {
 	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/GetMe"))
 
-	val, ok := c.Get("auth_user")
+	authUser, ok := getAuthUser(ctx, c)
 	if !ok {
-		logger.Error(ctx, "Auth token not in gin context")
 		SendServerError(c, "an error occured")
 		return
 	}
 
-	authUser, ok := val.(users.UserDTO)
+	SendOk(c, authUser)
+}
+
+// UpdateMe godoc
+//
+// @Summary     Update the authenticated user's profile
+// @Description A new email only replaces the current one once it is verified through the link sent to it.
+// @Accept      json
+// @Produce     json
+// @security    securitydefinitions.apikey
+// @Param       req body      users.UpdateProfileDTO true "body"
+// @Success     200 {object} APIResponse{data=users.UserDTO}
+// @Failure     400 {object} APIResponse
+// @Failure     412 {object} APIResponse
+// @Failure     500 {object} APIResponse
+// @Router      /me [patch]
+func (a *UsersFacade) UpdateMe(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/UpdateMe"))
+
+	authUser, ok := getAuthUser(ctx, c)
 	if !ok {
-		logger.Error(ctx, "Unable to parse auth user from gin context")
 		SendServerError(c, "an error occured")
 		return
 	}
 
-	SendOk(c, authUser)
+	var req users.UpdateProfileDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to users.UpdateProfileDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	user, err := a.usersService.UpdateProfile(c, authUser.ID, req)
+	if err != nil {
+		message := "An error occured while updating the user profile"
+		logger.Error(ctx, message, zap.Error(err))
+		SendPreconditionFailed(c, err.Error())
+		return
+	}
+
+	SendOk(c, user)
+}
+
+// ChangePassword godoc
+//
+// @Summary  Change the authenticated user's password
+// @Accept   json
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Param    req body      users.ChangePasswordDTO true "body"
+// @Success  200 {object} APIResponse{data=BlankStruct{access_token=string,type=string}}
+// @Failure  400 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /me/password [post]
+func (a *UsersFacade) ChangePassword(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/ChangePassword"))
+
+	authUser, ok := getAuthUser(ctx, c)
+	if !ok {
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	val, ok := c.Get("auth_access_token")
+	if !ok {
+		logger.Error(ctx, "Auth access token not in gin context")
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	var req users.ChangePasswordDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to users.ChangePasswordDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+	req.Token = val.(users.DecodedAccessTokenDTO)
+	req.Device = getDevice(c)
+
+	token, err := a.usersService.ChangePassword(c, authUser.ID, req)
+	if err != nil {
+		message := "An error occured while changing the user password"
+		logger.Error(ctx, message, zap.Error(err))
+
+		var validationErrors users.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			SendValidationErrors(c, err.Error(), validationErrors)
+			return
+		}
+
+		SendPreconditionFailed(c, err.Error())
+		return
+	}
+
+	// A new token is only issued when the other sessions were revoked
+	if token == nil {
+		SendOk(c, BlankStruct{})
+		return
+	}
+
+	SendOk(c, gin.H{
+		"access_token": token.AccessToken,
+		"type":         token.Type,
+	})
+}
+
+// VerifyEmail godoc
+//
+// @Summary Verify a user's email
+// @Produce json
+// @Param   token query     string true "email verification token"
+// @Success 200   {object} APIResponse{data=users.UserDTO}
+// @Failure 400   {object} APIResponse
+// @Failure 412   {object} APIResponse
+// @Failure 500   {object} APIResponse
+// @Router  /verify-email [get]
+func (a *UsersFacade) VerifyEmail(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/VerifyEmail"))
+
+	var req users.VerifyEmailDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request query to users.VerifyEmailDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	user, err := a.usersService.VerifyEmail(c, req)
+	if err != nil {
+		message := "An error occured while verifying the user email"
+		logger.Error(ctx, message, zap.Error(err))
+		SendPreconditionFailed(c, err.Error())
+		return
+	}
+
+	SendOk(c, user)
+}
+
+// RequestPasswordReset godoc
+//
+// @Summary     Email a password reset token
+// @Description The response doesn't tell whether the email is registered
+// @Accept      json
+// @Produce     json
+// @Param       req body      users.RequestPasswordResetDTO true "body"
+// @Success     200 {object} APIResponse{data=BlankStruct}
+// @Failure     400 {object} APIResponse
+// @Failure     500 {object} APIResponse
+// @Router      /forgot-password [post]
+func (a *UsersFacade) RequestPasswordReset(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/RequestPasswordReset"))
+
+	var req users.RequestPasswordResetDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to users.RequestPasswordResetDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	if err := a.usersService.RequestPasswordReset(c, req); err != nil {
+		message := "An error occured while requesting a password reset"
+		logger.Error(ctx, message, zap.Error(err))
+		SendServerError(c, message)
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// ResetPassword godoc
+//
+// @Summary Reset a user's password with a password reset token
+// @Accept  json
+// @Produce json
+// @Param   req body      users.ResetPasswordDTO true "body"
+// @Success 200 {object} APIResponse{data=BlankStruct}
+// @Failure 400 {object} APIResponse
+// @Failure 412 {object} APIResponse
+// @Failure 500 {object} APIResponse
+// @Router  /reset-password [post]
+func (a *UsersFacade) ResetPassword(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/ResetPassword"))
+
+	var req users.ResetPasswordDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to users.ResetPasswordDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	if err := a.usersService.ResetPassword(c, req); err != nil {
+		message := "An error occured while resetting the user password"
+		logger.Error(ctx, message, zap.Error(err))
+
+		var validationErrors users.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			SendValidationErrors(c, err.Error(), validationErrors)
+			return
+		}
+
+		SendPreconditionFailed(c, err.Error())
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// DeleteMe godoc
+//
+// @Summary     Delete the authenticated user's account
+// @Description The account is disabled immediately and permanently erased after the grace period
+// @Accept      json
+// @Produce     json
+// @security    securitydefinitions.apikey
+// @Param       req body      users.DeleteAccountDTO true "body"
+// @Success     200 {object} APIResponse{data=BlankStruct}
+// @Failure     400 {object} APIResponse
+// @Failure     412 {object} APIResponse
+// @Failure     500 {object} APIResponse
+// @Router      /me [delete]
+func (a *UsersFacade) DeleteMe(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/DeleteMe"))
+
+	authUser, ok := getAuthUser(ctx, c)
+	if !ok {
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	var req users.DeleteAccountDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Unable to bind request body to users.DeleteAccountDTO", zap.Error(err))
+		SendBadRequest(c, err.Error())
+		return
+	}
+
+	if err := a.usersService.DeleteAccount(c, authUser.ID, req); err != nil {
+		message := "An error occured while deleting the user account"
+		logger.Error(ctx, message, zap.Error(err))
+		SendPreconditionFailed(c, err.Error())
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// ExportMe godoc
+//
+// @Summary  Export everything stored about the authenticated user
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Success  200 {object} APIResponse{data=users.UserDataExportDTO}
+// @Failure  400 {object} APIResponse
+// @Failure  412 {object} APIResponse
+// @Failure  500 {object} APIResponse
+// @Router   /me/export [get]
+func (a *UsersFacade) ExportMe(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/ExportMe"))
+
+	authUser, ok := getAuthUser(ctx, c)
+	if !ok {
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	export, err := a.usersService.ExportData(c, authUser.ID)
+	if err != nil {
+		message := "An error occured while exporting the user data"
+		logger.Error(ctx, message, zap.Error(err))
+		SendPreconditionFailed(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.json"`, authUser.ID))
+	SendOk(c, export)
+}
+
+// ListSessions godoc
+//
+// @Summary  List the authenticated user's active sessions
+// @Produce  json
+// @security securitydefinitions.apikey
+// @Success  200 {object} APIResponse{data=[]users.SessionDTO}
+// @Failure  500 {object} APIResponse
+// @Router   /me/sessions [get]
+func (a *UsersFacade) ListSessions(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/ListSessions"))
+
+	val, ok := c.Get("auth_access_token")
+	if !ok {
+		logger.Error(ctx, "Auth access token not in gin context")
+		SendServerError(c, "an error occured")
+		return
+	}
+	accessToken := val.(users.DecodedAccessTokenDTO)
+
+	sessions, err := a.usersService.ListSessions(c, accessToken.User.ID, accessToken.SessionID)
+	if err != nil {
+		message := "An error occured while listing the sessions"
+		logger.Error(ctx, message, zap.Error(err))
+		SendServerError(c, err.Error())
+		return
+	}
+
+	SendOk(c, sessions)
+}
+
+// RevokeSession godoc
+//
+// @Summary     Revoke one of the authenticated user's sessions
+// @Description The session's access token is rejected from then on
+// @Produce     json
+// @security    securitydefinitions.apikey
+// @Param       id  path      string true "session id"
+// @Success     200 {object} APIResponse{data=BlankStruct}
+// @Failure     404 {object} APIResponse
+// @Failure     500 {object} APIResponse
+// @Router      /me/sessions/{id} [delete]
+func (a *UsersFacade) RevokeSession(c *gin.Context) {
+	ctx := logger.With(c.Request.Context(), zap.String(logger.FunctionNameField, "UsersFacade/RevokeSession"))
+
+	authUser, ok := getAuthUser(ctx, c)
+	if !ok {
+		SendServerError(c, "an error occured")
+		return
+	}
+
+	if err := a.usersService.RevokeSession(c, authUser.ID, c.Param("id")); err != nil {
+		message := "An error occured while revoking the session"
+		logger.Error(ctx, message, zap.Error(err))
+		if errors.Is(err, users.ErrSessionNotFound) {
+			SendNotFound(c, err.Error())
+			return
+		}
+		SendServerError(c, err.Error())
+		return
+	}
+
+	SendOk(c, BlankStruct{})
+}
+
+// getDevice describes the device the request was made from.
+func getDevice(c *gin.Context) users.DeviceDTO {
+	return users.DeviceDTO{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// getAuthUser returns the user set on the gin context by Middlewares.HandleUserAuth.
+func getAuthUser(ctx context.Context, c *gin.Context) (users.UserDTO, bool) {
+	val, ok := c.Get("auth_user")
+	if !ok {
+		logger.Error(ctx, "Auth user not in gin context")
+		return users.UserDTO{}, false
+	}
+
+	authUser, ok := val.(users.UserDTO)
+	if !ok {
+		logger.Error(ctx, "Unable to parse auth user from gin context")
+		return users.UserDTO{}, false
+	}
+
+	return authUser, true
 }
 
 func NewUsersFacade(
 	usersService users.UsersService,
+	dpopVerifier *dpop.Verifier,
 ) *UsersFacade {
-	return &UsersFacade{usersService}
+	return &UsersFacade{usersService, dpopVerifier}
 }
@@ -1,11 +1,20 @@
 package http
 
 import (
+	"expvar"
 	"fmt"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/admin"
+	"github.com/the-code-genin/simple-jwt-api-go/application/gateway"
+	"github.com/the-code-genin/simple-jwt-api-go/application/oauth"
+	"github.com/the-code-genin/simple-jwt-api-go/application/roles"
+	"github.com/the-code-genin/simple-jwt-api-go/application/social"
 	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/dpop"
+	"github.com/the-code-genin/simple-jwt-api-go/common/scopes"
+	db_roles "github.com/the-code-genin/simple-jwt-api-go/database/roles"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -28,10 +37,26 @@ func (s *Server) Run(port int) error {
 // @BasePath    /
 // @accept      json
 // @produce     json
-func NewServer(isProd bool, usersService users.UsersService) (*Server, error) {
+func NewServer(
+	isProd bool,
+	metricsEnabled bool,
+	usersService users.UsersService,
+	adminService admin.AdminService,
+	rolesService roles.RolesService,
+	oauthService oauth.OAuthService,
+	socialService social.SocialService,
+	gatewayService gateway.GatewayService,
+	dpopVerifier *dpop.Verifier,
+	verificationPolicy users.VerificationPolicy,
+) (*Server, error) {
 	// Create route handlers
-	usersFacade := handlers.NewUsersFacade(usersService)
-	middlewares := handlers.NewMiddlewares(usersService)
+	usersFacade := handlers.NewUsersFacade(usersService, dpopVerifier)
+	adminFacade := handlers.NewAdminFacade(adminService)
+	rolesFacade := handlers.NewRolesFacade(rolesService)
+	oauthFacade := handlers.NewOAuthFacade(oauthService, dpopVerifier)
+	socialFacade := handlers.NewSocialFacade(socialService, isProd)
+	gatewayFacade := handlers.NewGatewayFacade(gatewayService)
+	middlewares := handlers.NewMiddlewares(usersService, dpopVerifier, verificationPolicy)
 
 	// Create and configure router
 	if isProd {
@@ -50,10 +75,88 @@ func NewServer(isProd bool, usersService users.UsersService) (*Server, error) {
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
+	if !isProd || metricsEnabled {
+		router.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+	}
+
+	// Routes changing the account or its access always verify tokens against the backing stores
+	strictAuth := middlewares.HandleUserAuthWith(users.VerificationStrict)
+
 	router.POST("/register", usersFacade.Register)
 	router.POST("/generate-access-token", usersFacade.GenerateAccessToken)
-	router.POST("/blacklist-access-token", middlewares.HandleUserAuth, usersFacade.BlacklistAccessToken)
-	router.GET("/me", middlewares.HandleUserAuth, usersFacade.GetMe)
+	router.POST("/blacklist-access-token", strictAuth, usersFacade.BlacklistAccessToken)
+	router.GET("/verify-email", usersFacade.VerifyEmail)
+	router.POST("/forgot-password", usersFacade.RequestPasswordReset)
+	router.POST("/reset-password", usersFacade.ResetPassword)
+	router.GET("/auth/providers", socialFacade.ListProviders)
+	router.GET("/auth/verify", gatewayFacade.Verify)
+	router.GET("/auth/:provider/login", socialFacade.Login)
+	router.GET("/auth/:provider/callback", socialFacade.Callback)
+	router.GET("/oauth/authorize", oauthFacade.Authorize)
+	router.POST("/oauth/authorize", oauthFacade.SubmitAuthorization)
+	router.POST("/oauth/device_authorization", oauthFacade.DeviceAuthorization)
+	router.GET("/oauth/device", oauthFacade.Device)
+	router.POST("/oauth/device", oauthFacade.SubmitDevice)
+	router.POST("/oauth/token", oauthFacade.Token)
+	router.POST("/oauth/introspect", oauthFacade.Introspect)
+
+	router.GET("/.well-known/openid-configuration", oauthFacade.Discovery)
+	router.GET("/.well-known/jwks.json", oauthFacade.JWKS)
+	openID := middlewares.RequireScopes(scopes.ScopeOpenID)
+	router.GET("/userinfo", middlewares.HandleUserAuth, openID, oauthFacade.UserInfo)
+	router.POST("/userinfo", middlewares.HandleUserAuth, openID, oauthFacade.UserInfo)
+
+	profile := middlewares.RequireScopes(scopes.ScopeProfile)
+	router.GET("/me", middlewares.HandleUserAuth, profile, usersFacade.GetMe)
+	router.PATCH("/me", strictAuth, profile, usersFacade.UpdateMe)
+	router.DELETE("/me", strictAuth, usersFacade.DeleteMe)
+	router.POST("/me/password", strictAuth, usersFacade.ChangePassword)
+	router.GET("/me/export", middlewares.HandleUserAuth, profile, usersFacade.ExportMe)
+	router.GET("/me/sessions", middlewares.HandleUserAuth, profile, usersFacade.ListSessions)
+	router.DELETE("/me/sessions/:id", strictAuth, profile, usersFacade.RevokeSession)
+	router.GET("/me/identities", middlewares.HandleUserAuth, profile, socialFacade.ListIdentities)
+	router.POST("/me/identities/:provider", strictAuth, profile, socialFacade.LinkIdentity)
+	router.DELETE("/me/identities/:provider", strictAuth, profile, socialFacade.UnlinkIdentity)
+
+	adminRoutes := router.Group("/admin", strictAuth)
+
+	// Admin routes need both the permission and a token scoped for it
+	readUsers := adminRoutes.Group("",
+		middlewares.RequireScopes(db_roles.PermissionUsersRead),
+		middlewares.RequirePermissions(db_roles.PermissionUsersRead),
+	)
+	readUsers.GET("/users", adminFacade.ListUsers)
+	readUsers.GET("/users/:id", adminFacade.GetUser)
+
+	manageUsers := adminRoutes.Group("",
+		middlewares.RequireScopes(db_roles.PermissionUsersManage),
+		middlewares.RequirePermissions(db_roles.PermissionUsersManage),
+	)
+	manageUsers.DELETE("/users/:id", adminFacade.DeleteUser)
+	manageUsers.POST("/users/:id/suspend", adminFacade.SuspendUser)
+	manageUsers.POST("/users/:id/unsuspend", adminFacade.UnsuspendUser)
+	manageUsers.POST("/users/:id/force-password-reset", adminFacade.ForcePasswordReset)
+
+	manageRoles := adminRoutes.Group("",
+		middlewares.RequireScopes(db_roles.PermissionRolesManage),
+		middlewares.RequirePermissions(db_roles.PermissionRolesManage),
+	)
+	manageRoles.GET("/roles", rolesFacade.ListRoles)
+	manageRoles.POST("/roles", rolesFacade.CreateRole)
+	manageRoles.PUT("/roles/:name/permissions", rolesFacade.SetRolePermissions)
+	manageRoles.DELETE("/roles/:name", rolesFacade.DeleteRole)
+	manageRoles.GET("/permissions", rolesFacade.ListPermissions)
+	manageRoles.POST("/permissions", rolesFacade.CreatePermission)
+	manageRoles.POST("/users/:id/roles", rolesFacade.AssignUserRole)
+	manageRoles.DELETE("/users/:id/roles/:role", rolesFacade.RemoveUserRole)
+
+	manageClients := adminRoutes.Group("",
+		middlewares.RequireScopes(db_roles.PermissionClientsManage),
+		middlewares.RequirePermissions(db_roles.PermissionClientsManage),
+	)
+	manageClients.GET("/oauth/clients", oauthFacade.ListClients)
+	manageClients.POST("/oauth/clients", oauthFacade.CreateClient)
+	manageClients.DELETE("/oauth/clients/:id", oauthFacade.DeleteClient)
 
 	return &Server{router}, nil
 }
@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/the-code-genin/simple-jwt-api-go/application/users"
+	"github.com/the-code-genin/simple-jwt-api-go/common/logger"
+	"go.uber.org/zap"
+)
+
+// Scheduler runs periodic maintenance jobs in the background.
+type Scheduler struct {
+	usersService  users.UsersService
+	purgeInterval time.Duration
+}
+
+func (s *Scheduler) Run(ctx context.Context) error {
+	ctx = logger.With(ctx, zap.String(logger.FunctionNameField, "Scheduler/Run"))
+
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeDeletedUsers(ctx)
+		s.purgeExpiredSessions(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) purgeDeletedUsers(ctx context.Context) {
+	count, err := s.usersService.PurgeDeletedUsers(ctx)
+	if err != nil {
+		logger.Error(ctx, "An error occured while purging deleted users", zap.Error(err))
+		return
+	}
+	logger.Info(ctx, "Purged deleted users", zap.Int64("count", count))
+}
+
+func (s *Scheduler) purgeExpiredSessions(ctx context.Context) {
+	count, err := s.usersService.PurgeExpiredSessions(ctx)
+	if err != nil {
+		logger.Error(ctx, "An error occured while purging expired sessions", zap.Error(err))
+		return
+	}
+	logger.Info(ctx, "Purged expired sessions", zap.Int64("count", count))
+}
+
+// NewScheduler runs the jobs every purgeInterval, which has to be positive.
+func NewScheduler(usersService users.UsersService, purgeInterval time.Duration) (*Scheduler, error) {
+	if purgeInterval <= 0 {
+		return nil, fmt.Errorf("the purge interval must be positive, got %s", purgeInterval)
+	}
+	return &Scheduler{usersService, purgeInterval}, nil
+}